@@ -0,0 +1,61 @@
+package goa
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// limitedBody is a request body that fails with an error with ID ErrPayloadTooLarge once more
+// than max bytes have been read. HandleRoute uses it to enforce the route MaxBodySize for requests
+// whose body size is not known in advance, e.g. requests that use chunked transfer encoding.
+type limitedBody struct {
+	io.ReadCloser
+	max       int64
+	remaining int64
+	err       error
+}
+
+// newLimitedBody returns a body that reads at most max bytes from body.
+func newLimitedBody(body io.ReadCloser, max int64) *limitedBody {
+	return &limitedBody{ReadCloser: body, max: max, remaining: max}
+}
+
+// Read reads from the underlying body and fails if the body is larger than the maximum size.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		b.err = payloadTooLargeError(b.max)
+		return n, b.err
+	}
+	b.remaining -= int64(n)
+	if terr, ok := err.(*TypedError); ok && terr.ID == ErrPayloadTooLarge {
+		// The body wraps another limited body whose limit was exceeded
+		b.err = err
+	}
+	return n, err
+}
+
+// drain reads the bytes left in the body after decoding, at most max+1, so that trailing data
+// past the maximum size is detected even if the decoder stopped reading early. It returns an error
+// with ID ErrPayloadTooLarge if the body is larger than the maximum size.
+func (b *limitedBody) drain() error {
+	io.Copy(ioutil.Discard, b)
+	return b.err
+}
+
+// payloadTooLargeError returns the error produced when a request body is larger than max bytes.
+func payloadTooLargeError(max int64) error {
+	return &TypedError{
+		ID:   ErrPayloadTooLarge,
+		Mesg: fmt.Sprintf("request body must not be larger than %d bytes", max),
+	}
+}
@@ -0,0 +1,180 @@
+package goa_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+var _ = Describe("MaxBodySize", func() {
+	var service goa.Service
+	var maxBodySize int64
+	var handler goa.Handler
+	var payload interface{}
+	var handled bool
+
+	serve := func(body string, chunked bool) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/bottles", strings.NewReader(body))
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Content-Type", "application/json")
+		if chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		service.ServeMux().ServeHTTP(rec, req)
+		return rec
+	}
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		maxBodySize = 16
+		payload = nil
+		handled = false
+		handler = func(ctx *goa.Context) error {
+			handled = true
+			return ctx.RespondBytes(201, []byte("created"))
+		}
+	})
+
+	JustBeforeEach(func() {
+		ctrl := service.NewController("BottleController")
+		unmarshal := func(ctx *goa.Context) error {
+			return ctx.Service().DecodeRequest(ctx, &payload)
+		}
+		route := &goa.Route{Method: "POST", Path: "/bottles", Resource: "bottle", Action: "create", MaxBodySize: maxBodySize}
+		service.ServeMux().Handle(route.Method, route.Path, ctrl.HandleRoute(route, "create", func(ctx *goa.Context) error {
+			return handler(ctx)
+		}, unmarshal))
+	})
+
+	It("accepts bodies up to the maximum size", func() {
+		rec := serve(`{"name":"wine1"}`, false)
+		Ω(rec.Code).Should(Equal(201))
+		Ω(payload).Should(Equal(map[string]interface{}{"name": "wine1"}))
+	})
+
+	It("rejects larger bodies with 413 Payload Too Large", func() {
+		rec := serve(`{"name":"bottle"}`, false)
+		Ω(rec.Code).Should(Equal(413))
+		Ω(rec.Body.String()).Should(ContainSubstring("16 bytes"))
+		Ω(handled).Should(BeFalse())
+	})
+
+	Context("with chunked bodies", func() {
+		It("decodes bodies up to the maximum size", func() {
+			rec := serve(`{"name":"wine"}`, true)
+			Ω(rec.Code).Should(Equal(201))
+			Ω(payload).Should(Equal(map[string]interface{}{"name": "wine"}))
+		})
+
+		It("rejects larger bodies with 413 Payload Too Large", func() {
+			rec := serve(`{"name":"`+strings.Repeat("a", 1024)+`"}`, true)
+			Ω(rec.Code).Should(Equal(413))
+			Ω(handled).Should(BeFalse())
+		})
+
+		It("handles empty bodies like requests without body", func() {
+			rec := serve("", true)
+			Ω(rec.Code).Should(Equal(201))
+			Ω(payload).Should(BeNil())
+		})
+
+		It("rejects trailing data past the maximum size that the decoder does not read", func() {
+			body := io.MultiReader(strings.NewReader(`{"name":"wine"}`), strings.NewReader(strings.Repeat(" ", 1024)))
+			req, err := http.NewRequest("POST", "/bottles", body)
+			Ω(err).ShouldNot(HaveOccurred())
+			req.Header.Set("Content-Type", "application/json")
+			req.ContentLength = -1
+			rec := httptest.NewRecorder()
+			service.ServeMux().ServeHTTP(rec, req)
+			Ω(rec.Code).Should(Equal(413))
+			Ω(handled).Should(BeFalse())
+		})
+	})
+
+	Context("with a handler that reads the body", func() {
+		var streamSize int64
+
+		BeforeEach(func() {
+			streamSize = 0
+			handler = func(ctx *goa.Context) error {
+				if _, err := ioutil.ReadAll(ctx.StreamBody(streamSize)); err != nil {
+					return err
+				}
+				return ctx.RespondBytes(201, []byte("created"))
+			}
+		})
+
+		JustBeforeEach(func() {
+			route := &goa.Route{Method: "PUT", Path: "/bottles", MaxBodySize: maxBodySize}
+			ctrl := service.NewController("BottleController")
+			service.ServeMux().Handle(route.Method, route.Path, ctrl.HandleRoute(route, "upload", handler, nil))
+		})
+
+		It("fails reads past the maximum size with 413 Payload Too Large", func() {
+			req, err := http.NewRequest("PUT", "/bottles", strings.NewReader(strings.Repeat("a", 1024)))
+			Ω(err).ShouldNot(HaveOccurred())
+			req.ContentLength = -1
+			rec := httptest.NewRecorder()
+			service.ServeMux().ServeHTTP(rec, req)
+			Ω(rec.Code).Should(Equal(413))
+		})
+
+		Context("with a smaller stream size", func() {
+			BeforeEach(func() {
+				streamSize = 8
+			})
+
+			It("fails reads past the stream size with 413 Payload Too Large", func() {
+				req, err := http.NewRequest("PUT", "/bottles", strings.NewReader(strings.Repeat("a", 12)))
+				Ω(err).ShouldNot(HaveOccurred())
+				rec := httptest.NewRecorder()
+				service.ServeMux().ServeHTTP(rec, req)
+				Ω(rec.Code).Should(Equal(413))
+				Ω(rec.Body.String()).Should(ContainSubstring("8 bytes"))
+			})
+		})
+	})
+
+	Context("with a handler that reads a multipart form", func() {
+		BeforeEach(func() {
+			maxBodySize = 256
+			handler = func(ctx *goa.Context) error {
+				if _, err := ctx.FormFile("image"); err != nil {
+					return goa.InvalidEncodingError(err, nil)
+				}
+				return ctx.RespondBytes(201, []byte("created"))
+			}
+		})
+
+		JustBeforeEach(func() {
+			route := &goa.Route{Method: "PUT", Path: "/bottles", MaxBodySize: maxBodySize}
+			ctrl := service.NewController("BottleController")
+			service.ServeMux().Handle(route.Method, route.Path, ctrl.HandleRoute(route, "upload", handler, nil))
+		})
+
+		It("rejects forms larger than the maximum size with 413 Payload Too Large", func() {
+			var body bytes.Buffer
+			w := multipart.NewWriter(&body)
+			fw, err := w.CreateFormFile("image", "image.png")
+			Ω(err).ShouldNot(HaveOccurred())
+			fw.Write(bytes.Repeat([]byte("a"), 1024))
+			Ω(w.Close()).Should(Succeed())
+			req, err := http.NewRequest("PUT", "/bottles", &body)
+			Ω(err).ShouldNot(HaveOccurred())
+			req.Header.Set("Content-Type", w.FormDataContentType())
+			req.ContentLength = -1
+			rec := httptest.NewRecorder()
+			service.ServeMux().ServeHTTP(rec, req)
+			Ω(rec.Code).Should(Equal(413))
+		})
+	})
+})
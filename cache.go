@@ -0,0 +1,418 @@
+package goa
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// ResponseCache caches the rendered responses of GET requests so that actions that render the
+	// same media type view over and over do not have to.
+	ResponseCache struct {
+		// Store holds the cached responses.
+		Store ResponseCacheStore
+		// Key computes the key that identifies the response to a request.
+		Key ResponseCacheKeyFunc
+	}
+
+	// ResponseCacheKeyFunc computes the key that identifies the response to a request. Requests
+	// for which the func returns an empty key are not cached.
+	ResponseCacheKeyFunc func(ctx *Context) string
+
+	// ResponseCacheStore is the interface implemented by the stores that hold the cached
+	// responses. The goa package provides an in-memory LRU implementation with
+	// NewMemoryResponseCacheStore, implementations backed by shared stores make it possible to
+	// share cached responses across multiple service instances.
+	ResponseCacheStore interface {
+		// Get returns the response cached with the given key, nil if there is none or if
+		// it expired.
+		Get(key string) (*CachedResponse, error)
+		// Set caches the response with the given key for the given duration.
+		Set(key string, resp *CachedResponse, ttl time.Duration) error
+		// Invalidate discards the responses whose key starts with the given prefix.
+		Invalidate(prefix string) error
+	}
+
+	// CachedResponse is a response held by a ResponseCacheStore.
+	CachedResponse struct {
+		// Status is the response status code.
+		Status int
+		// Header is the response header.
+		Header http.Header
+		// Body is the response body.
+		Body []byte
+		// Created is the time the response was rendered.
+		Created time.Time
+	}
+
+	// cacheWriter is the response writer used by the response cache middleware. It sets the
+	// Cache-Control header of successful responses and records the responses that may be
+	// cached.
+	cacheWriter struct {
+		rw           http.ResponseWriter
+		cacheControl string
+		record       bool
+		code         int
+		buf          bytes.Buffer
+	}
+
+	// memoryResponseCacheStore is the in-memory ResponseCacheStore implementation.
+	memoryResponseCacheStore struct {
+		mu         sync.Mutex
+		maxEntries int
+		entries    map[string]*list.Element
+		lru        *list.List
+	}
+
+	// cacheEntry is a response held by memoryResponseCacheStore.
+	cacheEntry struct {
+		key     string
+		resp    *CachedResponse
+		expires time.Time
+	}
+)
+
+// DefaultResponseCache is the response cache used by Cache and Context.InvalidateCache. It holds
+// up to 1000 responses in memory and uses DefaultResponseCacheKey, set its Store and Key fields
+// to change that.
+var DefaultResponseCache = &ResponseCache{
+	Store: NewMemoryResponseCacheStore(1000),
+	Key:   DefaultResponseCacheKey,
+}
+
+// Cache returns a middleware that sets the "Cache-Control" header of successful responses to the
+// given value and caches the responses of GET requests using DefaultResponseCache.
+// goagen generates code that uses Cache for actions whose design uses the CacheControl DSL, for
+// example:
+//
+//	Action("show", func() {
+//		CacheControl("public", "max-age=60")
+//	})
+//
+// caches the responses of the show action for one minute.
+func Cache(cacheControl string) Middleware {
+	return DefaultResponseCache.Middleware(cacheControl)
+}
+
+// Middleware returns a middleware that sets the "Cache-Control" header of successful responses to
+// the given value. The middleware also caches the 200 responses of GET requests for the duration
+// given by the "s-maxage" directive or by the "max-age" directive if there is no "s-maxage"
+// directive. Responses are not cached if the directives include "private", "no-cache" or
+// "no-store", if the handler streams them or if they set cookies. Responses to requests with an
+// "Authorization" header are only cached if the directives include "public" or "s-maxage" as
+// specified by RFC 7234 section 3.2.
+// Only the headers set or changed by the handler are cached. Cached responses are served without
+// calling the handler, they do not override the headers already set by the outer middlewares and
+// have an "Age" response header.
+// The cache keys start with the name of the designed resource so that Invalidate can discard all
+// the cached responses of a resource actions.
+func (c *ResponseCache) Middleware(cacheControl string) Middleware {
+	ttl := cacheTTL(cacheControl)
+	shared := sharedCacheControl(cacheControl)
+	return func(h Handler) Handler {
+		return func(ctx *Context) error {
+			rw, ok := ctx.Value(respKey).(http.ResponseWriter)
+			if !ok {
+				return h(ctx)
+			}
+			key := ""
+			if req := ctx.Request(); req != nil && req.Method == "GET" && ttl > 0 &&
+				(shared || req.Header.Get("Authorization") == "") {
+				key = c.Key(ctx)
+			}
+			if key != "" {
+				key = ctx.ResourceName() + "|" + key
+				resp, err := c.Store.Get(key)
+				if err != nil {
+					if ctx.Logger != nil {
+						ctx.Error("failed to get cached response", "key", key, "err", err)
+					}
+				} else if resp != nil {
+					header := ctx.Header()
+					for k, v := range resp.Header {
+						if _, ok := header[k]; !ok {
+							header[k] = append([]string(nil), v...)
+						}
+					}
+					header.Set("Age", strconv.Itoa(int(time.Since(resp.Created).Seconds())))
+					return ctx.RespondBytes(resp.Status, resp.Body)
+				}
+			}
+			before := make(http.Header, len(rw.Header()))
+			for k, v := range rw.Header() {
+				before[k] = append([]string(nil), v...)
+			}
+			cw := &cacheWriter{rw: rw, cacheControl: cacheControl, record: key != ""}
+			ctx.SetResponseWriter(cw)
+			err := h(ctx)
+			ctx.SetResponseWriter(rw)
+			if err != nil || !cw.record || cw.code != http.StatusOK || rw.Header().Get("Set-Cookie") != "" {
+				return err
+			}
+			resp := &CachedResponse{
+				Status:  cw.code,
+				Header:  make(http.Header),
+				Body:    cw.buf.Bytes(),
+				Created: time.Now(),
+			}
+			for k, v := range rw.Header() {
+				if !equalValues(before[k], v) {
+					resp.Header[k] = append([]string(nil), v...)
+				}
+			}
+			if serr := c.Store.Set(key, resp, ttl); serr != nil && ctx.Logger != nil {
+				ctx.Error("failed to cache response", "key", key, "err", serr)
+			}
+			return nil
+		}
+	}
+}
+
+// Invalidate discards the cached responses of the actions of the resource with the given name.
+func (c *ResponseCache) Invalidate(resource string) error {
+	return c.Store.Invalidate(resource + "|")
+}
+
+// InvalidateCache discards the responses of the actions of the given resources cached by
+// DefaultResponseCache, it discards the responses of the actions of the resource that handles the
+// request if no resource is given. Actions that modify resources call InvalidateCache so that
+// subsequent requests do not get stale responses.
+func (ctx *Context) InvalidateCache(resources ...string) error {
+	if len(resources) == 0 {
+		resources = []string{ctx.ResourceName()}
+	}
+	for _, r := range resources {
+		if err := DefaultResponseCache.Invalidate(r); err != nil {
+			return fmt.Errorf("failed to invalidate cached responses of %s: %s", r, err)
+		}
+	}
+	return nil
+}
+
+// DefaultResponseCacheKey is the ResponseCacheKeyFunc used by DefaultResponseCache. The key is
+// made of the API version, the route that matched the request, the content type negotiated with
+// the request "Accept" header, the view requested with the "view" parameter and the request URL
+// and querystring parameters as returned by Context.AllParams.
+func DefaultResponseCacheKey(ctx *Context) string {
+	req := ctx.Request()
+	path := req.URL.Path
+	if route := ctx.Route(); route != nil {
+		path = route.Path
+	}
+	return strings.Join([]string{
+		ctx.APIVersion(),
+		req.Method + " " + path,
+		negotiatedContentType(ctx),
+		ctx.Get("view"),
+		ctx.AllParams().Encode(),
+	}, "|")
+}
+
+// NewMemoryResponseCacheStore returns a ResponseCacheStore that holds up to maxEntries responses
+// in memory, the least recently used responses are discarded first when the store is full.
+func NewMemoryResponseCacheStore(maxEntries int) ResponseCacheStore {
+	return &memoryResponseCacheStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Get returns the response cached with the given key.
+func (s *memoryResponseCacheStore) Get(key string) (*CachedResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !time.Now().Before(entry.expires) {
+		s.remove(elem)
+		return nil, nil
+	}
+	s.lru.MoveToFront(elem)
+	return entry.resp, nil
+}
+
+// Set caches the response with the given key.
+func (s *memoryResponseCacheStore) Set(key string, resp *CachedResponse, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("invalid time to live %s", ttl)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := &cacheEntry{key: key, resp: resp, expires: time.Now().Add(ttl)}
+	if elem, ok := s.entries[key]; ok {
+		elem.Value = entry
+		s.lru.MoveToFront(elem)
+		return nil
+	}
+	s.entries[key] = s.lru.PushFront(entry)
+	for s.maxEntries > 0 && s.lru.Len() > s.maxEntries {
+		s.remove(s.lru.Back())
+	}
+	return nil
+}
+
+// Invalidate discards the responses whose key starts with the given prefix.
+func (s *memoryResponseCacheStore) Invalidate(prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, elem := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			s.remove(elem)
+		}
+	}
+	return nil
+}
+
+// remove removes the given element from the store.
+func (s *memoryResponseCacheStore) remove(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.entries, elem.Value.(*cacheEntry).key)
+}
+
+// Header returns the response header.
+func (cw *cacheWriter) Header() http.Header {
+	return cw.rw.Header()
+}
+
+// WriteHeader sets the Cache-Control header of successful responses and writes the header.
+func (cw *cacheWriter) WriteHeader(code int) {
+	if cw.code != 0 {
+		return
+	}
+	cw.code = code
+	if code >= 200 && code < 300 && cw.rw.Header().Get("Cache-Control") == "" {
+		cw.rw.Header().Set("Cache-Control", cw.cacheControl)
+	}
+	cw.rw.WriteHeader(code)
+}
+
+// Write writes the response body and records it if the response may be cached.
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	if cw.code == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.record {
+		cw.buf.Write(b)
+	}
+	return cw.rw.Write(b)
+}
+
+// Flush flushes the response to the client, streamed responses are not cached.
+func (cw *cacheWriter) Flush() {
+	cw.record = false
+	cw.buf.Reset()
+	if f, ok := cw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify returns a channel that receives a value when the client closes the connection, the
+// channel is nil if the underlying response writer cannot detect it.
+func (cw *cacheWriter) CloseNotify() <-chan bool {
+	if cn, ok := cw.rw.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+// Hijack lets the caller take over the connection, e.g. to upgrade it to a WebSocket connection.
+// The response is then not cached.
+func (cw *cacheWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	cw.record = false
+	cw.buf.Reset()
+	return hijack(cw.rw)
+}
+
+// wrapped returns the response writer wrapped by the Cache middleware.
+func (cw *cacheWriter) wrapped() http.ResponseWriter {
+	return cw.rw
+}
+
+// cacheTTL returns the duration the responses with the given Cache-Control header may be cached
+// by the service, 0 if they may not be.
+func cacheTTL(cacheControl string) time.Duration {
+	maxAge, sMaxAge := -1, -1
+	for _, d := range strings.Split(cacheControl, ",") {
+		elems := strings.SplitN(strings.TrimSpace(d), "=", 2)
+		name := strings.ToLower(elems[0])
+		switch name {
+		case "private", "no-cache", "no-store":
+			return 0
+		case "max-age", "s-maxage":
+			if len(elems) != 2 {
+				continue
+			}
+			secs, err := strconv.Atoi(elems[1])
+			if err != nil {
+				continue
+			}
+			if name == "max-age" {
+				maxAge = secs
+			} else {
+				sMaxAge = secs
+			}
+		}
+	}
+	if sMaxAge >= 0 {
+		return time.Duration(sMaxAge) * time.Second
+	}
+	if maxAge > 0 {
+		return time.Duration(maxAge) * time.Second
+	}
+	return 0
+}
+
+// sharedCacheControl returns true if the given Cache-Control header explicitly allows shared caches
+// to store the responses to authenticated requests, i.e. if it has a "public" or "s-maxage"
+// directive.
+func sharedCacheControl(cacheControl string) bool {
+	for _, d := range strings.Split(cacheControl, ",") {
+		name := strings.ToLower(strings.SplitN(strings.TrimSpace(d), "=", 2)[0])
+		if name == "public" || name == "s-maxage" {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues returns true if the given header values are identical.
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// negotiatedContentType returns the content type negotiated with the request "Accept" header
+// among the content types the action produces, see EncodeResponse. It returns the "Accept" header
+// value if the content types cannot be determined.
+func negotiatedContentType(ctx *Context) string {
+	req := ctx.Request()
+	offers := ctx.Produces()
+	if offers == nil {
+		if app, ok := ctx.Service().(*Application); ok {
+			offers = app.offeredContentTypes()
+		}
+	}
+	if offers == nil {
+		return req.Header.Get("Accept")
+	}
+	return negotiateContentType(req, offers)
+}
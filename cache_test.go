@@ -0,0 +1,234 @@
+package goa_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+// cachedBottle is the media type rendered by the cached action.
+type cachedBottle struct {
+	ID       string `json:"id" xml:"id"`
+	View     string `json:"view" xml:"view"`
+	Rendered int    `json:"rendered" xml:"rendered"`
+}
+
+var _ = Describe("ResponseCache", func() {
+	var service goa.Service
+	var cacheControl string
+	var status int
+	var rendered int
+	var authorization string
+
+	serve := func(method, path, accept string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		service.ServeMux().ServeHTTP(rec, req)
+		return rec
+	}
+
+	BeforeEach(func() {
+		goa.DefaultResponseCache.Store = goa.NewMemoryResponseCacheStore(10)
+		service = goa.New("test")
+		cacheControl = "public, max-age=60"
+		status = 200
+		rendered = 0
+		authorization = ""
+	})
+
+	JustBeforeEach(func() {
+		ctrl := service.NewController("BottleController")
+		show := goa.Cache(cacheControl)(func(ctx *goa.Context) error {
+			rendered++
+			if status != 200 {
+				return ctx.RespondBytes(status, []byte("not found"))
+			}
+			return service.(*goa.Application).EncodeResponse(ctx, 200,
+				&cachedBottle{ID: ctx.Get("id"), View: ctx.Get("view"), Rendered: rendered})
+		})
+		update := func(ctx *goa.Context) error {
+			if err := ctx.InvalidateCache(); err != nil {
+				return err
+			}
+			ctx.WriteHeader(204)
+			return nil
+		}
+		route := &goa.Route{Method: "GET", Path: "/bottles/:id", Resource: "bottle", Action: "show"}
+		service.ServeMux().Handle(route.Method, route.Path, ctrl.HandleRoute(route, "show", show, nil))
+		route = &goa.Route{Method: "PUT", Path: "/bottles/:id", Resource: "bottle", Action: "update"}
+		service.ServeMux().Handle(route.Method, route.Path, ctrl.HandleRoute(route, "update", update, nil))
+	})
+
+	It("serves the cached responses", func() {
+		rec := serve("GET", "/bottles/1", "")
+		Ω(rec.Code).Should(Equal(200))
+		Ω(rec.Header().Get("Cache-Control")).Should(Equal("public, max-age=60"))
+		Ω(rec.Header().Get("Age")).Should(BeEmpty())
+		body := rec.Body.String()
+
+		rec = serve("GET", "/bottles/1", "")
+		Ω(rec.Code).Should(Equal(200))
+		Ω(rec.Body.String()).Should(Equal(body))
+		Ω(rec.Header().Get("Cache-Control")).Should(Equal("public, max-age=60"))
+		Ω(rec.Header().Get("Content-Type")).Should(Equal("application/json"))
+		Ω(rec.Header().Get("Age")).Should(Equal("0"))
+		Ω(rendered).Should(Equal(1))
+	})
+
+	It("caches a response per route params, view and content type", func() {
+		serve("GET", "/bottles/1", "")
+		serve("GET", "/bottles/2", "")
+		serve("GET", "/bottles/1?view=tiny", "")
+		rec := serve("GET", "/bottles/1", "application/xml")
+		Ω(rec.Header().Get("Content-Type")).Should(Equal("application/xml"))
+		Ω(rendered).Should(Equal(4))
+		serve("GET", "/bottles/1?view=tiny", "")
+		serve("GET", "/bottles/1", "application/xml")
+		Ω(rendered).Should(Equal(4))
+	})
+
+	It("discards the cached responses when a write action invalidates them", func() {
+		serve("GET", "/bottles/1", "")
+		serve("GET", "/bottles/2", "")
+		rec := serve("PUT", "/bottles/1", "")
+		Ω(rec.Code).Should(Equal(204))
+		serve("GET", "/bottles/1", "")
+		serve("GET", "/bottles/2", "")
+		Ω(rendered).Should(Equal(4))
+	})
+
+	Context("with an outer middleware that sets response headers", func() {
+		var requests int
+
+		BeforeEach(func() {
+			requests = 0
+			service.Use(func(h goa.Handler) goa.Handler {
+				return func(ctx *goa.Context) error {
+					requests++
+					ctx.Header().Set("X-Request-Id", fmt.Sprintf("req-%d", requests))
+					return h(ctx)
+				}
+			})
+		})
+
+		It("does not replay the headers of the cached request", func() {
+			serve("GET", "/bottles/1", "")
+			rec := serve("GET", "/bottles/1", "")
+			Ω(rendered).Should(Equal(1))
+			Ω(rec.Header()["X-Request-Id"]).Should(Equal([]string{"req-2"}))
+			Ω(rec.Header().Get("Content-Type")).Should(Equal("application/json"))
+		})
+	})
+
+	Context("with requests made with different credentials", func() {
+		BeforeEach(func() {
+			cacheControl = "max-age=60"
+		})
+
+		It("does not share the responses", func() {
+			authorization = "Bearer alice"
+			serve("GET", "/bottles/1", "")
+			authorization = "Bearer bob"
+			rec := serve("GET", "/bottles/1", "")
+			Ω(rec.Body.String()).Should(ContainSubstring(`"rendered":2`))
+			Ω(rendered).Should(Equal(2))
+		})
+
+		Context("and public responses", func() {
+			BeforeEach(func() {
+				cacheControl = "public, max-age=60"
+			})
+
+			It("shares the responses", func() {
+				authorization = "Bearer alice"
+				serve("GET", "/bottles/1", "")
+				authorization = "Bearer bob"
+				serve("GET", "/bottles/1", "")
+				Ω(rendered).Should(Equal(1))
+			})
+		})
+	})
+
+	Context("with private responses", func() {
+		BeforeEach(func() {
+			cacheControl = "private, max-age=60"
+		})
+
+		It("sets the Cache-Control header but does not cache them", func() {
+			serve("GET", "/bottles/1", "")
+			rec := serve("GET", "/bottles/1", "")
+			Ω(rec.Header().Get("Cache-Control")).Should(Equal("private, max-age=60"))
+			Ω(rendered).Should(Equal(2))
+		})
+	})
+
+	Context("with error responses", func() {
+		BeforeEach(func() {
+			status = 404
+		})
+
+		It("does not cache them", func() {
+			serve("GET", "/bottles/1", "")
+			rec := serve("GET", "/bottles/1", "")
+			Ω(rec.Code).Should(Equal(404))
+			Ω(rec.Header().Get("Cache-Control")).Should(BeEmpty())
+			Ω(rendered).Should(Equal(2))
+		})
+	})
+})
+
+var _ = Describe("NewMemoryResponseCacheStore", func() {
+	var store goa.ResponseCacheStore
+
+	BeforeEach(func() {
+		store = goa.NewMemoryResponseCacheStore(2)
+	})
+
+	set := func(key string, ttl time.Duration) {
+		err := store.Set(key, &goa.CachedResponse{Status: 200, Body: []byte(key)}, ttl)
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+
+	cached := func(key string) bool {
+		resp, err := store.Get(key)
+		Ω(err).ShouldNot(HaveOccurred())
+		return resp != nil
+	}
+
+	It("discards the least recently used responses", func() {
+		set("a", time.Minute)
+		set("b", time.Minute)
+		Ω(cached("a")).Should(BeTrue())
+		set("c", time.Minute)
+		Ω(cached("a")).Should(BeTrue())
+		Ω(cached("b")).Should(BeFalse())
+		Ω(cached("c")).Should(BeTrue())
+	})
+
+	It("discards the expired responses", func() {
+		set("a", time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+		Ω(cached("a")).Should(BeFalse())
+	})
+
+	It("invalidates the responses by key prefix", func() {
+		for i := 0; i < 2; i++ {
+			set(fmt.Sprintf("bottle|%d", i), time.Minute)
+		}
+		Ω(store.Invalidate("bottle|")).Should(Succeed())
+		Ω(cached("bottle|0")).Should(BeFalse())
+		Ω(cached("bottle|1")).Should(BeFalse())
+	})
+})
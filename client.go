@@ -2,6 +2,8 @@ package goa
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
@@ -14,6 +16,7 @@ import (
 	"strings"
 	"time"
 
+	"golang.org/x/net/context"
 	"gopkg.in/alecthomas/kingpin.v2"
 	"gopkg.in/inconshreveable/log15.v2"
 )
@@ -36,6 +39,9 @@ type (
 		UserAgent string
 		// Dump indicates whether to dump request response.
 		Dump bool
+
+		// ctx is the context whose trace is propagated to the requests, see WithContext.
+		ctx context.Context
 	}
 
 	// Signer is the common interface implemented by all signers.
@@ -53,16 +59,45 @@ type (
 		Username string
 		// Password is err guess what? the basic auth password.
 		Password string
+		// FlagPrefix is prepended to the names of the command line flags registered by
+		// RegisterFlags so that the flags of multiple signers do not clash.
+		FlagPrefix string
+	}
+
+	// APIKeySigner implements API key auth. The key is set in a header or in the URL query
+	// string.
+	APIKeySigner struct {
+		// Header is the name of the HTTP header which contains the key.
+		Header string
+		// Query is the name of the URL query string parameter which contains the key. It is
+		// only used if Header is empty.
+		Query string
+		// Format represents the format used to render the key.
+		// The default is "%s"
+		Format string
+		// FlagPrefix is prepended to the names of the command line flags registered by
+		// RegisterFlags so that the flags of multiple signers do not clash.
+		FlagPrefix string
+
+		// key stores the actual API key.
+		key string
 	}
 
-	// JWTSigner implements JSON Web Token auth.
+	// JWTSigner implements JSON Web Token auth. The token is set in a header or in the URL
+	// query string.
 	JWTSigner struct {
 		// Header is the name of the HTTP header which contains the JWT.
-		// The default is "Authentication"
+		// The default is "Authorization"
 		Header string
+		// Query is the name of the URL query string parameter which contains the JWT. It is
+		// only used if Header is empty.
+		Query string
 		// Format represents the format used to render the JWT.
-		// The default is "Bearer %s"
+		// The default is "Bearer %s" for headers and "%s" for query strings.
 		Format string
+		// FlagPrefix is prepended to the names of the command line flags registered by
+		// RegisterFlags so that the flags of multiple signers do not clash.
+		FlagPrefix string
 
 		// token stores the actual JWT.
 		token string
@@ -89,6 +124,9 @@ type (
 		// RefreshToken contains the OAuth2 refresh token from which access tokens are
 		// created.
 		RefreshToken string
+		// FlagPrefix is prepended to the names of the command line flags registered by
+		// RegisterFlags so that the flags of multiple signers do not clash.
+		FlagPrefix string
 
 		// accessToken is the temporary access token.
 		accessToken string
@@ -104,9 +142,30 @@ func NewClient() *Client {
 	return &Client{Logger: logger, Client: http.DefaultClient}
 }
 
-// Do wraps the underlying http client Do method and adds logging.
+// WithContext returns a copy of the client that propagates the trace of the given context to the
+// requests it makes, see ContextTraceParent. Use it to make requests while handling a request,
+// e.g. with the handler goa.Context.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+// Do wraps the underlying http client Do method and adds logging. Do requests compressed
+// responses unless the request already sets the "Accept-Encoding" header and decompresses the
+// bodies of gzip and deflate encoded responses transparently. Do sets the "traceparent" and
+// "tracestate" headers if the client was created with WithContext and the context carries a
+// trace.
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	req.Header.Set("User-Agent", c.UserAgent)
+	if c.ctx != nil && req.Header.Get("traceparent") == "" {
+		if tp := ContextTraceParent(c.ctx); tp != nil {
+			tp.SetHeaders(req.Header)
+		}
+	}
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+	}
 	var reqBody []byte
 	startedAt := time.Now()
 	id := shortID()
@@ -120,6 +179,10 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err = decompressBody(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
 	if c.Dump {
 		c.dumpResponse(resp, req, reqBody)
 	} else {
@@ -128,6 +191,47 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	return resp, err
 }
 
+// decompressBody replaces the body of gzip and deflate encoded responses with a reader that
+// decompresses it. It removes the "Content-Encoding" and "Content-Length" headers and sets the
+// response Uncompressed field so that the response describes the decompressed body.
+func decompressBody(resp *http.Response) error {
+	var body io.ReadCloser
+	var err error
+	switch strings.ToLower(resp.Header.Get("Content-Encoding")) {
+	case "gzip", "x-gzip":
+		body, err = gzip.NewReader(resp.Body)
+	case "deflate":
+		body, err = zlib.NewReader(resp.Body)
+	default:
+		return nil
+	}
+	if err == io.EOF {
+		body, err = ioutil.NopCloser(resp.Body), nil // Empty body
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decompress response body: %s", err)
+	}
+	resp.Body = &decompressedBody{ReadCloser: body, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// decompressedBody is the body of a response decompressed by the client, closing it closes the
+// raw response body.
+type decompressedBody struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+// Close closes the decompressor and the raw response body.
+func (b *decompressedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.raw.Close()
+}
+
 // Sign adds the basic auth header to the request.
 func (s *BasicSigner) Sign(req *http.Request) error {
 	if s.Username != "" && s.Password != "" {
@@ -138,12 +242,47 @@ func (s *BasicSigner) Sign(req *http.Request) error {
 
 // RegisterFlags adds the "--user" and "--pass" flags to the client tool.
 func (s *BasicSigner) RegisterFlags(app *kingpin.Application) {
-	app.Flag("user", "Basic Auth username").StringVar(&s.Username)
-	app.Flag("pass", "Basic Auth password").StringVar(&s.Password)
+	app.Flag(s.FlagPrefix+"user", "Basic Auth username").StringVar(&s.Username)
+	app.Flag(s.FlagPrefix+"pass", "Basic Auth password").StringVar(&s.Password)
+}
+
+// Sign adds the API key header or query string parameter.
+func (s *APIKeySigner) Sign(req *http.Request) error {
+	if s.key == "" {
+		return nil
+	}
+	format := s.Format
+	if format == "" {
+		format = "%s"
+	}
+	key := fmt.Sprintf(format, s.key)
+	if s.Header != "" {
+		req.Header.Set(s.Header, key)
+		return nil
+	}
+	values := req.URL.Query()
+	values.Set(s.Query, key)
+	req.URL.RawQuery = values.Encode()
+	return nil
 }
 
-// Sign adds the JWT auth header.
+// RegisterFlags adds the "--key" flag to the client tool.
+func (s *APIKeySigner) RegisterFlags(app *kingpin.Application) {
+	app.Flag(s.FlagPrefix+"key", "API key").StringVar(&s.key)
+}
+
+// Sign adds the JWT auth header or query string parameter.
 func (s *JWTSigner) Sign(req *http.Request) error {
+	if s.Header == "" && s.Query != "" {
+		format := s.Format
+		if format == "" {
+			format = "%s"
+		}
+		values := req.URL.Query()
+		values.Set(s.Query, fmt.Sprintf(format, s.token))
+		req.URL.RawQuery = values.Encode()
+		return nil
+	}
 	header := s.Header
 	if header == "" {
 		header = "Authorization"
@@ -158,7 +297,7 @@ func (s *JWTSigner) Sign(req *http.Request) error {
 
 // RegisterFlags adds the "--jwt" flag to the client tool.
 func (s *JWTSigner) RegisterFlags(app *kingpin.Application) {
-	app.Flag("jwt", "JSON web token").StringVar(&s.token)
+	app.Flag(s.FlagPrefix+"jwt", "JSON web token").StringVar(&s.token)
 }
 
 // Sign refreshes the access token if needed and adds the OAuth header.
@@ -174,9 +313,9 @@ func (s *OAuth2Signer) Sign(req *http.Request) error {
 
 // RegisterFlags adds the "--refreshURL" and "--refreshToken" flags to the client tool.
 func (s *OAuth2Signer) RegisterFlags(app *kingpin.Application) {
-	app.Flag("refreshURL", "OAuth2 refresh URL format, e.g. https://somewhere.com/token?grant_type=authorization_code&code=%s&client_id=xxx").
+	app.Flag(s.FlagPrefix+"refreshURL", "OAuth2 refresh URL format, e.g. https://somewhere.com/token?grant_type=authorization_code&code=%s&client_id=xxx").
 		StringVar(&s.RefreshURLFormat)
-	app.Flag("refreshToken", "OAuth2 refresh token or authorization code").
+	app.Flag(s.FlagPrefix+"refreshToken", "OAuth2 refresh token or authorization code").
 		StringVar(&s.RefreshToken)
 }
 
@@ -0,0 +1,311 @@
+package goa
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// compressWriter is the response writer used by the Compress middleware. It buffers the
+	// response body until it is large enough to be worth compressing, then writes it through a
+	// compressor if the response is not already compressed.
+	compressWriter struct {
+		rw       http.ResponseWriter
+		pools    *compressorPools
+		encoding string
+		minSize  int
+		code     int
+		buf      bytes.Buffer
+		decided  bool
+		comp     compressor
+		wire     int
+	}
+
+	// compressor is the interface implemented by gzip.Writer and zlib.Writer.
+	compressor interface {
+		io.WriteCloser
+		Flush() error
+		Reset(io.Writer)
+	}
+
+	// compressorPools contains the pools of compressors used by a Compress middleware.
+	compressorPools struct {
+		gzip sync.Pool
+		zlib sync.Pool
+	}
+
+	// countingWriter counts the bytes written to the underlying writer.
+	countingWriter struct {
+		w io.Writer
+		n *int
+	}
+)
+
+// compressedContentTypes lists the prefixes of the content types of response bodies that are
+// already compressed.
+var compressedContentTypes = []string{
+	"image/",
+	"audio/",
+	"video/",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-bzip2",
+	"application/x-7z-compressed",
+}
+
+// Compress returns a middleware that compresses the response bodies with gzip or deflate (zlib
+// format) when the request "Accept-Encoding" header accepts it, gzip is preferred if the client
+// accepts both equally. level is the compression level as defined by the compress/flate package,
+// e.g. flate.DefaultCompression, invalid levels fall back to flate.DefaultCompression. Bodies
+// smaller than minSize bytes and bodies that are already compressed, i.e. responses that define
+// the "Content-Encoding" header or whose content type is an image, audio or video type or a
+// compressed archive type, are written as is.
+// Streamed responses are compressed as soon as they are flushed so that each flush sends the data
+// written so far to the client. Requests that upgrade the connection, e.g. WebSocket handshakes,
+// are passed through untouched.
+// The middleware compresses the data written by the handler to the context so that
+// Context.ResponseLength returns the length of the uncompressed body and
+// Context.ResponseWireLength the number of body bytes actually sent to the client.
+func Compress(level, minSize int) Middleware {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		level = flate.DefaultCompression
+	}
+	pools := &compressorPools{
+		gzip: sync.Pool{New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(nil, level)
+			return w
+		}},
+		zlib: sync.Pool{New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(nil, level)
+			return w
+		}},
+	}
+	return func(h Handler) Handler {
+		return func(ctx *Context) error {
+			req := ctx.Request()
+			rw, ok := ctx.Value(respKey).(http.ResponseWriter)
+			if req == nil || !ok || req.Method == "HEAD" || req.Header.Get("Range") != "" ||
+				headerContains(req.Header, "Connection", "upgrade") {
+				return h(ctx)
+			}
+			rw.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"))
+			if encoding == "" {
+				return h(ctx)
+			}
+			cw := &compressWriter{rw: rw, pools: pools, encoding: encoding, minSize: minSize}
+			ctx.SetResponseWriter(cw)
+			err := h(ctx)
+			if cerr := cw.Close(); err == nil {
+				err = cerr
+			}
+			ctx.SetResponseWriter(rw)
+			ctx.SetValue(respWireLenKey, cw.wire)
+			return err
+		}
+	}
+}
+
+// negotiateEncoding returns the preferred compression encoding accepted by the given
+// "Accept-Encoding" header value, empty string if the client accepts neither gzip nor deflate.
+func negotiateEncoding(accept string) string {
+	var best string
+	var bestQ float64
+	for _, elem := range strings.Split(accept, ",") {
+		coding, params, err := mime.ParseMediaType(strings.TrimSpace(elem))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		var candidates []string
+		switch coding {
+		case "gzip", "x-gzip":
+			candidates = []string{"gzip"}
+		case "deflate":
+			candidates = []string{"deflate"}
+		case "*":
+			candidates = []string{"gzip", "deflate"}
+		}
+		for _, c := range candidates {
+			if q > bestQ || (q == bestQ && c == "gzip" && best != "gzip") {
+				best, bestQ = c, q
+			}
+		}
+	}
+	return best
+}
+
+// Header returns the response header.
+func (cw *compressWriter) Header() http.Header {
+	return cw.rw.Header()
+}
+
+// WriteHeader records the response status code, the header is written once the writer knows
+// whether the body is compressed.
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.code == 0 {
+		cw.code = code
+	}
+}
+
+// Write buffers the data until the body is large enough to be compressed.
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.code == 0 {
+		cw.code = http.StatusOK
+	}
+	if cw.decided {
+		return cw.write(b)
+	}
+	cw.buf.Write(b)
+	if cw.buf.Len() >= cw.minSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush decides whether to compress the body if it has not done so yet and flushes the data
+// written so far to the client.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.code == 0 {
+			cw.code = http.StatusOK
+		}
+		if err := cw.decide(true); err != nil {
+			return
+		}
+	}
+	if cw.comp != nil {
+		cw.comp.Flush()
+	}
+	if f, ok := cw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify returns a channel that receives a value when the client closes the connection, the
+// channel is nil if the underlying response writer cannot detect it.
+func (cw *compressWriter) CloseNotify() <-chan bool {
+	if cn, ok := cw.rw.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+// Hijack lets the caller take over the connection, e.g. to upgrade it to a WebSocket connection.
+// The data written to the connection is not compressed.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	cw.decided = true
+	return hijack(cw.rw)
+}
+
+// wrapped returns the response writer wrapped by the Compress middleware.
+func (cw *compressWriter) wrapped() http.ResponseWriter {
+	return cw.rw
+}
+
+// Close writes the buffered data and completes the compressed stream if any.
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.code == 0 {
+			return nil // Nothing was written, let the error handler respond.
+		}
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+	if cw.comp == nil {
+		return nil
+	}
+	err := cw.comp.Close()
+	cw.comp.Reset(nil)
+	if cw.encoding == "gzip" {
+		cw.pools.gzip.Put(cw.comp)
+	} else {
+		cw.pools.zlib.Put(cw.comp)
+	}
+	cw.comp = nil
+	return err
+}
+
+// decide writes the response header and the buffered data compressing it if compress is true and
+// the response is not already compressed.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	header := cw.rw.Header()
+	if compress && header.Get("Content-Encoding") == "" && !isCompressed(header.Get("Content-Type")) &&
+		cw.code != http.StatusNoContent && cw.code != http.StatusNotModified {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		out := &countingWriter{w: cw.rw, n: &cw.wire}
+		if cw.encoding == "gzip" {
+			cw.comp = cw.pools.gzip.Get().(*gzip.Writer)
+		} else {
+			cw.comp = cw.pools.zlib.Get().(*zlib.Writer)
+		}
+		cw.comp.Reset(out)
+	}
+	cw.rw.WriteHeader(cw.code)
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	_, err := cw.write(cw.buf.Bytes())
+	cw.buf.Reset()
+	return err
+}
+
+// write writes the data to the compressor if any, to the underlying response writer otherwise.
+func (cw *compressWriter) write(b []byte) (int, error) {
+	if cw.comp != nil {
+		return cw.comp.Write(b)
+	}
+	n, err := cw.rw.Write(b)
+	cw.wire += n
+	return n, err
+}
+
+// Write writes to the underlying writer and counts the bytes written.
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	*w.n += n
+	return n, err
+}
+
+// isCompressed returns true if the given content type is the type of already compressed data.
+func isCompressed(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	if contentType == "image/svg+xml" {
+		return false
+	}
+	for _, prefix := range compressedContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package goa_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+var _ = Describe("Compress", func() {
+	var level int
+	var acceptEncoding string
+	var contentType string
+	var body string
+	var rec *httptest.ResponseRecorder
+	var ctx *goa.Context
+
+	JustBeforeEach(func() {
+		req, err := http.NewRequest("GET", "/bottles", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rec = httptest.NewRecorder()
+		ctx = goa.NewContext(nil, goa.New("test"), req, rec, url.Values{})
+		h := func(ctx *goa.Context) error {
+			if contentType != "" {
+				ctx.Header().Set("Content-Type", contentType)
+			}
+			return ctx.RespondBytes(200, []byte(body))
+		}
+		err = goa.Compress(level, 100)(h)(ctx)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	BeforeEach(func() {
+		level = flate.DefaultCompression
+		acceptEncoding = "gzip, deflate"
+		contentType = "application/json"
+		body = strings.Repeat(`{"name":"bottle"}`, 20)
+	})
+
+	It("compresses the response with gzip", func() {
+		Ω(rec.Code).Should(Equal(200))
+		Ω(rec.Header().Get("Content-Encoding")).Should(Equal("gzip"))
+		Ω(rec.Header().Get("Vary")).Should(Equal("Accept-Encoding"))
+		r, err := gzip.NewReader(rec.Body)
+		Ω(err).ShouldNot(HaveOccurred())
+		b, err := ioutil.ReadAll(r)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(b)).Should(Equal(body))
+	})
+
+	Context("with an invalid compression level", func() {
+		BeforeEach(func() {
+			level = 42
+		})
+
+		It("uses the default compression level", func() {
+			Ω(rec.Header().Get("Content-Encoding")).Should(Equal("gzip"))
+			r, err := gzip.NewReader(rec.Body)
+			Ω(err).ShouldNot(HaveOccurred())
+			b, err := ioutil.ReadAll(r)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(Equal(body))
+		})
+	})
+
+	It("reports the uncompressed and wire lengths", func() {
+		Ω(ctx.ResponseLength()).Should(Equal(len(body)))
+		Ω(ctx.ResponseWireLength()).Should(Equal(rec.Body.Len()))
+		Ω(ctx.ResponseWireLength()).Should(BeNumerically("<", len(body)))
+	})
+
+	Context("with a client that prefers deflate", func() {
+		BeforeEach(func() {
+			acceptEncoding = "gzip;q=0.5, deflate"
+		})
+
+		It("compresses the response with deflate", func() {
+			Ω(rec.Header().Get("Content-Encoding")).Should(Equal("deflate"))
+			r, err := zlib.NewReader(rec.Body)
+			Ω(err).ShouldNot(HaveOccurred())
+			b, err := ioutil.ReadAll(r)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(Equal(body))
+		})
+	})
+
+	Context("with a client that does not accept compressed responses", func() {
+		BeforeEach(func() {
+			acceptEncoding = "identity, gzip;q=0"
+		})
+
+		It("does not compress the response", func() {
+			Ω(rec.Header().Get("Content-Encoding")).Should(BeEmpty())
+			Ω(rec.Body.String()).Should(Equal(body))
+			Ω(ctx.ResponseWireLength()).Should(Equal(len(body)))
+		})
+	})
+
+	Context("with a small response", func() {
+		BeforeEach(func() {
+			body = `{"name":"bottle"}`
+		})
+
+		It("does not compress the response", func() {
+			Ω(rec.Code).Should(Equal(200))
+			Ω(rec.Header().Get("Content-Encoding")).Should(BeEmpty())
+			Ω(rec.Body.String()).Should(Equal(body))
+		})
+	})
+
+	Context("with an already compressed response", func() {
+		BeforeEach(func() {
+			contentType = "image/png"
+		})
+
+		It("does not compress the response", func() {
+			Ω(rec.Header().Get("Content-Encoding")).Should(BeEmpty())
+			Ω(rec.Body.String()).Should(Equal(body))
+		})
+	})
+})
+
+var _ = Describe("Client", func() {
+	var server *httptest.Server
+	var acceptEncoding string
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			acceptEncoding = req.Header.Get("Accept-Encoding")
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			w.Write([]byte("compressed"))
+			w.Close()
+			rw.Header().Set("Content-Encoding", "gzip")
+			rw.Write(buf.Bytes())
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("requests and decompresses compressed responses", func() {
+		req, err := http.NewRequest("GET", server.URL, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		resp, err := goa.NewClient().Do(req)
+		Ω(err).ShouldNot(HaveOccurred())
+		defer resp.Body.Close()
+		Ω(acceptEncoding).Should(Equal("gzip, deflate"))
+		Ω(resp.Header.Get("Content-Encoding")).Should(BeEmpty())
+		Ω(resp.Uncompressed).Should(BeTrue())
+		b, err := ioutil.ReadAll(resp.Body)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(b)).Should(Equal("compressed"))
+	})
+})
@@ -2,8 +2,11 @@ package goa
 
 import (
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"golang.org/x/net/context"
 	log "gopkg.in/inconshreveable/log15.v2"
@@ -17,6 +20,14 @@ type Context struct {
 	log.Logger      // Context logger
 }
 
+// streamWriter is the writer given to the functions that stream response bodies, it flushes the
+// data to the client as it is written and counts the number of bytes written.
+type streamWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+	n       int
+}
+
 // key is the type used to store internal values in the context.
 // Context provides typed accessor methods to these values.
 type key int
@@ -30,6 +41,15 @@ const (
 	respWrittenKey
 	respStatusKey
 	respLenKey
+	respWireLenKey
+	securityScopesKey
+	producesKey
+	resourceNameKey
+	actionNameKey
+	apiVersionKey
+	routeKey
+	traceParentKey
+	spanKey
 )
 
 // NewContext builds a goa context from the given context.Context and request state.
@@ -120,6 +140,69 @@ func (ctx *Context) ResponseLength() int {
 	return 0
 }
 
+// ResponseWireLength returns the number of response body bytes sent to the client. It differs from
+// ResponseLength when the Compress middleware compressed the response body.
+func (ctx *Context) ResponseWireLength() int {
+	if is := ctx.Value(respWireLenKey); is != nil {
+		return is.(int)
+	}
+	return ctx.ResponseLength()
+}
+
+// ResourceName returns the name of the designed resource whose action handles the request, empty
+// if the controller handling the request does not implement a designed resource.
+func (ctx *Context) ResourceName() string {
+	if name := ctx.Value(resourceNameKey); name != nil {
+		return name.(string)
+	}
+	return ""
+}
+
+// ActionName returns the name of the action that handles the request, the code generated by
+// goagen uses the name of the designed action.
+func (ctx *Context) ActionName() string {
+	if name := ctx.Value(actionNameKey); name != nil {
+		return name.(string)
+	}
+	return ""
+}
+
+// APIVersion returns the API version of the resource whose action handles the request, empty for
+// unversioned resources.
+func (ctx *Context) APIVersion() string {
+	if version := ctx.Value(apiVersionKey); version != nil {
+		return version.(string)
+	}
+	return ""
+}
+
+// Route returns the route that matched the request, nil if the request handler was not created
+// with the controller HandleRoute method.
+func (ctx *Context) Route() *Route {
+	if route := ctx.Value(routeKey); route != nil {
+		return route.(*Route)
+	}
+	return nil
+}
+
+// RequiredScopes returns the security scopes required by the action as set by RequireSecurity,
+// nil if the action does not require any.
+func (ctx *Context) RequiredScopes() []string {
+	if s := ctx.Value(securityScopesKey); s != nil {
+		return s.([]string)
+	}
+	return nil
+}
+
+// Produces returns the content types the action may use to render response bodies as set by
+// Produces, nil if the action does not restrict them.
+func (ctx *Context) Produces() []string {
+	if p := ctx.Value(producesKey); p != nil {
+		return p.([]string)
+	}
+	return nil
+}
+
 // Get returns the param or querystring value with the given name.
 func (ctx *Context) Get(name string) string {
 	iparams := ctx.Value(paramsKey)
@@ -177,11 +260,86 @@ func (ctx *Context) RespondBytes(code int, body []byte) error {
 	return nil
 }
 
-// Respond serializes the given body matching the request Accept header against the service
-// encoders. It uses the default service encoder if no match is found.
-func (ctx *Context) Respond(code int, body interface{}) error {
+// RespondStream writes the given HTTP status code and calls fn to stream the response body. The
+// data written by fn is flushed to the client as it is written so that the response uses chunked
+// transfer encoding unless the Content-Length header is set. Any error returned by fn is returned
+// as is, note that the response status code cannot be changed once fn writes to the body.
+// This method should only be called once per request.
+func (ctx *Context) RespondStream(code int, fn func(w io.Writer) error) error {
+	rw, ok := ctx.Value(respKey).(http.ResponseWriter)
+	if !ok {
+		return fmt.Errorf("response writer not initialized")
+	}
 	ctx.WriteHeader(code)
-	return ctx.Service().EncodeResponse(ctx, body)
+	sw := &streamWriter{w: rw}
+	sw.flusher, _ = rw.(http.Flusher)
+	err := fn(sw)
+	ctx.Context = context.WithValue(ctx.Context, respLenKey, ctx.ResponseLength()+sw.n)
+	return err
+}
+
+// StreamBody returns the request body for actions that read it as a raw stream instead of relying
+// on the service decoders. Reading more than maxSize bytes from the body fails with an error with
+// ID ErrPayloadTooLarge if maxSize is greater than 0, HandleRoute responds with 413 Payload Too
+// Large if the handler returns an error after such a read.
+func (ctx *Context) StreamBody(maxSize int64) io.Reader {
+	req := ctx.Request()
+	if req == nil || req.Body == nil {
+		return strings.NewReader("")
+	}
+	if maxSize > 0 {
+		body := newLimitedBody(req.Body, maxSize)
+		req.Body = body
+		return body
+	}
+	return req.Body
+}
+
+// FormFile returns the header of the first file uploaded with the given form field name in the
+// multipart form request body, nil if there is none. The request body is parsed the first time
+// FormFile is called, see MaxFormMemory.
+func (ctx *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := ctx.multipartForm()
+	if err != nil {
+		if err == http.ErrNotMultipart {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if fhs := form.File[name]; len(fhs) > 0 {
+		return fhs[0], nil
+	}
+	return nil, nil
+}
+
+// multipartForm parses the multipart form request body if it hasn't been parsed yet and returns
+// the form.
+func (ctx *Context) multipartForm() (*multipart.Form, error) {
+	req := ctx.Request()
+	if req == nil {
+		return nil, http.ErrNotMultipart
+	}
+	if req.MultipartForm == nil {
+		if err := req.ParseMultipartForm(MaxFormMemory); err != nil {
+			return nil, err
+		}
+	}
+	return req.MultipartForm, nil
+}
+
+// Respond serializes the given body using the service encoder that matches the request Accept
+// header and writes the response with the given status code, see Service.EncodeResponse. It
+// returns an error with ID ErrNotAcceptable and does not write the response if no acceptable
+// content type is available.
+func (ctx *Context) Respond(code int, body interface{}) error {
+	return ctx.Service().EncodeResponse(ctx, code, body)
+}
+
+// RespondProblem writes the given problem details using the problem status as response status
+// code. The body is serialized matching the request Accept header against the service encoders,
+// see Service.EncodeProblem.
+func (ctx *Context) RespondProblem(p *Problem) error {
+	return ctx.Service().EncodeProblem(ctx, p)
 }
 
 // BadRequest sends a HTTP response with status code 400 and the given error as body.
@@ -224,3 +382,13 @@ func (ctx *Context) Write(body []byte) (int, error) {
 	}
 	return 0, fmt.Errorf("response writer not initialized")
 }
+
+// Write writes the data to the response and flushes it to the client.
+func (sw *streamWriter) Write(b []byte) (int, error) {
+	n, err := sw.w.Write(b)
+	sw.n += n
+	if err == nil && sw.flusher != nil {
+		sw.flusher.Flush()
+	}
+	return n, err
+}
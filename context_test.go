@@ -3,7 +3,10 @@ package goa_test
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 
@@ -200,6 +203,64 @@ var _ = Describe("Context", func() {
 				Ω(string(tw.Body)).Should(ContainSubstring(err.Error()))
 			})
 		})
+
+		Context("RespondStream", func() {
+			BeforeEach(func() {
+				rw = httptest.NewRecorder()
+				handler = func(c *goa.Context) error {
+					ctx = c
+					return c.RespondStream(respStatus, func(w io.Writer) error {
+						for i := 0; i < 3; i++ {
+							if _, err := w.Write(respContent); err != nil {
+								return err
+							}
+						}
+						return nil
+					})
+				}
+			})
+
+			It("streams the response body", func() {
+				rec := rw.(*httptest.ResponseRecorder)
+				Ω(rec.Code).Should(Equal(respStatus))
+				Ω(rec.Flushed).Should(BeTrue())
+				Ω(rec.Body.String()).Should(Equal(strings.Repeat(string(respContent), 3)))
+				Ω(ctx.ResponseLength()).Should(Equal(3 * len(respContent)))
+			})
+		})
+
+		Context("StreamBody", func() {
+			var body []byte
+			var readErr error
+
+			BeforeEach(func() {
+				unmarshaler = nil
+				body = nil
+				readErr = nil
+				handler = func(c *goa.Context) error {
+					ctx = c
+					body, readErr = ioutil.ReadAll(c.StreamBody(int64(len(reqBody))))
+					return c.RespondBytes(respStatus, nil)
+				}
+			})
+
+			It("returns the raw request body", func() {
+				Ω(readErr).ShouldNot(HaveOccurred())
+				Ω(string(body)).Should(Equal(reqBody))
+				Ω(ctx.RawPayload()).Should(BeNil())
+			})
+
+			Context("with a request body larger than the limit", func() {
+				BeforeEach(func() {
+					request.Body = ioutil.NopCloser(strings.NewReader(reqBody + "more"))
+				})
+
+				It("fails to read past the limit", func() {
+					Ω(readErr).Should(HaveOccurred())
+					Ω(string(body)).Should(Equal(reqBody))
+				})
+			})
+		})
 	})
 
 })
@@ -0,0 +1,177 @@
+package goa
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSPolicy describes the cross-origin resource sharing policy that applies to requests sent by
+// a given origin. Code generated by goagen initializes the policies from the design Origin DSL.
+type CORSPolicy struct {
+	// Origin is the origin the policy applies to: an exact origin such as
+	// "http://example.com", a pattern containing a single "*" wildcard such as
+	// "https://*.example.com" or "*" to match all origins.
+	Origin string
+	// Methods lists the HTTP methods allowed by preflight requests. The method requested by
+	// the preflight request is allowed if empty.
+	Methods []string
+	// Headers lists the request headers allowed by preflight requests, "*" allows the headers
+	// requested by the preflight request.
+	Headers []string
+	// Exposed lists the response headers exposed to the client.
+	Exposed []string
+	// MaxAge is the number of seconds the client may cache the preflight response.
+	MaxAge uint
+	// Credentials is true if the client may send credentials with the request.
+	Credentials bool
+}
+
+// CORS returns a middleware that adds the CORS headers to the responses of requests whose
+// "Origin" header matches one of the given policies. The headers are set before the handler runs
+// so that error responses also include them.
+// Code generated by goagen uses CORS to wrap the handlers of resources that define CORS policies.
+func CORS(policies ...*CORSPolicy) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx *Context) error {
+			origin := ctx.Request().Header.Get("Origin")
+			if p := MatchCORSPolicy(origin, policies); p != nil {
+				if header := ctx.Header(); header != nil {
+					p.setAllowOrigin(header, origin)
+					if len(p.Exposed) > 0 {
+						header.Set("Access-Control-Expose-Headers", strings.Join(p.Exposed, ", "))
+					}
+				}
+			}
+			return h(ctx)
+		}
+	}
+}
+
+// CORSPreflight returns a handler that responds to CORS preflight OPTIONS requests. The response
+// includes the CORS headers if the request "Origin" header matches one of the given policies and
+// the requested method is allowed by the policy, the response has no CORS header otherwise which
+// causes the client to reject the actual request.
+// Code generated by goagen mounts CORSPreflight on the paths of resources that define CORS
+// policies.
+func CORSPreflight(policies ...*CORSPolicy) Handler {
+	return func(ctx *Context) error {
+		req := ctx.Request()
+		origin := req.Header.Get("Origin")
+		method := req.Header.Get("Access-Control-Request-Method")
+		p := MatchCORSPolicy(origin, policies)
+		if p == nil || method == "" || !p.allowsMethod(method) {
+			return ctx.RespondBytes(200, nil)
+		}
+		header := ctx.Header()
+		if header == nil {
+			return ctx.RespondBytes(200, nil)
+		}
+		p.setAllowOrigin(header, origin)
+		if len(p.Methods) > 0 {
+			header.Set("Access-Control-Allow-Methods", strings.Join(p.Methods, ", "))
+		} else {
+			header.Set("Access-Control-Allow-Methods", method)
+		}
+		requested := req.Header.Get("Access-Control-Request-Headers")
+		if allowed := p.allowedHeaders(requested); allowed != "" {
+			header.Set("Access-Control-Allow-Headers", allowed)
+		}
+		if p.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.FormatUint(uint64(p.MaxAge), 10))
+		}
+		return ctx.RespondBytes(200, nil)
+	}
+}
+
+// MatchCORSPolicy returns the policy that applies to the given origin, nil if there is none.
+// Policies whose origin is an exact match take precedence over policies that use wildcards.
+func MatchCORSPolicy(origin string, policies []*CORSPolicy) *CORSPolicy {
+	if origin == "" {
+		return nil
+	}
+	for _, p := range policies {
+		if strings.EqualFold(p.Origin, origin) {
+			return p
+		}
+	}
+	for _, p := range policies {
+		if MatchOrigin(origin, p.Origin) {
+			return p
+		}
+	}
+	return nil
+}
+
+// MatchOrigin returns true if the given origin matches spec. spec is either an exact origin, a
+// pattern containing a single "*" wildcard that matches one or more characters or "*" which
+// matches all origins.
+func MatchOrigin(origin, spec string) bool {
+	if origin == "" {
+		return false
+	}
+	if spec == "*" {
+		return true
+	}
+	elems := strings.SplitN(spec, "*", 2)
+	if len(elems) == 1 {
+		return strings.EqualFold(origin, spec)
+	}
+	prefix, suffix := strings.ToLower(elems[0]), strings.ToLower(elems[1])
+	origin = strings.ToLower(origin)
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+// setAllowOrigin sets the "Access-Control-Allow-Origin" and "Access-Control-Allow-Credentials"
+// headers. The request origin is echoed back unless the policy applies to all origins and does not
+// allow credentials.
+func (p *CORSPolicy) setAllowOrigin(h http.Header, origin string) {
+	if p.Origin == "*" && !p.Credentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if !varies(h, "Origin") {
+		h.Add("Vary", "Origin")
+	}
+	if p.Credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// varies returns true if the Vary header values already list the given request header.
+func varies(h http.Header, name string) bool {
+	for _, v := range h["Vary"] {
+		for _, n := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(n), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowsMethod returns true if the policy allows the given method.
+func (p *CORSPolicy) allowsMethod(method string) bool {
+	if len(p.Methods) == 0 {
+		return true
+	}
+	for _, m := range p.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedHeaders returns the value of the "Access-Control-Allow-Headers" header given the value of
+// the preflight request "Access-Control-Request-Headers" header.
+func (p *CORSPolicy) allowedHeaders(requested string) string {
+	for _, h := range p.Headers {
+		if h == "*" {
+			return requested
+		}
+	}
+	return strings.Join(p.Headers, ", ")
+}
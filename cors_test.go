@@ -0,0 +1,151 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+var _ = Describe("MatchOrigin", func() {
+	It("matches exact origins", func() {
+		Ω(goa.MatchOrigin("http://example.com", "http://example.com")).Should(BeTrue())
+		Ω(goa.MatchOrigin("http://example.com", "http://Example.com")).Should(BeTrue())
+		Ω(goa.MatchOrigin("http://example.org", "http://example.com")).Should(BeFalse())
+	})
+
+	It("matches wildcards", func() {
+		Ω(goa.MatchOrigin("http://example.com", "*")).Should(BeTrue())
+		Ω(goa.MatchOrigin("https://api.example.com", "https://*.example.com")).Should(BeTrue())
+		Ω(goa.MatchOrigin("https://example.com", "https://*.example.com")).Should(BeFalse())
+		Ω(goa.MatchOrigin("http://api.example.com", "https://*.example.com")).Should(BeFalse())
+	})
+
+	It("does not match empty origins", func() {
+		Ω(goa.MatchOrigin("", "*")).Should(BeFalse())
+	})
+})
+
+var _ = Describe("CORS", func() {
+	var policies []*goa.CORSPolicy
+	var origin string
+	var preflight bool
+	var rw *TestResponseWriter
+	var handlerCalled bool
+	var err error
+
+	BeforeEach(func() {
+		policies = []*goa.CORSPolicy{
+			{Origin: "*"},
+			{
+				Origin:      "http://example.com",
+				Methods:     []string{"GET", "POST"},
+				Headers:     []string{"X-Shared-Secret"},
+				Exposed:     []string{"X-Time"},
+				MaxAge:      600,
+				Credentials: true,
+			},
+		}
+		origin = "http://example.com"
+		preflight = false
+		rw = &TestResponseWriter{ParentHeader: make(http.Header)}
+		handlerCalled = false
+	})
+
+	JustBeforeEach(func() {
+		method := "GET"
+		if preflight {
+			method = "OPTIONS"
+		}
+		req, e := http.NewRequest(method, "/goo", nil)
+		Ω(e).ShouldNot(HaveOccurred())
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if preflight {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+			req.Header.Set("Access-Control-Request-Headers", "X-Shared-Secret")
+		}
+		ctx := goa.NewContext(nil, goa.New("test"), req, rw, url.Values{})
+		if preflight {
+			err = goa.CORSPreflight(policies...)(ctx)
+			return
+		}
+		h := func(ctx *goa.Context) error {
+			handlerCalled = true
+			return nil
+		}
+		err = goa.CORS(policies...)(h)(ctx)
+	})
+
+	It("adds the headers of the matching policy", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(handlerCalled).Should(BeTrue())
+		Ω(rw.ParentHeader.Get("Access-Control-Allow-Origin")).Should(Equal(origin))
+		Ω(rw.ParentHeader.Get("Access-Control-Allow-Credentials")).Should(Equal("true"))
+		Ω(rw.ParentHeader.Get("Access-Control-Expose-Headers")).Should(Equal("X-Time"))
+		Ω(rw.ParentHeader.Get("Vary")).Should(Equal("Origin"))
+	})
+
+	Context("with a response that already varies on the origin", func() {
+		BeforeEach(func() {
+			rw.ParentHeader.Set("Vary", "Accept-Encoding, Origin")
+		})
+
+		It("does not add the Vary header again", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rw.ParentHeader["Vary"]).Should(Equal([]string{"Accept-Encoding, Origin"}))
+		})
+	})
+
+	Context("with an origin matching the wildcard policy", func() {
+		BeforeEach(func() {
+			origin = "http://example.org"
+		})
+
+		It("allows all origins", func() {
+			Ω(rw.ParentHeader.Get("Access-Control-Allow-Origin")).Should(Equal("*"))
+			Ω(rw.ParentHeader.Get("Access-Control-Allow-Credentials")).Should(BeEmpty())
+		})
+	})
+
+	Context("with no origin", func() {
+		BeforeEach(func() {
+			origin = ""
+		})
+
+		It("does not add headers", func() {
+			Ω(handlerCalled).Should(BeTrue())
+			Ω(rw.ParentHeader).Should(BeEmpty())
+		})
+	})
+
+	Context("with a preflight request", func() {
+		BeforeEach(func() {
+			preflight = true
+		})
+
+		It("responds with the policy headers", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rw.Status).Should(Equal(200))
+			Ω(rw.ParentHeader.Get("Access-Control-Allow-Origin")).Should(Equal(origin))
+			Ω(rw.ParentHeader.Get("Access-Control-Allow-Methods")).Should(Equal("GET, POST"))
+			Ω(rw.ParentHeader.Get("Access-Control-Allow-Headers")).Should(Equal("X-Shared-Secret"))
+			Ω(rw.ParentHeader.Get("Access-Control-Max-Age")).Should(Equal("600"))
+		})
+
+		Context("for a method that is not allowed", func() {
+			BeforeEach(func() {
+				policies[1].Methods = []string{"GET"}
+			})
+
+			It("responds without CORS headers", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(rw.Status).Should(Equal(200))
+				Ω(rw.ParentHeader).Should(BeEmpty())
+			})
+		})
+	})
+})
@@ -0,0 +1,53 @@
+package goa
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Deprecation describes a deprecated API version, resource or action. Code generated by goagen
+// initializes it from the design Deprecated DSL.
+type Deprecation struct {
+	// Sunset is the date after which the deprecated endpoints may stop responding, zero if not
+	// known.
+	Sunset time.Time
+	// Link is the URL of the replacement version, resource or action if any.
+	Link string
+}
+
+// Deprecated returns a middleware that adds the deprecation headers described by the given
+// deprecation to the responses. The headers are set before the handler runs so that error
+// responses also include them.
+// Code generated by goagen uses Deprecated to wrap the handlers of deprecated resources and actions.
+func Deprecated(d *Deprecation) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx *Context) error {
+			if header := ctx.Header(); header != nil {
+				d.SetHeaders(header)
+			}
+			return h(ctx)
+		}
+	}
+}
+
+// SetHeaders sets the "Deprecation" header and, if the deprecation defines them, the "Sunset" and
+// "Link" headers. The "Link" header points to the replacement with the "successor-version"
+// relation type, it replaces any "successor-version" link set previously so that the link of a
+// deprecated action overrides the one of its deprecated API version.
+func (d *Deprecation) SetHeaders(header http.Header) {
+	header.Set("Deprecation", "true")
+	if !d.Sunset.IsZero() {
+		header.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		var links []string
+		for _, l := range header["Link"] {
+			if !strings.HasSuffix(l, `rel="successor-version"`) {
+				links = append(links, l)
+			}
+		}
+		header["Link"] = append(links, fmt.Sprintf(`<%s>; rel="successor-version"`, d.Link))
+	}
+}
@@ -0,0 +1,70 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+var _ = Describe("Deprecated", func() {
+	var deprecation *goa.Deprecation
+	var versionDeprecation *goa.Deprecation
+	var rec *httptest.ResponseRecorder
+	var handlerCalled bool
+
+	BeforeEach(func() {
+		deprecation = &goa.Deprecation{}
+		versionDeprecation = nil
+		handlerCalled = false
+	})
+
+	JustBeforeEach(func() {
+		req, err := http.NewRequest("GET", "/bottles", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rec = httptest.NewRecorder()
+		if versionDeprecation != nil {
+			versionDeprecation.SetHeaders(rec.Header())
+		}
+		ctx := goa.NewContext(nil, goa.New("test"), req, rec, url.Values{})
+		h := func(ctx *goa.Context) error {
+			handlerCalled = true
+			return ctx.Respond(200, nil)
+		}
+		Ω(goa.Deprecated(deprecation)(h)(ctx)).ShouldNot(HaveOccurred())
+	})
+
+	It("sets the Deprecation header", func() {
+		Ω(handlerCalled).Should(BeTrue())
+		Ω(rec.Header().Get("Deprecation")).Should(Equal("true"))
+		Ω(rec.Header()).ShouldNot(HaveKey("Sunset"))
+		Ω(rec.Header()).ShouldNot(HaveKey("Link"))
+	})
+
+	Context("with a sunset date and a replacement", func() {
+		BeforeEach(func() {
+			loc := time.FixedZone("PST", -8*3600)
+			deprecation.Sunset = time.Date(2016, 1, 1, 16, 0, 0, 0, loc)
+			deprecation.Link = "http://example.com/v2/bottles"
+		})
+
+		It("sets the Sunset and Link headers", func() {
+			Ω(rec.Header().Get("Sunset")).Should(Equal("Sat, 02 Jan 2016 00:00:00 GMT"))
+			Ω(rec.Header().Get("Link")).Should(Equal(`<http://example.com/v2/bottles>; rel="successor-version"`))
+		})
+
+		Context("in a deprecated API version", func() {
+			BeforeEach(func() {
+				versionDeprecation = &goa.Deprecation{Link: "http://example.com/v2"}
+			})
+
+			It("sends a single successor link", func() {
+				Ω(rec.Header()["Link"]).Should(Equal([]string{`<http://example.com/v2/bottles>; rel="successor-version"`}))
+			})
+		})
+	})
+})
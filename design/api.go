@@ -6,7 +6,9 @@ import (
 	"path"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -38,6 +40,23 @@ type (
 		Types map[string]*UserTypeDefinition
 		// MediaTypes indexes the API media types by canonical identifier.
 		MediaTypes map[string]*MediaTypeDefinition
+		// SecuritySchemes indexes the API security schemes by name.
+		SecuritySchemes map[string]*SecuritySchemeDefinition
+		// Security is the security requirement that applies to all the API actions unless
+		// overridden by a resource or an action.
+		Security *SecurityDefinition
+		// Origins indexes the CORS policies that apply to all resources by origin.
+		Origins map[string]*CORSDefinition
+		// Consumes lists the encodings of the request bodies of all the API actions.
+		Consumes []*EncodingDefinition
+		// Produces lists the encodings of the response bodies of all the API actions.
+		Produces []*EncodingDefinition
+		// MaxBodySize is the maximum size in bytes of the request bodies of all the API
+		// actions, 0 if not limited.
+		MaxBodySize int64
+		// Router is the name of the router used by the generated service, either "httprouter"
+		// (the default) or "tree".
+		Router string
 		// rand is the random generator used to generate examples.
 		rand *RandomGenerator
 	}
@@ -52,6 +71,9 @@ type (
 		Description string
 		// API version if any
 		Version string
+		// Name of the request media type parameter that specifies the API version, the
+		// version is read from the request path if empty
+		MediaTypeVersionParam string
 		// API hostname
 		Host string
 		// API URL schemes
@@ -68,10 +90,14 @@ type (
 		License *LicenseDefinition
 		// Docs points to the API external documentation
 		Docs *DocsDefinition
+		// Deprecation of the API version if deprecated
+		Deprecation *DeprecationDefinition
 		// Traits available to all API resources and actions indexed by name
 		Traits map[string]*TraitDefinition
 		// Responses available to all API actions indexed by name
 		Responses map[string]*ResponseDefinition
+		// Errors that may be returned by all API actions indexed by name
+		Errors map[string]*ResponseDefinition
 		// Response template factories available to all API actions indexed by name
 		ResponseTemplates map[string]*ResponseTemplateDefinition
 		// Built-in responses
@@ -136,10 +162,26 @@ type (
 		CanonicalActionName string
 		// Map of response definitions that apply to all actions indexed by name.
 		Responses map[string]*ResponseDefinition
+		// Map of error definitions that apply to all actions indexed by name.
+		Errors map[string]*ResponseDefinition
 		// Path and query string parameters that apply to all actions.
 		Params *AttributeDefinition
 		// Request headers that apply to all actions.
 		Headers *AttributeDefinition
+		// Security requirement that applies to all actions unless overridden by an action.
+		Security *SecurityDefinition
+		// Origins indexes the resource CORS policies by origin. These policies override the
+		// API policies defined for the same origin.
+		Origins map[string]*CORSDefinition
+		// Consumes lists the encodings of the request bodies of the resource actions.
+		Consumes []*EncodingDefinition
+		// Produces lists the encodings of the response bodies of the resource actions.
+		Produces []*EncodingDefinition
+		// Deprecation of the resource if deprecated, applies to all the resource actions.
+		Deprecation *DeprecationDefinition
+		// MaxBodySize is the maximum size in bytes of the request bodies of the resource
+		// actions if it overrides the API one.
+		MaxBodySize int64
 		// DSLFunc contains the DSL used to create this definition if any.
 		DSLFunc func()
 		// metadata is a list of key/value pairs
@@ -156,6 +198,15 @@ type (
 		Description string
 		// Response body media type if any
 		MediaType string
+		// Stream is true if the action streams the response body instead of serializing a
+		// media type.
+		Stream bool
+		// Events is true if the response body is a stream of server-sent events, each event
+		// renders the response media type.
+		Events bool
+		// WebSocket is true if the response upgrades the connection to a WebSocket connection,
+		// each message sent to the client renders the response media type.
+		WebSocket bool
 		// Response header definitions
 		Headers *AttributeDefinition
 		// Parent action or resource
@@ -199,14 +250,46 @@ type (
 		Routes []*RouteDefinition
 		// Map of possible response definitions indexed by name
 		Responses map[string]*ResponseDefinition
+		// Map of possible error definitions indexed by name
+		Errors map[string]*ResponseDefinition
 		// Path and query string parameters
 		Params *AttributeDefinition
 		// Query string parameters only
 		QueryParams *AttributeDefinition
 		// Payload blueprint (request body) if any
 		Payload *UserTypeDefinition
+		// StreamPayload is true if the action reads the request body as a raw stream instead
+		// of a decoded payload.
+		StreamPayload bool
+		// MaxStreamSize is the maximum number of bytes read from the streamed request body,
+		// 0 means no limit.
+		MaxStreamSize int64
+		// Form is the content type of the request body if the payload is sent as a form,
+		// either MultipartFormContentType or URLEncodedFormContentType.
+		Form string
+		// Files lists the files uploaded in the multipart form payload.
+		Files []*FileDefinition
 		// Request headers that need to be made available to action
 		Headers *AttributeDefinition
+		// Security requirement of the action if it overrides the resource or API one
+		Security *SecurityDefinition
+		// Consumes lists the encodings of the request bodies if they override the resource
+		// or API ones.
+		Consumes []*EncodingDefinition
+		// Produces lists the encodings of the response bodies if they override the resource
+		// or API ones.
+		Produces []*EncodingDefinition
+		// Deprecation of the action if deprecated
+		Deprecation *DeprecationDefinition
+		// ETag is true if the action responses have ETags and the action supports
+		// conditional requests.
+		ETag bool
+		// CacheControl is the value of the "Cache-Control" header of the action responses
+		// if the action defines one, e.g. "public, max-age=60".
+		CacheControl string
+		// MaxBodySize is the maximum size in bytes of the request bodies if it overrides the
+		// resource or API one.
+		MaxBodySize int64
 		// Metadata is a list of key/value pairs
 		Metadata MetadataDefinition
 	}
@@ -552,6 +635,67 @@ func (v *APIVersionDefinition) IterateResponses(it ResponseIterator) error {
 	return nil
 }
 
+// IterateErrors calls the given iterator passing in each error that may be returned by the
+// actions of the API version sorted in alphabetical order. This includes the errors defined at
+// the API level as well as the errors defined by the resources and actions that support the
+// version. Errors defined multiple times with the same name are iterated only once, API errors
+// take precedence over resource errors which take precedence over action errors.
+// Iteration stops if an iterator returns an error and in this case IterateErrors returns that
+// error.
+func (v *APIVersionDefinition) IterateErrors(it ResponseIterator) error {
+	errors := make(map[string]*ResponseDefinition)
+	for _, e := range v.allErrors() {
+		if _, ok := errors[e.Name]; !ok {
+			errors[e.Name] = e
+		}
+	}
+	names := make([]string, len(errors))
+	i := 0
+	for n := range errors {
+		names[i] = n
+		i++
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if err := it(errors[n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allErrors returns all the error definitions of the version in order of precedence: API errors
+// first, then resource errors and finally action errors.
+func (v *APIVersionDefinition) allErrors() []*ResponseDefinition {
+	var errs []*ResponseDefinition
+	appendErrors := func(m map[string]*ResponseDefinition) {
+		names := make([]string, 0, len(m))
+		for n := range m {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			errs = append(errs, m[n])
+		}
+	}
+	appendErrors(v.Errors)
+	if v != Design.APIVersionDefinition {
+		appendErrors(Design.Errors)
+	}
+	var actionErrs []map[string]*ResponseDefinition
+	v.IterateResources(func(r *ResourceDefinition) error {
+		appendErrors(r.Errors)
+		return r.IterateActions(func(a *ActionDefinition) error {
+			actionErrs = append(actionErrs, a.Errors)
+			return nil
+		})
+	})
+	for _, ae := range actionErrs {
+		appendErrors(ae)
+	}
+	return errs
+}
+
 // DSL returns the initialization DSL.
 func (v *APIVersionDefinition) DSL() func() {
 	return v.DSLFunc
@@ -808,6 +952,9 @@ func (r *ResponseDefinition) Dup() *ResponseDefinition {
 		Status:      r.Status,
 		Description: r.Description,
 		MediaType:   r.MediaType,
+		Stream:      r.Stream,
+		Events:      r.Events,
+		WebSocket:   r.WebSocket,
 	}
 	if r.Headers != nil {
 		res.Headers = r.Headers.Dup()
@@ -832,6 +979,15 @@ func (r *ResponseDefinition) Merge(other *ResponseDefinition) {
 	if r.MediaType == "" {
 		r.MediaType = other.MediaType
 	}
+	if !r.Stream {
+		r.Stream = other.Stream
+	}
+	if !r.Events {
+		r.Events = other.Events
+	}
+	if !r.WebSocket {
+		r.WebSocket = other.WebSocket
+	}
 	if other.Headers != nil {
 		otherHeaders := other.Headers.Type.ToObject()
 		if len(otherHeaders) > 0 {
@@ -921,6 +1077,87 @@ func (a *ActionDefinition) HasAbsoluteRoutes() bool {
 	return true
 }
 
+// Timeout returns the action timeout defined via the "timeout" metadata, e.g.:
+//
+//	Metadata("timeout", "5s")
+//
+// Timeout returns 0 if the action does not define a timeout and an error if the metadata value
+// is not a positive duration as understood by time.ParseDuration.
+func (a *ActionDefinition) Timeout() (time.Duration, error) {
+	val, ok := a.Metadata["timeout"]
+	if !ok {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("timeout must be positive, got %s", val)
+	}
+	return d, nil
+}
+
+// RateLimit returns the maximum number of requests each client may send to the action per period
+// defined via the "ratelimit" metadata, e.g.:
+//
+//	Metadata("ratelimit", "100/1m")
+//
+// RateLimit returns 0 if the action does not define a rate limit and an error if the metadata value
+// is not a positive number of requests followed by a slash and a positive duration as understood by
+// time.ParseDuration.
+func (a *ActionDefinition) RateLimit() (int, time.Duration, error) {
+	val, ok := a.Metadata["ratelimit"]
+	if !ok {
+		return 0, 0, nil
+	}
+	elems := strings.SplitN(val, "/", 2)
+	if len(elems) != 2 {
+		return 0, 0, fmt.Errorf("rate limit must be of the form requests/period, got %s", val)
+	}
+	requests, err := strconv.Atoi(elems[0])
+	if err != nil || requests <= 0 {
+		return 0, 0, fmt.Errorf("rate limit requests must be a positive integer, got %s", elems[0])
+	}
+	period, err := time.ParseDuration(elems[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	if period <= 0 {
+		return 0, 0, fmt.Errorf("rate limit period must be positive, got %s", elems[1])
+	}
+	return requests, period, nil
+}
+
+// EffectiveMaxBodySize returns the maximum size in bytes of the action request bodies: the action
+// MaxBodySize if set, the one of the parent resource otherwise and finally the one of the API.
+// If none of these is set and the action accepts files that all define a maximum size then the
+// limit is the sum of these sizes plus FormValuesMaxSize so that the request bodies are not read
+// past the file limits. EffectiveMaxBodySize returns 0 if the size of the request bodies is not
+// limited.
+func (a *ActionDefinition) EffectiveMaxBodySize() int64 {
+	if a.MaxBodySize > 0 {
+		return a.MaxBodySize
+	}
+	if a.Parent != nil && a.Parent.MaxBodySize > 0 {
+		return a.Parent.MaxBodySize
+	}
+	if Design != nil && Design.MaxBodySize > 0 {
+		return Design.MaxBodySize
+	}
+	if len(a.Files) == 0 {
+		return 0
+	}
+	size := FormValuesMaxSize
+	for _, f := range a.Files {
+		if f.MaxSize <= 0 {
+			return 0
+		}
+		size += f.MaxSize
+	}
+	return size
+}
+
 // Context returns the generic definition name used in error messages.
 func (l *LinkDefinition) Context() string {
 	var prefix, suffix string
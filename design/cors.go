@@ -0,0 +1,72 @@
+package design
+
+import (
+	"fmt"
+	"sort"
+)
+
+type (
+	// CORSDefinition defines the cross-origin resource sharing policy that applies to requests
+	// sent by a given origin.
+	CORSDefinition struct {
+		// Parent is the API or resource that defines the policy.
+		Parent Definition
+		// Origin is the origin the policy applies to: an exact origin such as
+		// "http://example.com", a pattern containing a single "*" wildcard such as
+		// "https://*.example.com" or "*" to match all origins.
+		Origin string
+		// Methods lists the HTTP methods allowed by preflight requests.
+		Methods []string
+		// Headers lists the request headers allowed by preflight requests, "*" allows all
+		// headers.
+		Headers []string
+		// Exposed lists the response headers exposed to the client.
+		Exposed []string
+		// MaxAge is the number of seconds the client may cache the preflight response.
+		MaxAge uint
+		// Credentials is true if the client may send credentials (cookies, HTTP
+		// authentication) with the request.
+		Credentials bool
+	}
+
+	// CORSIterator is the type of functions given to IterateOrigins.
+	CORSIterator func(cors *CORSDefinition) error
+)
+
+// Context returns the generic definition name used in error messages.
+func (cors *CORSDefinition) Context() string {
+	var suffix string
+	if cors.Parent != nil {
+		suffix = fmt.Sprintf(" of %s", cors.Parent.Context())
+	}
+	return fmt.Sprintf("CORS policy for origin %#v%s", cors.Origin, suffix)
+}
+
+// IterateOrigins calls the given iterator passing in each CORS policy that applies to the resource
+// sorted by origin. The resource policies override the API policies defined for the same origin.
+// Iteration stops if an iterator returns an error and in this case IterateOrigins returns that
+// error.
+func (r *ResourceDefinition) IterateOrigins(it CORSIterator) error {
+	origins := make(map[string]*CORSDefinition)
+	if Design != nil {
+		for o, cors := range Design.Origins {
+			origins[o] = cors
+		}
+	}
+	for o, cors := range r.Origins {
+		origins[o] = cors
+	}
+	names := make([]string, len(origins))
+	i := 0
+	for o := range origins {
+		names[i] = o
+		i++
+	}
+	sort.Strings(names)
+	for _, o := range names {
+		if err := it(origins[o]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
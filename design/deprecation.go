@@ -0,0 +1,44 @@
+package design
+
+import (
+	"fmt"
+	"time"
+)
+
+type (
+	// DeprecationDefinition describes the deprecation of an API version, resource or action.
+	DeprecationDefinition struct {
+		// Description of the deprecation
+		Description string
+		// Sunset is the date after which the deprecated endpoints may stop responding, zero
+		// if not known.
+		Sunset time.Time
+		// URL of the replacement version, resource or action if any
+		URL string
+		// Parent API version, resource or action
+		Parent Definition
+	}
+)
+
+// Context returns the generic definition name used in error messages.
+func (d *DeprecationDefinition) Context() string {
+	var suffix string
+	if d.Parent != nil {
+		suffix = fmt.Sprintf(" of %s", d.Parent.Context())
+	}
+	return "deprecation" + suffix
+}
+
+// EffectiveDeprecation returns the deprecation that applies to the action: the action deprecation
+// if there is one and the parent resource deprecation otherwise. It returns nil if neither the
+// action nor its resource is deprecated. The deprecation of API versions applies to all the
+// actions they expose, see APIVersionDefinition.Deprecation.
+func (a *ActionDefinition) EffectiveDeprecation() *DeprecationDefinition {
+	if a.Deprecation != nil {
+		return a.Deprecation
+	}
+	if a.Parent != nil {
+		return a.Parent.Deprecation
+	}
+	return nil
+}
@@ -128,7 +128,34 @@ func PATCH(path string) *design.RouteDefinition {
 //
 // Headers can be used inside Action to define the action request headers, Response to define the
 // response headers or Resource to define common request headers to all the resource actions.
-func Headers(dsl func()) {
+//
+// When used in an Origin DSL Headers lists the names of the request headers allowed by the CORS
+// policy instead:
+//
+//	Origin("http://example.com", func() {
+//		Headers("X-Account", "X-Shared-Secret")
+//	})
+func Headers(params ...interface{}) {
+	if cors, ok := corsDefinition(false); ok {
+		for _, p := range params {
+			h, ok := p.(string)
+			if !ok {
+				invalidArgError("string", p)
+				return
+			}
+			cors.Headers = append(cors.Headers, h)
+		}
+		return
+	}
+	if len(params) != 1 {
+		ReportError("Headers must be given exactly one DSL function")
+		return
+	}
+	dsl, ok := params[0].(func())
+	if !ok {
+		invalidArgError("function", params[0])
+		return
+	}
 	if a, ok := actionDefinition(false); ok {
 		headers := newAttribute(a.Parent.MediaType)
 		if ExecuteDSL(dsl, headers) {
@@ -2,6 +2,7 @@ package dsl_test
 
 import (
 	"strconv"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -62,6 +63,40 @@ var _ = Describe("Action", func() {
 		})
 	})
 
+	Context("with a rate limit", func() {
+		var ratelimit string
+
+		BeforeEach(func() {
+			name = "foo"
+			ratelimit = "100/1m"
+			dsl = func() {
+				Routing(GET("/:id"))
+				Metadata("ratelimit", ratelimit)
+			}
+		})
+
+		It("produces a valid action with the given rate limit", func() {
+			Ω(Errors).ShouldNot(HaveOccurred())
+			Ω(action.Validate(Design.APIVersionDefinition)).ShouldNot(HaveOccurred())
+			requests, period, err := action.RateLimit()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(requests).Should(Equal(100))
+			Ω(period).Should(Equal(time.Minute))
+		})
+
+		Context("that is invalid", func() {
+			BeforeEach(func() {
+				ratelimit = "100 per minute"
+			})
+
+			It("produces an invalid action", func() {
+				err := action.Validate(Design.APIVersionDefinition)
+				Ω(err).Should(HaveOccurred())
+				Ω(err.Error()).Should(ContainSubstring("invalid ratelimit metadata"))
+			})
+		})
+	})
+
 	Context("with a string payload", func() {
 		BeforeEach(func() {
 			name = "foo"
@@ -51,6 +51,13 @@ import (
 //				Required("header")
 //			})
 //		})
+//		JWTSecurity("jwt", func() {		// Security schemes available to the API
+//			Scope("api:read", "Read access")
+//		})
+//		Security("jwt")				// Security requirement of all API actions
+//		Origin("http://swagger.goa.design", func() {	// CORS policy of all API resources
+//			Methods("GET", "POST")
+//		})
 //	}
 //
 func API(name string, dsl func()) *design.APIDefinition {
@@ -105,6 +112,12 @@ func Description(d string) {
 		a.Description = d
 	} else if r, ok := responseDefinition(false); ok {
 		r.Description = d
+	} else if s, ok := securitySchemeDefinition(false); ok {
+		s.Description = d
+	} else if f, ok := fileDefinition(false); ok {
+		f.Description = d
+	} else if de, ok := deprecationDefinition(false); ok {
+		de.Description = d
 	} else if do, ok := docsDefinition(true); ok {
 		do.Description = d
 	}
@@ -153,6 +166,28 @@ func BaseParams(dsl func()) {
 	}
 }
 
+// MediaTypeVersioning declares that clients specify the API version in the media types of the
+// request Accept and Content-Type headers rather than in the request path. The argument is the name
+// of the media type parameter that contains the version, e.g.:
+//
+//	API("cellar", func() {
+//		MediaTypeVersioning("version")
+//	})
+//
+// makes the generated service read the version from headers such as
+// "Accept: application/json; version=1.0" or from vendor media type suffixes such as
+// "Accept: application/vnd.cellar.v1+json". The generated client sends the version in the
+// media type parameter. MediaTypeVersioning can only be used in the API DSL.
+func MediaTypeVersioning(param string) {
+	if param == "" {
+		ReportError("media type version parameter name cannot be empty")
+		return
+	}
+	if a, ok := apiDefinition(true); ok {
+		a.MediaTypeVersionParam = param
+	}
+}
+
 // TermsOfService describes the API terms of services or links to them.
 func TermsOfService(terms string) {
 	if a, ok := apiDefinition(false); ok {
@@ -256,12 +291,14 @@ func Email(email string) {
 	}
 }
 
-// URL sets the contact or license URL.
+// URL sets the contact, license, docs or deprecation replacement URL.
 func URL(url string) {
 	if c, ok := contactDefinition(false); ok {
 		c.URL = url
 	} else if l, ok := licenseDefinition(false); ok {
 		l.URL = url
+	} else if de, ok := deprecationDefinition(false); ok {
+		de.URL = url
 	} else if d, ok := docsDefinition(true); ok {
 		d.URL = url
 	}
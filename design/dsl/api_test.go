@@ -163,6 +163,19 @@ var _ = Describe("API", func() {
 			})
 		})
 
+		Context("with MediaTypeVersioning", func() {
+			BeforeEach(func() {
+				dsl = func() {
+					MediaTypeVersioning("version")
+				}
+			})
+
+			It("sets the API media type version parameter", func() {
+				Ω(Errors).ShouldNot(HaveOccurred())
+				Ω(Design.MediaTypeVersionParam).Should(Equal("version"))
+			})
+		})
+
 		Context("with BaseParams", func() {
 			const param1Name = "accountID"
 			const param1Type = Integer
@@ -179,8 +179,22 @@ func parseAttributeArgs(baseAttr *design.AttributeDefinition, args ...interface{
 	return dataType, description, dsl
 }
 
-// Header is an alias of Attribute.
+// Header is an alias of Attribute. When used in an APIKeySecurity or JWTSecurity definition Header
+// sets the name of the HTTP header that contains the credentials instead.
 func Header(name string, args ...interface{}) {
+	if s, ok := securitySchemeDefinition(false); ok {
+		if len(args) > 0 {
+			ReportError("too many arguments in call to Header")
+			return
+		}
+		if s.Kind != design.APIKeySecurityKind && s.Kind != design.JWTSecurityKind {
+			incompatibleDSL("Header")
+			return
+		}
+		s.In = "header"
+		s.ParamName = name
+		return
+	}
 	Attribute(name, args...)
 }
 
@@ -394,8 +408,17 @@ func MaxLength(val int) {
 
 // Required adds a "required" validation to the attribute.
 // See http://json-schema.org/latest/json-schema-validation.html#anchor61.
+// Required may also be used without argument in a File DSL to make the file required.
 func Required(names ...string) {
 	var at *design.AttributeDefinition
+	if f, ok := fileDefinition(false); ok {
+		if len(names) > 0 {
+			ReportError("Required must be used without argument in a File DSL")
+			return
+		}
+		f.Required = true
+		return
+	}
 	if a, ok := attributeDefinition(false); ok {
 		at = a
 	} else if mt, ok := mediaTypeDefinition(true); ok {
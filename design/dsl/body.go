@@ -0,0 +1,25 @@
+package dsl
+
+// MaxBodySize sets the maximum size in bytes of the request bodies. MaxBodySize can be used in the
+// API, Resource or Action DSL, actions inherit the maximum size of their resource which inherit the
+// maximum size of the API:
+//
+//	API("cellar", func() {
+//		MaxBodySize(1 << 20) // 1MB
+//	})
+//
+// The generated code responds with 413 Payload Too Large to requests whose body is larger than the
+// maximum size, including requests that use chunked transfer encoding.
+func MaxBodySize(size int64) {
+	if size <= 0 {
+		ReportError("maximum body size must be positive, got %d", size)
+		return
+	}
+	if a, ok := apiDefinition(false); ok {
+		a.MaxBodySize = size
+	} else if r, ok := resourceDefinition(false); ok {
+		r.MaxBodySize = size
+	} else if a, ok := actionDefinition(true); ok {
+		a.MaxBodySize = size
+	}
+}
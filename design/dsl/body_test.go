@@ -0,0 +1,113 @@
+package dsl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("MaxBodySize", func() {
+	var apiDSL, resourceDSL, actionDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		apiDSL = func() {
+			MaxBodySize(1 << 20)
+		}
+		resourceDSL = func() {}
+		actionDSL = func() {}
+	})
+
+	JustBeforeEach(func() {
+		API("test", apiDSL)
+		Resource("res", func() {
+			resourceDSL()
+			Action("create", func() {
+				Routing(POST(""))
+				actionDSL()
+			})
+		})
+		dslErr = RunDSL()
+	})
+
+	It("applies the API maximum size to the actions", func() {
+		Ω(dslErr).ShouldNot(HaveOccurred())
+		Ω(Design.MaxBodySize).Should(Equal(int64(1 << 20)))
+		Ω(Design.Resources["res"].Actions["create"].EffectiveMaxBodySize()).Should(Equal(int64(1 << 20)))
+	})
+
+	Context("with an action that accepts files with a maximum size", func() {
+		BeforeEach(func() {
+			apiDSL = func() {}
+			actionDSL = func() {
+				MultipartForm()
+				File("image", func() {
+					MaxSize(1024)
+				})
+				File("thumbnail", func() {
+					MaxSize(512)
+				})
+			}
+		})
+
+		It("limits the body size to the files sizes and the form values", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			Ω(Design.Resources["res"].Actions["create"].EffectiveMaxBodySize()).Should(Equal(1536 + FormValuesMaxSize))
+		})
+
+		Context("and a file without maximum size", func() {
+			BeforeEach(func() {
+				actionDSL = func() {
+					MultipartForm()
+					File("image")
+				}
+			})
+
+			It("does not limit the body size", func() {
+				Ω(dslErr).ShouldNot(HaveOccurred())
+				Ω(Design.Resources["res"].Actions["create"].EffectiveMaxBodySize()).Should(BeZero())
+			})
+		})
+	})
+
+	Context("with resource and action maximum sizes", func() {
+		BeforeEach(func() {
+			resourceDSL = func() {
+				MaxBodySize(1024)
+			}
+		})
+
+		It("uses the resource maximum size", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			Ω(Design.Resources["res"].Actions["create"].EffectiveMaxBodySize()).Should(Equal(int64(1024)))
+		})
+
+		Context("and an action maximum size", func() {
+			BeforeEach(func() {
+				actionDSL = func() {
+					MaxBodySize(64)
+				}
+			})
+
+			It("uses the action maximum size", func() {
+				Ω(dslErr).ShouldNot(HaveOccurred())
+				Ω(Design.Resources["res"].Actions["create"].EffectiveMaxBodySize()).Should(Equal(int64(64)))
+			})
+		})
+	})
+
+	Context("with an invalid size", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				MaxBodySize(0)
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslErr).Should(HaveOccurred())
+		})
+	})
+})
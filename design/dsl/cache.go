@@ -0,0 +1,67 @@
+package dsl
+
+import (
+	"strconv"
+	"strings"
+)
+
+// cacheDirectives lists the response Cache-Control directives accepted by CacheControl, the value
+// is true for the directives that take a number of seconds.
+var cacheDirectives = map[string]bool{
+	"public":                 false,
+	"private":                false,
+	"no-cache":               false,
+	"no-store":               false,
+	"no-transform":           false,
+	"must-revalidate":        false,
+	"proxy-revalidate":       false,
+	"immutable":              false,
+	"max-age":                true,
+	"s-maxage":               true,
+	"stale-while-revalidate": true,
+	"stale-if-error":         true,
+}
+
+// CacheControl sets the "Cache-Control" header of the action responses. CacheControl can only be
+// used in the Action DSL:
+//
+//	Action("show", func() {
+//		Routing(GET("/:id"))
+//		CacheControl("public", "max-age=60")
+//		Response(OK)
+//	})
+//
+// The generated service also caches the rendered responses of GET requests for the duration given
+// by the "s-maxage" directive or by the "max-age" directive if there is no "s-maxage" directive.
+// Responses are not cached by the service if the directives include "private", "no-cache" or
+// "no-store". Actions that modify resources call the context InvalidateCache method to discard
+// the cached responses of the resource actions.
+func CacheControl(directives ...string) {
+	a, ok := actionDefinition(true)
+	if !ok {
+		return
+	}
+	if len(directives) == 0 {
+		ReportError("missing Cache-Control directives")
+		return
+	}
+	for _, d := range directives {
+		elems := strings.SplitN(d, "=", 2)
+		takesSeconds, known := cacheDirectives[elems[0]]
+		if !known {
+			ReportError("unknown Cache-Control directive %#v", d)
+			return
+		}
+		if takesSeconds != (len(elems) == 2) {
+			ReportError("invalid Cache-Control directive %#v", d)
+			return
+		}
+		if takesSeconds {
+			if secs, err := strconv.Atoi(elems[1]); err != nil || secs < 0 {
+				ReportError("invalid number of seconds in Cache-Control directive %#v", d)
+				return
+			}
+		}
+	}
+	a.CacheControl = strings.Join(directives, ", ")
+}
@@ -0,0 +1,65 @@
+package dsl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("CacheControl", func() {
+	var directives []string
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		directives = []string{"public", "max-age=60"}
+	})
+
+	JustBeforeEach(func() {
+		API("test", nil)
+		Resource("res", func() {
+			Action("show", func() {
+				Routing(GET("/:id"))
+				CacheControl(directives...)
+			})
+		})
+		dslErr = RunDSL()
+	})
+
+	It("sets the action Cache-Control header", func() {
+		Ω(dslErr).ShouldNot(HaveOccurred())
+		Ω(Design.Resources["res"].Actions["show"].CacheControl).Should(Equal("public, max-age=60"))
+	})
+
+	Context("with an unknown directive", func() {
+		BeforeEach(func() {
+			directives = []string{"public", "forever"}
+		})
+
+		It("produces an error", func() {
+			Ω(dslErr).Should(HaveOccurred())
+		})
+	})
+
+	Context("with an invalid number of seconds", func() {
+		BeforeEach(func() {
+			directives = []string{"max-age=1m"}
+		})
+
+		It("produces an error", func() {
+			Ω(dslErr).Should(HaveOccurred())
+		})
+	})
+
+	Context("with a directive missing its number of seconds", func() {
+		BeforeEach(func() {
+			directives = []string{"s-maxage"}
+		})
+
+		It("produces an error", func() {
+			Ω(dslErr).Should(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,92 @@
+package dsl
+
+import "github.com/raphael/goa/design"
+
+// Origin defines the CORS policy for a given origin. The origin can use a single "*" wildcard,
+// e.g. "https://*.example.com", or be "*" to match all origins. Origin can be used in the API DSL
+// to define policies that apply to all resources or in a Resource DSL to override the API policy
+// for the same origin. The optional DSL describes the policy:
+//
+//	Origin("http://swagger.goa.design", func() {
+//		Methods("GET", "POST")		// Methods allowed by preflight requests
+//		Headers("X-Shared-Secret")	// Request headers allowed by preflight requests
+//		Expose("X-Time")		// Response headers exposed to the client
+//		MaxAge(600)			// How long clients may cache preflight responses
+//		Credentials()			// Allow clients to send credentials
+//	})
+//
+// The generated code handles the preflight OPTIONS requests sent to the resource action paths and
+// adds the CORS headers to the responses of the actual requests.
+func Origin(origin string, dsl ...func()) {
+	if len(dsl) > 1 {
+		ReportError("too many arguments given to Origin")
+		return
+	}
+	cors := &design.CORSDefinition{Origin: origin}
+	if a, ok := apiDefinition(false); ok {
+		cors.Parent = a
+		if !runCORSDSL(cors, dsl) {
+			return
+		}
+		if a.Origins == nil {
+			a.Origins = make(map[string]*design.CORSDefinition)
+		}
+		a.Origins[origin] = cors
+	} else if r, ok := resourceDefinition(true); ok {
+		cors.Parent = r
+		if !runCORSDSL(cors, dsl) {
+			return
+		}
+		if r.Origins == nil {
+			r.Origins = make(map[string]*design.CORSDefinition)
+		}
+		r.Origins[origin] = cors
+	}
+}
+
+// Methods sets the HTTP methods allowed by the CORS policy preflight requests.
+func Methods(vals ...string) {
+	if cors, ok := corsDefinition(true); ok {
+		cors.Methods = append(cors.Methods, vals...)
+	}
+}
+
+// Expose sets the response headers exposed to the client by the CORS policy.
+func Expose(vals ...string) {
+	if cors, ok := corsDefinition(true); ok {
+		cors.Exposed = append(cors.Exposed, vals...)
+	}
+}
+
+// MaxAge sets the number of seconds clients may cache the CORS policy preflight responses.
+func MaxAge(val uint) {
+	if cors, ok := corsDefinition(true); ok {
+		cors.MaxAge = val
+	}
+}
+
+// Credentials allows clients to send credentials such as cookies or HTTP authentication with the
+// requests covered by the CORS policy.
+func Credentials() {
+	if cors, ok := corsDefinition(true); ok {
+		cors.Credentials = true
+	}
+}
+
+// runCORSDSL runs the given CORS policy DSL if any.
+func runCORSDSL(cors *design.CORSDefinition, dsl []func()) bool {
+	if len(dsl) == 0 {
+		return true
+	}
+	return ExecuteDSL(dsl[0], cors)
+}
+
+// corsDefinition returns true and current context if it is a CORSDefinition,
+// nil and false otherwise.
+func corsDefinition(failIfNotCORS bool) (*design.CORSDefinition, bool) {
+	cors, ok := ctxStack.Current().(*design.CORSDefinition)
+	if !ok && failIfNotCORS {
+		incompatibleDSL(caller())
+	}
+	return cors, ok
+}
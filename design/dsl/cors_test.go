@@ -0,0 +1,113 @@
+package dsl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("Origin", func() {
+	var apiDSL func()
+	var resDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		apiDSL = nil
+		resDSL = func() {
+			Action("show", func() {
+				Routing(GET("/:id"))
+			})
+		}
+	})
+
+	JustBeforeEach(func() {
+		API("test", apiDSL)
+		Resource("res", resDSL)
+		dslErr = RunDSL()
+	})
+
+	Context("in the API DSL", func() {
+		BeforeEach(func() {
+			apiDSL = func() {
+				Origin("http://example.com", func() {
+					Methods("GET", "POST")
+					Headers("X-Shared-Secret", "X-Account")
+					Expose("X-Time")
+					MaxAge(600)
+					Credentials()
+				})
+			}
+		})
+
+		It("records the CORS policy", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			Ω(Design.Origins).Should(HaveKey("http://example.com"))
+			cors := Design.Origins["http://example.com"]
+			Ω(cors.Parent).Should(Equal(Design))
+			Ω(cors.Methods).Should(Equal([]string{"GET", "POST"}))
+			Ω(cors.Headers).Should(Equal([]string{"X-Shared-Secret", "X-Account"}))
+			Ω(cors.Exposed).Should(Equal([]string{"X-Time"}))
+			Ω(cors.MaxAge).Should(Equal(uint(600)))
+			Ω(cors.Credentials).Should(BeTrue())
+			Ω(Design.Validate()).Should(BeNil())
+		})
+
+		Context("and in a resource DSL", func() {
+			BeforeEach(func() {
+				resDSL = func() {
+					Origin("http://example.com", func() {
+						Methods("GET")
+					})
+					Origin("*")
+					Action("show", func() {
+						Routing(GET("/:id"))
+					})
+				}
+			})
+
+			It("overrides the API policy", func() {
+				Ω(dslErr).ShouldNot(HaveOccurred())
+				var origins []*CORSDefinition
+				Design.Resources["res"].IterateOrigins(func(cors *CORSDefinition) error {
+					origins = append(origins, cors)
+					return nil
+				})
+				Ω(origins).Should(HaveLen(2))
+				Ω(origins[0].Origin).Should(Equal("*"))
+				Ω(origins[1].Origin).Should(Equal("http://example.com"))
+				Ω(origins[1].Methods).Should(Equal([]string{"GET"}))
+				Ω(origins[1].Parent).Should(Equal(Design.Resources["res"]))
+			})
+		})
+	})
+
+	Context("with an origin containing multiple wildcards", func() {
+		BeforeEach(func() {
+			apiDSL = func() {
+				Origin("http://*.*.example.com")
+			}
+		})
+
+		It("produces a validation error", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("at most one wildcard"))
+		})
+	})
+
+	Context("with a policy DSL used outside of Origin", func() {
+		BeforeEach(func() {
+			apiDSL = func() {
+				MaxAge(600)
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslErr).Should(HaveOccurred())
+			Ω(dslErr.Error()).Should(ContainSubstring("invalid use of MaxAge"))
+		})
+	})
+})
@@ -0,0 +1,73 @@
+package dsl
+
+import (
+	"time"
+
+	"github.com/raphael/goa/design"
+)
+
+// Deprecated marks an API version, a resource or an action as deprecated. Deprecated can be used
+// in the Version, Resource and Action DSLs, the optional DSL may use Description, Sunset and URL:
+//
+//	Version("1.0", func() {
+//		Deprecated(func() {
+//			Description("Version 1.0 is replaced by version 2.0")
+//			Sunset("2016-06-30")
+//			URL("http://cellar.goa.design/docs/2.0")
+//		})
+//	})
+//
+// The generated service adds the "Deprecation", "Sunset" and "Link" headers to the responses of
+// deprecated endpoints, the generated Swagger specification marks the corresponding operations
+// as deprecated and the generated client prints a warning when it receives these headers.
+func Deprecated(dsl ...func()) {
+	if len(dsl) > 1 {
+		ReportError("too many arguments given to Deprecated")
+		return
+	}
+	var parent design.Definition
+	if v, ok := versionDefinition(false); ok {
+		parent = v
+	} else if r, ok := resourceDefinition(false); ok {
+		parent = r
+	} else if a, ok := actionDefinition(true); ok {
+		parent = a
+	} else {
+		return
+	}
+	d := &design.DeprecationDefinition{Parent: parent}
+	if len(dsl) == 1 && !ExecuteDSL(dsl[0], d) {
+		return
+	}
+	switch p := parent.(type) {
+	case *design.APIVersionDefinition:
+		p.Deprecation = d
+	case *design.ResourceDefinition:
+		p.Deprecation = d
+	case *design.ActionDefinition:
+		p.Deprecation = d
+	}
+}
+
+// Sunset sets the date after which the deprecated version, resource or action may stop responding.
+// The date uses the YYYY-MM-DD format, e.g. "2016-06-30".
+func Sunset(date string) {
+	if d, ok := deprecationDefinition(true); ok {
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			ReportError("invalid sunset date %#v, must use the YYYY-MM-DD format", date)
+			return
+		}
+		d.Sunset = t
+	}
+}
+
+// deprecationDefinition returns true and current context if it is a DeprecationDefinition,
+// nil and false otherwise.
+func deprecationDefinition(failIfNotDeprecation bool) (*design.DeprecationDefinition, bool) {
+	d, ok := ctxStack.Current().(*design.DeprecationDefinition)
+	if !ok && failIfNotDeprecation {
+		incompatibleDSL(caller())
+	}
+	return d, ok
+}
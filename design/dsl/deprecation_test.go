@@ -0,0 +1,91 @@
+package dsl_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("Deprecated", func() {
+	var versionDSL, actionDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		versionDSL = func() {
+			Deprecated(func() {
+				Description("replaced by version 2.0")
+				Sunset("2016-06-30")
+				URL("http://example.com/docs/2.0")
+			})
+		}
+		actionDSL = func() {
+			Deprecated()
+		}
+	})
+
+	JustBeforeEach(func() {
+		API("test", nil)
+		Version("1.0", versionDSL)
+		Resource("res", func() {
+			APIVersion("1.0")
+			Action("show", func() {
+				Routing(GET("/:id"))
+				actionDSL()
+			})
+			Action("list", func() {
+				Routing(GET(""))
+			})
+		})
+		dslErr = RunDSL()
+	})
+
+	It("records the deprecations", func() {
+		Ω(dslErr).ShouldNot(HaveOccurred())
+		Ω(Design.Validate()).Should(BeNil())
+		d := Design.APIVersions["1.0"].Deprecation
+		Ω(d).ShouldNot(BeNil())
+		Ω(d.Description).Should(Equal("replaced by version 2.0"))
+		Ω(d.Sunset).Should(Equal(time.Date(2016, 6, 30, 0, 0, 0, 0, time.UTC)))
+		Ω(d.URL).Should(Equal("http://example.com/docs/2.0"))
+		res := Design.Resources["res"]
+		Ω(res.Actions["show"].EffectiveDeprecation()).ShouldNot(BeNil())
+		Ω(res.Actions["list"].EffectiveDeprecation()).Should(BeNil())
+	})
+
+	Context("with an invalid sunset date", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				Deprecated(func() {
+					Sunset("06/30/2016")
+				})
+			}
+		})
+
+		It("reports an error", func() {
+			Ω(dslErr).Should(HaveOccurred())
+			Ω(dslErr.Error()).Should(ContainSubstring("invalid sunset date"))
+		})
+	})
+
+	Context("with an invalid replacement URL", func() {
+		BeforeEach(func() {
+			versionDSL = func() {
+				Deprecated(func() {
+					URL("not a URL")
+				})
+			}
+		})
+
+		It("fails validation", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			err := Design.Validate()
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("invalid deprecation URL value"))
+		})
+	})
+})
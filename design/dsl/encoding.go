@@ -0,0 +1,125 @@
+package dsl
+
+import "github.com/raphael/goa/design"
+
+// Produces lists the content types of the response bodies. Produces can be used in the API,
+// Resource or Action DSL, actions inherit the content types of their resource which inherit the
+// content types of the API. Produces may appear multiple times:
+//
+//	Action("show", func() {
+//		Produces("application/json", "application/xml")
+//	})
+//
+// The generated code restricts content negotiation to these content types and to the identifiers
+// of the action response media types and responds with 406 Not Acceptable if none of them is
+// acceptable to the client.
+//
+// The last argument may be a DSL that specifies the Go package that implements the encoder, see
+// EncodingPackage and EncodingFunction:
+//
+//	Produces("application/msgpack", func() {
+//		EncodingPackage("github.com/example/msgpack")
+//	})
+//
+// The generated main function registers the encoder with the service.
+func Produces(args ...interface{}) {
+	enc, ok := encodingDSL("Produces", args)
+	if !ok {
+		return
+	}
+	if a, ok := apiDefinition(false); ok {
+		a.Produces = append(a.Produces, enc)
+	} else if r, ok := resourceDefinition(false); ok {
+		r.Produces = append(r.Produces, enc)
+	} else if a, ok := actionDefinition(true); ok {
+		a.Produces = append(a.Produces, enc)
+	}
+}
+
+// Consumes lists the content types of the request bodies. Consumes can be used in the API,
+// Resource or Action DSL, actions inherit the content types of their resource which inherit the
+// content types of the API. Consumes may appear multiple times:
+//
+//	Action("create", func() {
+//		Consumes("application/json")
+//	})
+//
+// The generated code responds with 415 Unsupported Media Type to requests whose body content type
+// is not one of these content types.
+//
+// The last argument may be a DSL that specifies the Go package that implements the decoder, see
+// EncodingPackage and EncodingFunction:
+//
+//	Consumes("application/msgpack", func() {
+//		EncodingPackage("github.com/example/msgpack")
+//		EncodingFunction("NewDecoderFactory")
+//	})
+//
+// The generated main function registers the decoder with the service.
+func Consumes(args ...interface{}) {
+	enc, ok := encodingDSL("Consumes", args)
+	if !ok {
+		return
+	}
+	if a, ok := apiDefinition(false); ok {
+		a.Consumes = append(a.Consumes, enc)
+	} else if r, ok := resourceDefinition(false); ok {
+		r.Consumes = append(r.Consumes, enc)
+	} else if a, ok := actionDefinition(true); ok {
+		a.Consumes = append(a.Consumes, enc)
+	}
+}
+
+// EncodingPackage sets the path of the Go package that implements the encoder or decoder of the
+// enclosing Produces or Consumes DSL.
+func EncodingPackage(path string) {
+	if enc, ok := encodingDefinition(true); ok {
+		enc.PackagePath = path
+	}
+}
+
+// EncodingFunction sets the name of the package function that creates the encoder or decoder factory of
+// the enclosing Produces or Consumes DSL. The function must accept no argument and return a
+// goa.EncoderFactory for Produces or a goa.DecoderFactory for Consumes. It defaults to
+// "NewFactory".
+func EncodingFunction(name string) {
+	if enc, ok := encodingDefinition(true); ok {
+		enc.Function = name
+	}
+}
+
+// encodingDSL builds the encoding definition from the arguments given to Produces or Consumes:
+// content types optionally followed by a DSL.
+func encodingDSL(name string, args []interface{}) (*design.EncodingDefinition, bool) {
+	enc := &design.EncodingDefinition{}
+	var dsl func()
+	for i, arg := range args {
+		switch a := arg.(type) {
+		case string:
+			enc.MIMETypes = append(enc.MIMETypes, a)
+		case func():
+			if i != len(args)-1 {
+				ReportError("the DSL must be the last argument given to %s", name)
+				return nil, false
+			}
+			dsl = a
+		default:
+			invalidArgError("string or function", arg)
+			return nil, false
+		}
+	}
+	if dsl != nil && !ExecuteDSL(dsl, enc) {
+		return nil, false
+	}
+	return enc, true
+}
+
+// encodingDefinition returns true and current context if it is an EncodingDefinition,
+// nil and false otherwise.
+func encodingDefinition(failIfNotEnc bool) (*design.EncodingDefinition, bool) {
+	enc, ok := ctxStack.Current().(*design.EncodingDefinition)
+	if !ok && failIfNotEnc {
+		incompatibleDSL(caller())
+	}
+	return enc, ok
+}
@@ -0,0 +1,116 @@
+package dsl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("Produces and Consumes", func() {
+	var apiDSL func()
+	var actionDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		apiDSL = func() {
+			Produces("application/json", "application/xml")
+			Consumes("application/json")
+		}
+		actionDSL = nil
+	})
+
+	JustBeforeEach(func() {
+		API("test", apiDSL)
+		Resource("res", func() {
+			Action("show", func() {
+				Routing(GET("/:id"))
+				if actionDSL != nil {
+					actionDSL()
+				}
+			})
+		})
+		dslErr = RunDSL()
+	})
+
+	It("applies the API encodings to the actions", func() {
+		Ω(dslErr).ShouldNot(HaveOccurred())
+		Ω(Design.Validate()).Should(BeNil())
+		action := Design.Resources["res"].Actions["show"]
+		Ω(action.EffectiveProduces()).Should(Equal([]string{"application/json", "application/xml"}))
+		Ω(action.EffectiveConsumes()).Should(Equal([]string{"application/json"}))
+	})
+
+	Context("with action encodings", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				Produces("application/gob")
+			}
+		})
+
+		It("overrides the API encodings", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			action := Design.Resources["res"].Actions["show"]
+			Ω(action.EffectiveProduces()).Should(Equal([]string{"application/gob"}))
+			Ω(action.EffectiveConsumes()).Should(Equal([]string{"application/json"}))
+		})
+	})
+
+	Context("with an encoder implemented by a Go package", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				Produces("application/msgpack", func() {
+					EncodingPackage("github.com/example/msgpack")
+				})
+				Consumes("application/msgpack", func() {
+					EncodingPackage("github.com/example/msgpack")
+					EncodingFunction("NewDecoderFactory")
+				})
+			}
+		})
+
+		It("records the package and factory function", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			Ω(Design.Validate()).Should(BeNil())
+			action := Design.Resources["res"].Actions["show"]
+			Ω(action.Produces).Should(HaveLen(1))
+			Ω(action.Produces[0].PackagePath).Should(Equal("github.com/example/msgpack"))
+			Ω(action.Produces[0].FactoryFunction()).Should(Equal("NewFactory"))
+			Ω(action.Consumes).Should(HaveLen(1))
+			Ω(action.Consumes[0].MIMETypes).Should(Equal([]string{"application/msgpack"}))
+			Ω(action.Consumes[0].FactoryFunction()).Should(Equal("NewDecoderFactory"))
+		})
+	})
+
+	Context("with a factory function and no package", func() {
+		BeforeEach(func() {
+			apiDSL = func() {
+				Consumes("application/msgpack", func() {
+					EncodingFunction("NewDecoderFactory")
+				})
+			}
+		})
+
+		It("produces a validation error", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("must be used with a package path"))
+		})
+	})
+
+	Context("with an invalid content type", func() {
+		BeforeEach(func() {
+			apiDSL = func() {
+				Produces("application/json;;")
+			}
+		})
+
+		It("produces a validation error", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("invalid content type"))
+		})
+	})
+})
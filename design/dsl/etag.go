@@ -0,0 +1,22 @@
+package dsl
+
+// ETag enables ETags for the action. ETag can only be used in the Action DSL:
+//
+//	Action("show", func() {
+//		Routing(GET("/:id"))
+//		ETag()
+//		Response(OK)
+//	})
+//
+// The generated service adds an ETag to the successful responses of GET requests, it is computed
+// from the encoded response body unless the action sets it with the context SetETag method. The
+// service responds with 304 Not Modified to GET requests whose If-None-Match or
+// If-Modified-Since header shows that the client representation is up-to-date. Actions that
+// modify resources call the context CheckPreconditions method to honor the If-Match and
+// If-Unmodified-Since headers. The generated client methods accept the preconditions of the
+// requests.
+func ETag() {
+	if a, ok := actionDefinition(true); ok {
+		a.ETag = true
+	}
+}
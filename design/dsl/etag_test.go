@@ -0,0 +1,48 @@
+package dsl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("ETag", func() {
+	var actionDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		actionDSL = func() {
+			ETag()
+		}
+	})
+
+	JustBeforeEach(func() {
+		API("test", nil)
+		Resource("res", func() {
+			Action("show", func() {
+				Routing(GET("/:id"))
+				actionDSL()
+			})
+		})
+		dslErr = RunDSL()
+	})
+
+	It("enables ETags for the action", func() {
+		Ω(dslErr).ShouldNot(HaveOccurred())
+		Ω(Design.Resources["res"].Actions["show"].ETag).Should(BeTrue())
+	})
+
+	Context("without ETag", func() {
+		BeforeEach(func() {
+			actionDSL = func() {}
+		})
+
+		It("does not enable ETags", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			Ω(Design.Resources["res"].Actions["show"].ETag).Should(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,22 @@
+package dsl
+
+// ServerSentEvents declares that the response body is a stream of server-sent events
+// (text/event-stream). ServerSentEvents can only be used in the Response DSL, the response media
+// type must be defined in the design and is used to render each event:
+//
+//	Action("watch", func() {
+//		Routing(GET("/:id/events"))
+//		Response(OK, func() {
+//			Media(DashboardMedia)
+//			ServerSentEvents()
+//		})
+//	})
+//
+// The generated response helper method accepts a channel of media type instances, each instance
+// is validated and sent to the client as an event until the channel is closed or the client
+// disconnects.
+func ServerSentEvents() {
+	if r, ok := responseDefinition(true); ok {
+		r.Events = true
+	}
+}
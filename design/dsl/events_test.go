@@ -0,0 +1,100 @@
+package dsl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("ServerSentEvents", func() {
+	var actionDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		actionDSL = func() {
+			Response(OK, func() {
+				Media("application/vnd.goa.test.event")
+				ServerSentEvents()
+			})
+		}
+	})
+
+	JustBeforeEach(func() {
+		API("test", nil)
+		MediaType("application/vnd.goa.test.event", func() {
+			Attributes(func() {
+				Attribute("value", Integer)
+			})
+			View("default", func() {
+				Attribute("value")
+			})
+		})
+		Resource("res", func() {
+			Action("watch", func() {
+				Routing(GET("/:id/events"))
+				actionDSL()
+			})
+		})
+		dslErr = RunDSL()
+	})
+
+	It("records the events response", func() {
+		Ω(dslErr).ShouldNot(HaveOccurred())
+		Ω(Design.Validate()).Should(BeNil())
+		action := Design.Resources["res"].Actions["watch"]
+		Ω(action.Responses).Should(HaveKey(OK))
+		Ω(action.Responses[OK].Events).Should(BeTrue())
+		Ω(action.EventsResponse()).Should(Equal(action.Responses[OK]))
+	})
+
+	Context("with a media type that is not defined in the design", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				Response(OK, func() {
+					Media("application/json")
+					ServerSentEvents()
+				})
+			}
+		})
+
+		It("produces a validation error", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("must render a media type defined in the design"))
+		})
+	})
+
+	Context("with a streamed response", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				Response(OK, func() {
+					Media("application/vnd.goa.test.event")
+					Stream()
+					ServerSentEvents()
+				})
+			}
+		})
+
+		It("produces a validation error", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("cannot stream both raw data and server-sent events"))
+		})
+	})
+
+	Context("used outside of a response", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				ServerSentEvents()
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslErr).Should(HaveOccurred())
+			Ω(dslErr.Error()).Should(ContainSubstring("invalid use of ServerSentEvents"))
+		})
+	})
+})
@@ -0,0 +1,86 @@
+package dsl
+
+import "github.com/raphael/goa/design"
+
+// MultipartForm declares that the action payload is sent as a multipart form
+// (multipart/form-data). MultipartForm can only be used in the Action DSL, the payload attributes
+// are the form fields and File defines the uploaded files:
+//
+//	Action("upload", func() {
+//		Routing(POST("/"))
+//		MultipartForm()
+//		Payload(func() {
+//			Member("title", String)
+//			Required("title")
+//		})
+//		File("picture", func() {
+//			Required()
+//			MaxSize(10 * 1024 * 1024)
+//			ContentTypes("image/png", "image/jpeg")
+//		})
+//	})
+//
+// The payload attributes must be primitives or arrays of primitives. The generated action context
+// exposes the uploaded files as *multipart.FileHeader fields.
+func MultipartForm() {
+	if a, ok := actionDefinition(true); ok {
+		a.Form = design.MultipartFormContentType
+	}
+}
+
+// URLEncodedForm declares that the action payload is sent as a URL encoded form
+// (application/x-www-form-urlencoded). URLEncodedForm can only be used in the Action DSL, the
+// payload attributes are the form fields and must be primitives or arrays of primitives.
+func URLEncodedForm() {
+	if a, ok := actionDefinition(true); ok {
+		a.Form = design.URLEncodedFormContentType
+	}
+}
+
+// File defines a file uploaded in the multipart form payload of the action. The first argument is
+// the name of the form field containing the file, the optional DSL may use Description, Required,
+// MaxSize and ContentTypes. See MultipartForm.
+func File(name string, dsl ...func()) {
+	if len(dsl) > 1 {
+		ReportError("too many arguments given to File")
+		return
+	}
+	if a, ok := actionDefinition(true); ok {
+		for _, f := range a.Files {
+			if f.Name == name {
+				ReportError("file %#v is defined twice", name)
+				return
+			}
+		}
+		f := &design.FileDefinition{Name: name, Parent: a}
+		if len(dsl) == 1 && !ExecuteDSL(dsl[0], f) {
+			return
+		}
+		a.Files = append(a.Files, f)
+	}
+}
+
+// MaxSize sets the maximum size in bytes of the file.
+func MaxSize(size int64) {
+	if f, ok := fileDefinition(true); ok {
+		f.MaxSize = size
+	}
+}
+
+// ContentTypes lists the allowed file content types. The values may use wildcards such as
+// "image/*".
+func ContentTypes(contentTypes ...string) {
+	if f, ok := fileDefinition(true); ok {
+		f.ContentTypes = append(f.ContentTypes, contentTypes...)
+	}
+}
+
+// fileDefinition returns true and current context if it is a FileDefinition,
+// nil and false otherwise.
+func fileDefinition(failIfNotFile bool) (*design.FileDefinition, bool) {
+	f, ok := ctxStack.Current().(*design.FileDefinition)
+	if !ok && failIfNotFile {
+		incompatibleDSL(caller())
+	}
+	return f, ok
+}
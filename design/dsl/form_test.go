@@ -0,0 +1,91 @@
+package dsl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("MultipartForm", func() {
+	var actionDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		actionDSL = func() {
+			MultipartForm()
+			Payload(func() {
+				Member("title", String)
+			})
+			File("picture", func() {
+				Description("Picture")
+				Required()
+				MaxSize(1024)
+				ContentTypes("image/png", "image/jpeg")
+			})
+		}
+	})
+
+	JustBeforeEach(func() {
+		API("test", nil)
+		Resource("res", func() {
+			Action("upload", func() {
+				Routing(POST("/"))
+				actionDSL()
+			})
+		})
+		dslErr = RunDSL()
+	})
+
+	It("records the form and the files", func() {
+		Ω(dslErr).ShouldNot(HaveOccurred())
+		Ω(Design.Validate()).Should(BeNil())
+		action := Design.Resources["res"].Actions["upload"]
+		Ω(action.Form).Should(Equal(MultipartFormContentType))
+		Ω(action.EffectiveConsumes()).Should(Equal([]string{MultipartFormContentType}))
+		Ω(action.Files).Should(HaveLen(1))
+		f := action.Files[0]
+		Ω(f.Name).Should(Equal("picture"))
+		Ω(f.Description).Should(Equal("Picture"))
+		Ω(f.Required).Should(BeTrue())
+		Ω(f.MaxSize).Should(Equal(int64(1024)))
+		Ω(f.ContentTypes).Should(Equal([]string{"image/png", "image/jpeg"}))
+		Ω(f.Parent).Should(Equal(action))
+	})
+
+	Context("with files in a URL encoded form", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				URLEncodedForm()
+				File("picture")
+			}
+		})
+
+		It("produces a validation error", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("files can only be uploaded in a multipart form payload"))
+		})
+	})
+
+	Context("with a payload attribute that is an object", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				URLEncodedForm()
+				Payload(func() {
+					Member("origin", func() {
+						Attribute("country")
+					})
+				})
+			}
+		})
+
+		It("produces a validation error", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("must be a primitive or an array"))
+		})
+	})
+})
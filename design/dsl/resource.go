@@ -28,6 +28,7 @@ import "github.com/raphael/goa/design"
 //		CanonicalActionName("get")	// Name of action that returns canonical representation if not "show"
 //		UseTrait("Authenticated")	// Included trait if any, can appear more than once
 //		APIVersion("v1")		// API version exposing this resource, can appear more than once.
+//		Origin("*")			// CORS policy, overrides the API policy for the same origin
 //
 //		Action("show", func() {		// Action definition, can appear more than once
 //			// ... Action DSL
@@ -83,6 +83,68 @@ func Response(name string, paramsAndDSL ...interface{}) {
 	}
 }
 
+// ErrorResponse declares an error that the action may return. ErrorResponse may also appear in a
+// resource or API definition in which case the error may be returned by all the resource or API
+// actions. ErrorResponse accepts the same arguments as Response: the first argument is the name of the error and
+// the optional remaining arguments are the response template arguments followed by the DSL that
+// defines the error status code, media type and headers:
+//
+//	ErrorResponse("NotFound", func() {
+//		Status(404)
+//		Media(ErrorMedia)
+//	})
+//
+// As with Response the default responses can be used to define errors so that the example above
+// could also be written as:
+//
+//	ErrorResponse(NotFound, func() {
+//		Media(ErrorMedia)
+//	})
+//
+// goagen generates one constructor per error in the application package, the name of the
+// constructor is the name of the error prefixed with "Err" (ErrNotFound in the example above).
+// The constructor accepts the response body as argument if the error defines a media type.
+// Returning the value produced by the constructor from a controller action writes the error
+// response. Errors defined multiple times with the same name must have the same status code and
+// media type.
+func ErrorResponse(name string, paramsAndDSL ...interface{}) {
+	var errors map[string]*design.ResponseDefinition
+	var parent design.Definition
+	if a, ok := actionDefinition(false); ok {
+		if a.Errors == nil {
+			a.Errors = make(map[string]*design.ResponseDefinition)
+		}
+		errors, parent = a.Errors, a
+	} else if r, ok := resourceDefinition(false); ok {
+		if r.Errors == nil {
+			r.Errors = make(map[string]*design.ResponseDefinition)
+		}
+		errors, parent = r.Errors, r
+	} else {
+		var v *design.APIVersionDefinition
+		if a, ok := apiDefinition(false); ok {
+			v = a.APIVersionDefinition
+		} else if ver, ok := versionDefinition(true); ok {
+			v = ver
+		}
+		if v == nil {
+			return
+		}
+		if v.Errors == nil {
+			v.Errors = make(map[string]*design.ResponseDefinition)
+		}
+		errors, parent = v.Errors, v
+	}
+	if _, ok := errors[name]; ok {
+		ReportError("error %s is defined twice", name)
+		return
+	}
+	if resp := executeResponseDSL(name, paramsAndDSL...); resp != nil {
+		resp.Parent = parent
+		errors[name] = resp
+	}
+}
+
 // Status sets the Response status.
 func Status(status int) {
 	if r, ok := responseDefinition(true); ok {
@@ -198,3 +198,112 @@ var _ = Describe("Response", func() {
 	})
 
 })
+
+var _ = Describe("ErrorResponse", func() {
+	var apiDSL, resourceDSL, actionDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		apiDSL = nil
+		resourceDSL = nil
+		actionDSL = nil
+	})
+
+	JustBeforeEach(func() {
+		if apiDSL != nil {
+			API("api", apiDSL)
+		}
+		Resource("res", func() {
+			if resourceDSL != nil {
+				resourceDSL()
+			}
+			Action("action", func() {
+				Routing(GET("/"))
+				if actionDSL != nil {
+					actionDSL()
+				}
+			})
+		})
+		dslErr = RunDSL()
+	})
+
+	Context("in an action", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				ErrorResponse("NotFound", func() {
+					Status(404)
+					Media("application/json")
+				})
+			}
+		})
+
+		It("defines the action error", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			e := Design.Resources["res"].Actions["action"].Errors["NotFound"]
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Status).Should(Equal(404))
+			Ω(e.MediaType).Should(Equal("application/json"))
+			Ω(e.Parent).Should(Equal(Design.Resources["res"].Actions["action"]))
+		})
+	})
+
+	Context("in a resource using a default response", func() {
+		BeforeEach(func() {
+			resourceDSL = func() {
+				ErrorResponse(NotFound)
+			}
+		})
+
+		It("defines the resource error", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			e := Design.Resources["res"].Errors["NotFound"]
+			Ω(e).ShouldNot(BeNil())
+			Ω(e.Status).Should(Equal(404))
+		})
+	})
+
+	Context("in the API", func() {
+		BeforeEach(func() {
+			apiDSL = func() {
+				ErrorResponse("Conflict", func() {
+					Status(409)
+				})
+			}
+		})
+
+		It("defines the API error", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			Ω(Design.Errors).Should(HaveKey("Conflict"))
+			var names []string
+			Design.IterateErrors(func(e *ResponseDefinition) error {
+				names = append(names, e.Name)
+				return nil
+			})
+			Ω(names).Should(Equal([]string{"Conflict"}))
+		})
+	})
+
+	Context("defined twice with different status codes", func() {
+		BeforeEach(func() {
+			resourceDSL = func() {
+				ErrorResponse("NotFound", func() {
+					Status(404)
+				})
+			}
+			actionDSL = func() {
+				ErrorResponse("NotFound", func() {
+					Status(410)
+				})
+			}
+		})
+
+		It("produces a validation error", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("status or media type differs"))
+		})
+	})
+})
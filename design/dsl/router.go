@@ -0,0 +1,22 @@
+package dsl
+
+// Router sets the router used by the generated service. Router can only be used in the API DSL,
+// name is either "httprouter" (the default) or "tree":
+//
+//	API("cellar", func() {
+//		Router("tree")
+//	})
+//
+// The tree router accepts routes that httprouter rejects, for example routes with different
+// wildcard names at the same position ("/users/:id" and "/users/:name/posts") or routes with both
+// a wildcard and a static segment at the same position ("/users/:id" and "/users/me"). The
+// generated main function sets up the service mux with the corresponding goa router.
+func Router(name string) {
+	if name != "httprouter" && name != "tree" {
+		ReportError(`invalid router "%s", must be "httprouter" or "tree"`, name)
+		return
+	}
+	if a, ok := apiDefinition(true); ok {
+		a.Router = name
+	}
+}
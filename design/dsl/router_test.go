@@ -0,0 +1,68 @@
+package dsl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("Router", func() {
+	var apiDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		apiDSL = func() {
+			Router("tree")
+		}
+	})
+
+	JustBeforeEach(func() {
+		API("test", apiDSL)
+		Resource("user", func() {
+			BasePath("/users")
+			Action("show", func() {
+				Routing(GET("/:id"))
+			})
+			Action("me", func() {
+				Routing(GET("/me"))
+			})
+			Action("posts", func() {
+				Routing(GET("/:name/posts"))
+			})
+		})
+		dslErr = RunDSL()
+	})
+
+	It("accepts routes with different wildcard names at the same position", func() {
+		Ω(dslErr).ShouldNot(HaveOccurred())
+		Ω(Design.Router).Should(Equal("tree"))
+		Ω(Design.Validate()).Should(BeNil())
+	})
+
+	Context("with the default router", func() {
+		BeforeEach(func() {
+			apiDSL = nil
+		})
+
+		It("rejects routes with different wildcard names at the same position", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("Conflicting wildcards"))
+		})
+	})
+
+	Context("with an unknown router", func() {
+		BeforeEach(func() {
+			apiDSL = func() {
+				Router("gorilla")
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslErr).Should(HaveOccurred())
+		})
+	})
+})
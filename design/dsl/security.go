@@ -0,0 +1,261 @@
+package dsl
+
+import "github.com/raphael/goa/design"
+
+// BasicAuthSecurity defines a security scheme that uses HTTP basic authentication. The scheme is
+// defined in the API DSL and can then be required by the API, resources or actions with Security:
+//
+//	API("secured", func() {
+//		BasicAuthSecurity("basic", func() {
+//			Description("Use your account credentials")
+//		})
+//		Security("basic")
+//	})
+//
+// BasicAuthSecurity returns the scheme definition so that it may be given to Security in place
+// of the scheme name.
+func BasicAuthSecurity(name string, dsl ...func()) *design.SecuritySchemeDefinition {
+	return newSecurityScheme(design.BasicAuthSecurityKind, name, dsl)
+}
+
+// APIKeySecurity defines a security scheme where requests carry an API key in a header or in the
+// URL query string. The DSL must use Header or Query to specify where the key is located:
+//
+//	APIKeySecurity("key", func() {
+//		Header("X-API-Key")
+//	})
+func APIKeySecurity(name string, dsl ...func()) *design.SecuritySchemeDefinition {
+	return newSecurityScheme(design.APIKeySecurityKind, name, dsl)
+}
+
+// JWTSecurity defines a security scheme where requests carry a JSON Web Token. The token is read
+// from the "Authorization" header unless the DSL uses Header or Query to specify otherwise. The
+// DSL may list the scopes that tokens grant with Scope and the URL used to obtain tokens with
+// TokenURL:
+//
+//	JWTSecurity("jwt", func() {
+//		TokenURL("https://example.com/token")
+//		Scope("api:read", "Read access")
+//		Scope("api:write", "Write access")
+//	})
+func JWTSecurity(name string, dsl ...func()) *design.SecuritySchemeDefinition {
+	return newSecurityScheme(design.JWTSecurityKind, name, dsl, func(s *design.SecuritySchemeDefinition) {
+		s.In = "header"
+		s.ParamName = "Authorization"
+	})
+}
+
+// OAuth2Security defines a security scheme that uses OAuth2. The DSL must specify the OAuth2
+// flow using one of AccessCodeFlow, ImplicitFlow, PasswordFlow or ApplicationFlow and may list
+// the available scopes with Scope:
+//
+//	OAuth2Security("oauth2", func() {
+//		AccessCodeFlow("https://example.com/auth", "https://example.com/token")
+//		Scope("api:read", "Read access")
+//	})
+func OAuth2Security(name string, dsl ...func()) *design.SecuritySchemeDefinition {
+	return newSecurityScheme(design.OAuth2SecurityKind, name, dsl)
+}
+
+// Security sets the security requirement of the API, a resource or an action. The first argument
+// is either the name of a security scheme or the definition returned by one of the security
+// scheme DSL functions. The optional DSL lists the scopes required by OAuth2 and JWT schemes:
+//
+//	Action("update", func() {
+//		Security("jwt", func() {
+//			Scope("api:write")
+//		})
+//	})
+//
+// Actions inherit the requirement of their resource which itself inherits the requirement of
+// the API. Use NoSecurity to disable security for a given resource or action.
+func Security(scheme interface{}, dsl ...func()) {
+	var def *design.SecuritySchemeDefinition
+	switch s := scheme.(type) {
+	case string:
+		def = design.Design.SecuritySchemes[s]
+		if def == nil {
+			ReportError("unknown security scheme %#v", s)
+			return
+		}
+	case *design.SecuritySchemeDefinition:
+		def = s
+	default:
+		invalidArgError("string or *SecuritySchemeDefinition", scheme)
+		return
+	}
+	if len(dsl) > 1 {
+		ReportError("too many arguments given to Security")
+		return
+	}
+	sec := &design.SecurityDefinition{Scheme: def}
+	if len(dsl) == 1 {
+		if !ExecuteDSL(dsl[0], sec) {
+			return
+		}
+	}
+	setSecurity(sec)
+}
+
+// NoSecurity disables the security requirement inherited from the API or the parent resource.
+// NoSecurity can be used in a Resource or Action DSL.
+func NoSecurity() {
+	sec := &design.SecurityDefinition{
+		Scheme: &design.SecuritySchemeDefinition{Kind: design.NoSecurityKind},
+	}
+	if r, ok := resourceDefinition(false); ok {
+		r.Security = sec
+	} else if a, ok := actionDefinition(true); ok {
+		a.Security = sec
+	}
+}
+
+// Scope defines a scope available to an OAuth2 or JWT security scheme when used in the scheme
+// DSL, in this case the second argument is the scope description. Scope lists a scope required
+// by the requirement when used in the Security DSL.
+func Scope(name string, desc ...string) {
+	if len(desc) > 1 {
+		ReportError("too many arguments given to Scope")
+		return
+	}
+	if s, ok := securitySchemeDefinition(false); ok {
+		if s.Kind != design.OAuth2SecurityKind && s.Kind != design.JWTSecurityKind {
+			incompatibleDSL("Scope")
+			return
+		}
+		if s.Scopes == nil {
+			s.Scopes = make(map[string]string)
+		}
+		var d string
+		if len(desc) > 0 {
+			d = desc[0]
+		}
+		s.Scopes[name] = d
+	} else if sec, ok := securityDefinition(true); ok {
+		sec.Scopes = append(sec.Scopes, name)
+	}
+}
+
+// Query sets the name of the URL query string parameter that contains the credentials of an
+// APIKeySecurity or JWTSecurity scheme.
+func Query(name string) {
+	if s, ok := securitySchemeDefinition(true); ok {
+		if s.Kind != design.APIKeySecurityKind && s.Kind != design.JWTSecurityKind {
+			incompatibleDSL("Query")
+			return
+		}
+		s.In = "query"
+		s.ParamName = name
+	}
+}
+
+// TokenURL sets the URL used to obtain tokens for a JWTSecurity scheme.
+func TokenURL(url string) {
+	if s, ok := securitySchemeDefinition(true); ok {
+		s.TokenURL = url
+	}
+}
+
+// AccessCodeFlow sets the flow of an OAuth2Security scheme to the authorization code flow.
+func AccessCodeFlow(authorizationURL, tokenURL string) {
+	setOAuth2Flow("accessCode", authorizationURL, tokenURL)
+}
+
+// ImplicitFlow sets the flow of an OAuth2Security scheme to the implicit flow.
+func ImplicitFlow(authorizationURL string) {
+	setOAuth2Flow("implicit", authorizationURL, "")
+}
+
+// PasswordFlow sets the flow of an OAuth2Security scheme to the resource owner password
+// credentials flow.
+func PasswordFlow(tokenURL string) {
+	setOAuth2Flow("password", "", tokenURL)
+}
+
+// ApplicationFlow sets the flow of an OAuth2Security scheme to the client credentials flow.
+func ApplicationFlow(tokenURL string) {
+	setOAuth2Flow("application", "", tokenURL)
+}
+
+// newSecurityScheme creates a security scheme of the given kind, runs its DSL and records it in
+// the API definition.
+func newSecurityScheme(kind design.SecuritySchemeKind, name string, dsl []func(), init ...func(*design.SecuritySchemeDefinition)) *design.SecuritySchemeDefinition {
+	scheme := &design.SecuritySchemeDefinition{Kind: kind, Name: name}
+	for _, i := range init {
+		i(scheme)
+	}
+	a, ok := apiDefinition(true)
+	if !ok {
+		return scheme
+	}
+	if name == "" {
+		ReportError("security scheme name cannot be empty")
+		return scheme
+	}
+	if len(dsl) > 1 {
+		ReportError("too many arguments given to security scheme %s", name)
+		return scheme
+	}
+	if _, ok := a.SecuritySchemes[name]; ok {
+		ReportError("security scheme %s is defined twice", name)
+		return scheme
+	}
+	if len(dsl) == 1 {
+		if !ExecuteDSL(dsl[0], scheme) {
+			return scheme
+		}
+	}
+	if a.SecuritySchemes == nil {
+		a.SecuritySchemes = make(map[string]*design.SecuritySchemeDefinition)
+	}
+	a.SecuritySchemes[name] = scheme
+	return scheme
+}
+
+// setSecurity sets the security requirement of the current API, resource or action.
+func setSecurity(sec *design.SecurityDefinition) {
+	if a, ok := apiDefinition(false); ok {
+		a.Security = sec
+	} else if r, ok := resourceDefinition(false); ok {
+		r.Security = sec
+	} else if a, ok := actionDefinition(true); ok {
+		a.Security = sec
+	}
+}
+
+// setOAuth2Flow sets the flow of the current OAuth2 security scheme.
+func setOAuth2Flow(flow, authorizationURL, tokenURL string) {
+	if s, ok := securitySchemeDefinition(true); ok {
+		if s.Kind != design.OAuth2SecurityKind {
+			ReportError("%s can only be used in OAuth2Security", flow)
+			return
+		}
+		if s.Flow != "" {
+			ReportError("OAuth2 flow defined twice")
+			return
+		}
+		s.Flow = flow
+		s.AuthorizationURL = authorizationURL
+		s.TokenURL = tokenURL
+	}
+}
+
+// securitySchemeDefinition returns true and current context if it is a SecuritySchemeDefinition,
+// nil and false otherwise.
+func securitySchemeDefinition(failIfNotSecurityScheme bool) (*design.SecuritySchemeDefinition, bool) {
+	s, ok := ctxStack.Current().(*design.SecuritySchemeDefinition)
+	if !ok && failIfNotSecurityScheme {
+		incompatibleDSL(caller())
+	}
+	return s, ok
+}
+
+// securityDefinition returns true and current context if it is a SecurityDefinition,
+// nil and false otherwise.
+func securityDefinition(failIfNotSecurity bool) (*design.SecurityDefinition, bool) {
+	s, ok := ctxStack.Current().(*design.SecurityDefinition)
+	if !ok && failIfNotSecurity {
+		incompatibleDSL(caller())
+	}
+	return s, ok
+}
@@ -0,0 +1,213 @@
+package dsl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("Security", func() {
+	var apiDSL func()
+	var resDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		apiDSL = nil
+		resDSL = func() {
+			Action("show", func() {
+				Routing(GET("/:id"))
+			})
+		}
+	})
+
+	JustBeforeEach(func() {
+		API("test", apiDSL)
+		Resource("res", resDSL)
+		dslErr = RunDSL()
+	})
+
+	Context("with a basic auth scheme", func() {
+		const desc = "basic auth"
+
+		BeforeEach(func() {
+			apiDSL = func() {
+				BasicAuthSecurity("basic", func() {
+					Description(desc)
+				})
+				Security("basic")
+			}
+		})
+
+		It("records the scheme and the API requirement", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			Ω(Design.SecuritySchemes).Should(HaveKey("basic"))
+			scheme := Design.SecuritySchemes["basic"]
+			Ω(scheme.Kind).Should(Equal(BasicAuthSecurityKind))
+			Ω(scheme.Description).Should(Equal(desc))
+			Ω(Design.Security).ShouldNot(BeNil())
+			Ω(Design.Security.Scheme).Should(Equal(scheme))
+			Ω(Design.Validate()).Should(BeNil())
+		})
+
+		It("applies the API requirement to the actions", func() {
+			action := Design.Resources["res"].Actions["show"]
+			Ω(action.EffectiveSecurity()).Should(Equal(Design.Security))
+		})
+
+		Context("and an action that disables security", func() {
+			BeforeEach(func() {
+				resDSL = func() {
+					Action("show", func() {
+						Routing(GET("/:id"))
+						NoSecurity()
+					})
+				}
+			})
+
+			It("removes the requirement from the action", func() {
+				Ω(dslErr).ShouldNot(HaveOccurred())
+				action := Design.Resources["res"].Actions["show"]
+				Ω(action.Security).ShouldNot(BeNil())
+				Ω(action.Security.IsNone()).Should(BeTrue())
+				Ω(action.EffectiveSecurity()).Should(BeNil())
+			})
+		})
+	})
+
+	Context("with an API key scheme", func() {
+		BeforeEach(func() {
+			apiDSL = func() {
+				APIKeySecurity("key", func() {
+					Query("api_key")
+				})
+			}
+		})
+
+		It("sets the key location", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			scheme := Design.SecuritySchemes["key"]
+			Ω(scheme.In).Should(Equal("query"))
+			Ω(scheme.ParamName).Should(Equal("api_key"))
+			Ω(Design.Validate()).Should(BeNil())
+		})
+
+		Context("with no key location", func() {
+			BeforeEach(func() {
+				apiDSL = func() {
+					APIKeySecurity("key")
+				}
+			})
+
+			It("produces an invalid scheme", func() {
+				Ω(Design.SecuritySchemes["key"].Validate()).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Context("with a JWT scheme and a resource requirement with scopes", func() {
+		BeforeEach(func() {
+			apiDSL = func() {
+				JWTSecurity("jwt", func() {
+					Header("X-Token")
+					TokenURL("https://example.com/token")
+					Scope("read", "read access")
+					Scope("write", "write access")
+				})
+			}
+			resDSL = func() {
+				Security("jwt", func() {
+					Scope("read")
+				})
+				Action("show", func() {
+					Routing(GET("/:id"))
+				})
+				Action("update", func() {
+					Routing(PUT("/:id"))
+					Security("jwt", func() {
+						Scope("write")
+					})
+				})
+			}
+		})
+
+		It("records the scheme and the requirements", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			scheme := Design.SecuritySchemes["jwt"]
+			Ω(scheme.In).Should(Equal("header"))
+			Ω(scheme.ParamName).Should(Equal("X-Token"))
+			Ω(scheme.TokenURL).Should(Equal("https://example.com/token"))
+			Ω(scheme.Scopes).Should(Equal(map[string]string{"read": "read access", "write": "write access"}))
+			res := Design.Resources["res"]
+			Ω(res.Actions["show"].EffectiveSecurity().Scopes).Should(Equal([]string{"read"}))
+			Ω(res.Actions["update"].EffectiveSecurity().Scopes).Should(Equal([]string{"write"}))
+			Ω(Design.Validate()).Should(BeNil())
+		})
+
+		Context("with an unknown scope", func() {
+			BeforeEach(func() {
+				resDSL = func() {
+					Security("jwt", func() {
+						Scope("admin")
+					})
+					Action("show", func() {
+						Routing(GET("/:id"))
+					})
+				}
+			})
+
+			It("produces a validation error", func() {
+				verr := Design.Validate()
+				Ω(verr).ShouldNot(BeNil())
+				Ω(verr.Error()).Should(ContainSubstring(`scope "admin" is not defined`))
+			})
+		})
+	})
+
+	Context("with an OAuth2 scheme", func() {
+		BeforeEach(func() {
+			apiDSL = func() {
+				OAuth2Security("oauth2", func() {
+					AccessCodeFlow("https://example.com/auth", "https://example.com/token")
+					Scope("read")
+				})
+			}
+		})
+
+		It("sets the flow", func() {
+			Ω(dslErr).ShouldNot(HaveOccurred())
+			scheme := Design.SecuritySchemes["oauth2"]
+			Ω(scheme.Flow).Should(Equal("accessCode"))
+			Ω(scheme.AuthorizationURL).Should(Equal("https://example.com/auth"))
+			Ω(scheme.TokenURL).Should(Equal("https://example.com/token"))
+			Ω(Design.Validate()).Should(BeNil())
+		})
+
+		Context("with no flow", func() {
+			BeforeEach(func() {
+				apiDSL = func() {
+					OAuth2Security("oauth2")
+				}
+			})
+
+			It("produces an invalid scheme", func() {
+				Ω(Design.SecuritySchemes["oauth2"].Validate()).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Context("with an unknown scheme", func() {
+		BeforeEach(func() {
+			apiDSL = func() {
+				Security("unknown")
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslErr).Should(HaveOccurred())
+			Ω(dslErr.Error()).Should(ContainSubstring(`unknown security scheme "unknown"`))
+		})
+	})
+})
@@ -0,0 +1,39 @@
+package dsl
+
+// StreamPayload declares that the action reads the request body as a raw stream instead of a
+// payload decoded by the service decoders. StreamPayload can only be used in the Action DSL and
+// cannot be used together with Payload. maxSize is the maximum number of bytes that the action may
+// read from the request body, 0 means no limit:
+//
+//	Action("upload", func() {
+//		Routing(PUT("/:name"))
+//		StreamPayload(100 * 1024 * 1024) // Request bodies are limited to 100MB
+//		Response(NoContent)
+//	})
+//
+// The generated action context exposes the request body via its Body field.
+func StreamPayload(maxSize int64) {
+	if a, ok := actionDefinition(true); ok {
+		a.StreamPayload = true
+		a.MaxStreamSize = maxSize
+	}
+}
+
+// Stream declares that the action streams the response body instead of rendering a media type.
+// Stream can only be used in the Response DSL:
+//
+//	Action("download", func() {
+//		Routing(GET("/:name"))
+//		Response(OK, func() {
+//			Media("application/octet-stream")
+//			Stream()
+//		})
+//	})
+//
+// The generated response helper method accepts a function that writes the response body, the data
+// written by the function is flushed to the client as it is written.
+func Stream() {
+	if r, ok := responseDefinition(true); ok {
+		r.Stream = true
+	}
+}
@@ -0,0 +1,76 @@
+package dsl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("Stream", func() {
+	var actionDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		actionDSL = func() {
+			StreamPayload(1024)
+			Response(OK, func() {
+				Media("application/octet-stream")
+				Stream()
+			})
+		}
+	})
+
+	JustBeforeEach(func() {
+		API("test", nil)
+		Resource("res", func() {
+			Action("upload", func() {
+				Routing(PUT("/:id"))
+				actionDSL()
+			})
+		})
+		dslErr = RunDSL()
+	})
+
+	It("records the streamed payload and response", func() {
+		Ω(dslErr).ShouldNot(HaveOccurred())
+		Ω(Design.Validate()).Should(BeNil())
+		action := Design.Resources["res"].Actions["upload"]
+		Ω(action.StreamPayload).Should(BeTrue())
+		Ω(action.MaxStreamSize).Should(Equal(int64(1024)))
+		Ω(action.Payload).Should(BeNil())
+		Ω(action.Responses).Should(HaveKey(OK))
+		Ω(action.Responses[OK].Stream).Should(BeTrue())
+		Ω(action.Responses[OK].MediaType).Should(Equal("application/octet-stream"))
+	})
+
+	Context("with a payload", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				Payload(String)
+				StreamPayload(0)
+			}
+		})
+
+		It("produces a validation error", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("cannot define both a payload and a streamed payload"))
+		})
+	})
+
+	Context("with Stream used outside of a response", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				Stream()
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslErr).Should(HaveOccurred())
+			Ω(dslErr.Error()).Should(ContainSubstring("invalid use of Stream"))
+		})
+	})
+})
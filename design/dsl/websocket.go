@@ -0,0 +1,23 @@
+package dsl
+
+// WebSocket declares that the response upgrades the connection to a WebSocket connection.
+// WebSocket can only be used in the Response DSL of a SwitchingProtocols response of an action
+// with GET routes, the response media type must be defined in the design and is used to render
+// each message sent to the client:
+//
+//	Action("live", func() {
+//		Routing(GET("/:id/live"))
+//		Response(SwitchingProtocols, func() {
+//			Media(DashboardMedia)
+//			WebSocket()
+//		})
+//	})
+//
+// The generated response helper method accepts a channel of media type instances, each instance
+// is validated and sent to the client as a JSON message until the channel is closed or the client
+// closes the connection.
+func WebSocket() {
+	if r, ok := responseDefinition(true); ok {
+		r.WebSocket = true
+	}
+}
@@ -0,0 +1,113 @@
+package dsl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/raphael/goa/design"
+	. "github.com/raphael/goa/design/dsl"
+)
+
+var _ = Describe("WebSocket", func() {
+	var route func() *RouteDefinition
+	var actionDSL func()
+	var dslErr error
+
+	BeforeEach(func() {
+		InitDesign()
+		Errors = nil
+		route = func() *RouteDefinition { return GET("/:id/live") }
+		actionDSL = func() {
+			Response(SwitchingProtocols, func() {
+				Media("application/vnd.goa.test.event")
+				WebSocket()
+			})
+		}
+	})
+
+	JustBeforeEach(func() {
+		API("test", nil)
+		MediaType("application/vnd.goa.test.event", func() {
+			Attributes(func() {
+				Attribute("value", Integer)
+			})
+			View("default", func() {
+				Attribute("value")
+			})
+		})
+		Resource("res", func() {
+			Action("live", func() {
+				Routing(route())
+				actionDSL()
+			})
+		})
+		dslErr = RunDSL()
+	})
+
+	It("records the WebSocket response", func() {
+		Ω(dslErr).ShouldNot(HaveOccurred())
+		Ω(Design.Validate()).Should(BeNil())
+		action := Design.Resources["res"].Actions["live"]
+		Ω(action.Responses).Should(HaveKey(SwitchingProtocols))
+		Ω(action.Responses[SwitchingProtocols].WebSocket).Should(BeTrue())
+		Ω(action.WebSocketResponse()).Should(Equal(action.Responses[SwitchingProtocols]))
+	})
+
+	Context("with a response status other than 101", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				Response(OK, func() {
+					Media("application/vnd.goa.test.event")
+					WebSocket()
+				})
+			}
+		})
+
+		It("produces a validation error", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("status must be 101"))
+		})
+	})
+
+	Context("with a route that does not use GET", func() {
+		BeforeEach(func() {
+			route = func() *RouteDefinition { return POST("/:id/live") }
+		})
+
+		It("produces a validation error", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("must use GET routes"))
+		})
+	})
+
+	Context("with a media type that is not defined in the design", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				Response(SwitchingProtocols, func() {
+					Media("application/json")
+					WebSocket()
+				})
+			}
+		})
+
+		It("produces a validation error", func() {
+			verr := Design.Validate()
+			Ω(verr).ShouldNot(BeNil())
+			Ω(verr.Error()).Should(ContainSubstring("must render a media type defined in the design"))
+		})
+	})
+
+	Context("used outside of a response", func() {
+		BeforeEach(func() {
+			actionDSL = func() {
+				WebSocket()
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslErr).Should(HaveOccurred())
+			Ω(dslErr.Error()).Should(ContainSubstring("invalid use of WebSocket"))
+		})
+	})
+})
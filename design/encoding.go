@@ -0,0 +1,82 @@
+package design
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// EncodingDefinition defines an encoding supported by the API, a resource or an action: the
+	// content types of the request bodies it consumes or of the response bodies it produces.
+	EncodingDefinition struct {
+		// MIMETypes is the list of content types, e.g. "application/json".
+		MIMETypes []string
+		// PackagePath is the path to the Go package that implements the encoder or
+		// decoder, empty if the service built-in encoders and decoders handle the content
+		// types.
+		PackagePath string
+		// Function is the name of the package function that creates the encoder or
+		// decoder factory.
+		Function string
+	}
+)
+
+// DefaultEncodingFunction is the name of the package function that creates the encoder or decoder
+// factory when the encoding does not specify one.
+const DefaultEncodingFunction = "NewFactory"
+
+// Context returns the generic definition name used in error messages.
+func (enc *EncodingDefinition) Context() string {
+	return fmt.Sprintf("encoding for %s", strings.Join(enc.MIMETypes, ", "))
+}
+
+// FactoryFunction returns the name of the package function that creates the encoder or decoder
+// factory.
+func (enc *EncodingDefinition) FactoryFunction() string {
+	if enc.Function == "" {
+		return DefaultEncodingFunction
+	}
+	return enc.Function
+}
+
+// EffectiveProduces returns the content types of the response bodies produced by the action: the
+// content types listed by the action Produces definitions if there are any, the ones of the parent
+// resource otherwise and finally the ones of the API. It returns nil if none of these restrict the
+// content types.
+func (a *ActionDefinition) EffectiveProduces() []string {
+	encs := a.Produces
+	if len(encs) == 0 && a.Parent != nil {
+		encs = a.Parent.Produces
+	}
+	if len(encs) == 0 && Design != nil {
+		encs = Design.Produces
+	}
+	return mimeTypes(encs)
+}
+
+// EffectiveConsumes returns the content types of the request bodies consumed by the action: the
+// form content type if the payload is sent as a form, the content types listed by the action
+// Consumes definitions if there are any, the ones of the parent resource otherwise and finally the
+// ones of the API. It returns nil if none of these restrict the content types.
+func (a *ActionDefinition) EffectiveConsumes() []string {
+	if a.Form != "" {
+		return []string{a.Form}
+	}
+	encs := a.Consumes
+	if len(encs) == 0 && a.Parent != nil {
+		encs = a.Parent.Consumes
+	}
+	if len(encs) == 0 && Design != nil {
+		encs = Design.Consumes
+	}
+	return mimeTypes(encs)
+}
+
+// mimeTypes returns the content types of the given encodings.
+func mimeTypes(encs []*EncodingDefinition) []string {
+	var res []string
+	for _, enc := range encs {
+		res = append(res, enc.MIMETypes...)
+	}
+	return res
+}
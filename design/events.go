@@ -0,0 +1,26 @@
+package design
+
+// EventStreamContentType is the content type of responses that stream server-sent events.
+const EventStreamContentType = "text/event-stream"
+
+// EventsResponse returns the action response that streams server-sent events, nil if there is
+// none.
+func (a *ActionDefinition) EventsResponse() *ResponseDefinition {
+	for _, r := range a.Responses {
+		if r.Events {
+			return r
+		}
+	}
+	return nil
+}
+
+// WebSocketResponse returns the action response that upgrades the connection to a WebSocket
+// connection, nil if there is none.
+func (a *ActionDefinition) WebSocketResponse() *ResponseDefinition {
+	for _, r := range a.Responses {
+		if r.WebSocket {
+			return r
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package design
+
+import "fmt"
+
+const (
+	// MultipartFormContentType is the content type of multipart form payloads.
+	MultipartFormContentType = "multipart/form-data"
+
+	// URLEncodedFormContentType is the content type of URL encoded form payloads.
+	URLEncodedFormContentType = "application/x-www-form-urlencoded"
+)
+
+// FormValuesMaxSize is the number of bytes allowed in addition to the files maximum sizes in the
+// request bodies of actions that accept files and do not define a maximum body size, see
+// ActionDefinition.EffectiveMaxBodySize. It accounts for the other form values and for the
+// multipart encoding.
+var FormValuesMaxSize int64 = 1 << 20
+
+type (
+	// FileDefinition defines a file uploaded in a multipart form payload.
+	FileDefinition struct {
+		// Name is the name of the form field that contains the file.
+		Name string
+		// Description of the file
+		Description string
+		// Required is true if requests must include the file.
+		Required bool
+		// MaxSize is the maximum size of the file in bytes, 0 means no limit.
+		MaxSize int64
+		// ContentTypes lists the allowed file content types, any content type is allowed if
+		// empty.
+		ContentTypes []string
+		// Parent action
+		Parent *ActionDefinition
+	}
+)
+
+// Context returns the generic definition name used in error messages.
+func (f *FileDefinition) Context() string {
+	var suffix string
+	if f.Parent != nil {
+		suffix = fmt.Sprintf(" of %s", f.Parent.Context())
+	}
+	return fmt.Sprintf("file %#v%s", f.Name, suffix)
+}
@@ -0,0 +1,140 @@
+package design
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SecuritySchemeKind is the kind of a security scheme.
+type SecuritySchemeKind int
+
+const (
+	// NoSecurityKind is the kind of the scheme used by NoSecurity to disable security on a
+	// resource or action.
+	NoSecurityKind SecuritySchemeKind = iota
+	// BasicAuthSecurityKind is the kind of basic authentication schemes.
+	BasicAuthSecurityKind
+	// APIKeySecurityKind is the kind of API key schemes, the key is given in a header or in
+	// the URL query string.
+	APIKeySecurityKind
+	// JWTSecurityKind is the kind of JSON Web Token schemes.
+	JWTSecurityKind
+	// OAuth2SecurityKind is the kind of OAuth2 schemes.
+	OAuth2SecurityKind
+)
+
+type (
+	// SecuritySchemeDefinition defines a security scheme that can be required by the API,
+	// its resources or actions.
+	SecuritySchemeDefinition struct {
+		// Kind is the kind of scheme.
+		Kind SecuritySchemeKind
+		// Name is the name of the scheme used to reference it in requirements.
+		Name string
+		// Description of the scheme
+		Description string
+		// In is the location of the credentials for API key and JWT schemes, "header" or
+		// "query".
+		In string
+		// ParamName is the name of the header or query string parameter that contains the
+		// credentials for API key and JWT schemes.
+		ParamName string
+		// Flow is the OAuth2 flow, one of "accessCode", "implicit", "password" or
+		// "application".
+		Flow string
+		// AuthorizationURL is the OAuth2 authorization URL.
+		AuthorizationURL string
+		// TokenURL is the OAuth2 or JWT token URL.
+		TokenURL string
+		// Scopes lists the scopes available to OAuth2 and JWT schemes indexed by name. The
+		// values are the scope descriptions.
+		Scopes map[string]string
+		// Metadata is a list of key/value pairs
+		Metadata MetadataDefinition
+	}
+
+	// SecurityDefinition defines a security requirement: the scheme used to secure the API,
+	// a resource or an action together with the scopes required by the scheme if any.
+	SecurityDefinition struct {
+		// Scheme is the security scheme that must be satisfied.
+		Scheme *SecuritySchemeDefinition
+		// Scopes lists the scopes the request credentials must grant.
+		Scopes []string
+	}
+
+	// SecuritySchemeIterator is the type of functions given to IterateSecuritySchemes.
+	SecuritySchemeIterator func(s *SecuritySchemeDefinition) error
+)
+
+// Context returns the generic definition name used in error messages.
+func (s *SecuritySchemeDefinition) Context() string {
+	if s.Name != "" {
+		return fmt.Sprintf("security scheme %#v", s.Name)
+	}
+	return "unnamed security scheme"
+}
+
+// Type returns the name of the scheme kind as used in the design, e.g. "basic" or "oauth2".
+func (s *SecuritySchemeDefinition) Type() string {
+	switch s.Kind {
+	case BasicAuthSecurityKind:
+		return "basic"
+	case APIKeySecurityKind:
+		return "apiKey"
+	case JWTSecurityKind:
+		return "jwt"
+	case OAuth2SecurityKind:
+		return "oauth2"
+	default:
+		return "none"
+	}
+}
+
+// Context returns the generic definition name used in error messages.
+func (s *SecurityDefinition) Context() string {
+	if s.Scheme != nil && s.Scheme.Kind != NoSecurityKind {
+		return fmt.Sprintf("security requirement for %s", s.Scheme.Context())
+	}
+	return "security requirement"
+}
+
+// IsNone returns true if the requirement disables security (see NoSecurity).
+func (s *SecurityDefinition) IsNone() bool {
+	return s.Scheme == nil || s.Scheme.Kind == NoSecurityKind
+}
+
+// IterateSecuritySchemes calls the given iterator passing in each security scheme sorted in
+// alphabetical order. Iteration stops if an iterator returns an error and in this case
+// IterateSecuritySchemes returns that error.
+func (a *APIDefinition) IterateSecuritySchemes(it SecuritySchemeIterator) error {
+	names := make([]string, len(a.SecuritySchemes))
+	i := 0
+	for n := range a.SecuritySchemes {
+		names[i] = n
+		i++
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if err := it(a.SecuritySchemes[n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EffectiveSecurity returns the security requirement that applies to the action: the action
+// requirement if there is one, the parent resource requirement otherwise and finally the API
+// requirement. It returns nil if the action is not secured.
+func (a *ActionDefinition) EffectiveSecurity() *SecurityDefinition {
+	sec := a.Security
+	if sec == nil && a.Parent != nil {
+		sec = a.Parent.Security
+	}
+	if sec == nil && Design != nil {
+		sec = Design.Security
+	}
+	if sec == nil || sec.IsNone() {
+		return nil
+	}
+	return sec
+}
@@ -2,6 +2,7 @@ package design
 
 import (
 	"fmt"
+	"mime"
 	"net/url"
 	"strings"
 )
@@ -106,8 +107,22 @@ func (a *APIDefinition) Validate() *ValidationErrors {
 	a.validateContact(verr)
 	a.validateLicense(verr)
 	a.validateDocs(verr)
+	a.IterateSecuritySchemes(func(s *SecuritySchemeDefinition) error {
+		verr.Merge(s.Validate())
+		return nil
+	})
+	if a.Security != nil {
+		verr.Merge(a.Security.Validate())
+	}
+	for _, cors := range a.Origins {
+		verr.Merge(cors.Validate())
+	}
+	validateEncodings(verr, a.Consumes, a.Produces)
 
 	a.IterateVersions(func(ver *APIVersionDefinition) error {
+		if ver.Deprecation != nil {
+			verr.Merge(ver.Deprecation.Validate())
+		}
 		var allRoutes []*routeInfo
 		a.IterateResources(func(r *ResourceDefinition) error {
 			verr.Merge(r.Validate(ver))
@@ -135,28 +150,31 @@ func (a *APIDefinition) Validate() *ValidationErrors {
 			})
 			return nil
 		})
-		for _, route := range allRoutes {
-			for _, other := range allRoutes {
-				if route == other {
-					continue
-				}
-				if strings.HasPrefix(route.Key, other.Key) {
-					diffs := route.DifferentWildcards(other)
-					if len(diffs) > 0 {
-						var msg string
-						conflicts := make([]string, len(diffs))
-						for i, d := range diffs {
-							conflicts[i] = fmt.Sprintf(`"%s" from %s and "%s" from %s`, d[0].Name, d[0].Orig.Context(), d[1].Name, d[1].Orig.Context())
+		// The tree router accepts routes with different wildcard names at the same position.
+		if a.Router != "tree" {
+			for _, route := range allRoutes {
+				for _, other := range allRoutes {
+					if route == other {
+						continue
+					}
+					if strings.HasPrefix(route.Key, other.Key) {
+						diffs := route.DifferentWildcards(other)
+						if len(diffs) > 0 {
+							var msg string
+							conflicts := make([]string, len(diffs))
+							for i, d := range diffs {
+								conflicts[i] = fmt.Sprintf(`"%s" from %s and "%s" from %s`, d[0].Name, d[0].Orig.Context(), d[1].Name, d[1].Orig.Context())
+							}
+							msg = fmt.Sprintf("%s", strings.Join(conflicts, ", "))
+							verr.Add(route.Action,
+								`route "%s" conflicts with route "%s" of %s action %s. Make sure wildcards at the same positions have the same name. Conflicting wildcards are %s.`,
+								route.Route.FullPath(ver),
+								other.Route.FullPath(ver),
+								other.Resource.Name,
+								other.Action.Name,
+								msg,
+							)
 						}
-						msg = fmt.Sprintf("%s", strings.Join(conflicts, ", "))
-						verr.Add(route.Action,
-							`route "%s" conflicts with route "%s" of %s action %s. Make sure wildcards at the same positions have the same name. Conflicting wildcards are %s.`,
-							route.Route.FullPath(ver),
-							other.Route.FullPath(ver),
-							other.Resource.Name,
-							other.Action.Name,
-							msg,
-						)
 					}
 				}
 			}
@@ -173,6 +191,7 @@ func (a *APIDefinition) Validate() *ValidationErrors {
 			verr.Merge(r.Validate())
 			return nil
 		})
+		verr.Merge(ver.validateErrors())
 		return nil
 	})
 
@@ -223,6 +242,16 @@ func (r *ResourceDefinition) Validate(version *APIVersionDefinition) *Validation
 	if r.Params != nil {
 		verr.Merge(r.Params.Validate("resource parameters", r))
 	}
+	if r.Deprecation != nil {
+		verr.Merge(r.Deprecation.Validate())
+	}
+	if r.Security != nil {
+		verr.Merge(r.Security.Validate())
+	}
+	for _, cors := range r.Origins {
+		verr.Merge(cors.Validate())
+	}
+	validateEncodings(verr, r.Consumes, r.Produces)
 	if !r.SupportsNoVersion() {
 		if err := CanUse(r, Design); err != nil {
 			verr.Add(r, "Invalid API version in list")
@@ -298,6 +327,9 @@ func (a *ActionDefinition) Validate(version *APIVersionDefinition) *ValidationEr
 	if len(a.Routes) == 0 {
 		verr.Add(a, "No route defined for action")
 	}
+	if a.Deprecation != nil {
+		verr.Merge(a.Deprecation.Validate())
+	}
 	for i, r := range a.Responses {
 		for j, r2 := range a.Responses {
 			if i != j && r.Status == r2.Status {
@@ -305,14 +337,96 @@ func (a *ActionDefinition) Validate(version *APIVersionDefinition) *ValidationEr
 			}
 		}
 		verr.Merge(r.Validate())
+		if r.Events && a.EventsResponse() != r {
+			verr.Add(r, "action cannot define multiple server-sent events responses")
+		}
+		if r.WebSocket && a.WebSocketResponse() != r {
+			verr.Add(r, "action cannot define multiple WebSocket responses")
+		}
+	}
+	if a.WebSocketResponse() != nil {
+		for _, route := range a.Routes {
+			if route.Verb != "GET" {
+				verr.Add(a, "WebSocket actions must use GET routes, %s is not supported", route.Verb)
+			}
+		}
 	}
 	verr.Merge(a.ValidateParams(version))
 	if a.Payload != nil {
 		verr.Merge(a.Payload.Validate("action payload", a))
+		if a.StreamPayload {
+			verr.Add(a, "action cannot define both a payload and a streamed payload")
+		}
 	}
+	if a.MaxStreamSize < 0 {
+		verr.Add(a, "maximum streamed payload size cannot be negative")
+	}
+	verr.Merge(a.validateForm())
 	if a.Parent == nil {
 		verr.Add(a, "missing parent resource")
 	}
+	if _, err := a.Timeout(); err != nil {
+		verr.Add(a, "invalid timeout metadata: %s", err)
+	}
+	if _, _, err := a.RateLimit(); err != nil {
+		verr.Add(a, "invalid ratelimit metadata: %s", err)
+	}
+	if a.Security != nil {
+		verr.Merge(a.Security.Validate())
+	}
+	validateEncodings(verr, a.Consumes, a.Produces)
+	return verr.AsError()
+}
+
+// validateForm checks that the form payload attributes and files can be sent in a form.
+func (a *ActionDefinition) validateForm() *ValidationErrors {
+	verr := new(ValidationErrors)
+	if a.Form == "" {
+		if len(a.Files) > 0 {
+			verr.Add(a, "files can only be uploaded in a multipart form payload, use MultipartForm")
+		}
+		return verr.AsError()
+	}
+	if a.Form != MultipartFormContentType && a.Form != URLEncodedFormContentType {
+		verr.Add(a, "invalid form content type %#v", a.Form)
+	}
+	if len(a.Files) > 0 && a.Form != MultipartFormContentType {
+		verr.Add(a, "files can only be uploaded in a multipart form payload, use MultipartForm")
+	}
+	if a.StreamPayload {
+		verr.Add(a, "action cannot define both a form payload and a streamed payload")
+	}
+	if a.Payload != nil {
+		if !a.Payload.Type.IsObject() {
+			verr.Add(a, "form payload must be an object")
+		} else {
+			for n, att := range a.Payload.Type.ToObject() {
+				if att.Type.IsObject() || att.Type.Kind() == HashKind {
+					verr.Add(a, "form payload attribute %#v must be a primitive or an array", n)
+				} else if arr, ok := att.Type.(*Array); ok && !arr.ElemType.Type.IsPrimitive() {
+					verr.Add(a, "form payload attribute %#v must be an array of primitives", n)
+				}
+			}
+		}
+	}
+	for _, f := range a.Files {
+		if f.Name == "" {
+			verr.Add(f, "file name cannot be empty")
+		}
+		if f.MaxSize < 0 {
+			verr.Add(f, "maximum file size cannot be negative")
+		}
+		for _, ct := range f.ContentTypes {
+			if _, _, err := mime.ParseMediaType(ct); err != nil {
+				verr.Add(f, "invalid content type %#v: %s", ct, err)
+			}
+		}
+		if a.Payload != nil && a.Payload.Type.IsObject() {
+			if _, ok := a.Payload.Type.ToObject()[f.Name]; ok {
+				verr.Add(f, "file has the same name as a payload attribute")
+			}
+		}
+	}
 	return verr.AsError()
 }
 
@@ -420,6 +534,44 @@ func (r *ResponseDefinition) Validate() *ValidationErrors {
 	if r.Status == 0 {
 		verr.Add(r, "response status not defined")
 	}
+	if r.Events {
+		if r.Stream {
+			verr.Add(r, "response cannot stream both raw data and server-sent events")
+		}
+		if Design != nil && Design.MediaTypeWithIdentifier(r.MediaType) == nil {
+			verr.Add(r, "server-sent events must render a media type defined in the design")
+		}
+	}
+	if r.WebSocket {
+		if r.Stream || r.Events {
+			verr.Add(r, "WebSocket response cannot also stream raw data or server-sent events")
+		}
+		if r.Status != 101 {
+			verr.Add(r, "WebSocket response status must be 101 (SwitchingProtocols)")
+		}
+		if Design != nil && Design.MediaTypeWithIdentifier(r.MediaType) == nil {
+			verr.Add(r, "WebSocket messages must render a media type defined in the design")
+		}
+	}
+	return verr.AsError()
+}
+
+// validateErrors checks that the errors of the version are consistent: errors defined multiple
+// times with the same name must use the same status code and media type.
+func (v *APIVersionDefinition) validateErrors() *ValidationErrors {
+	verr := new(ValidationErrors)
+	seen := make(map[string]*ResponseDefinition)
+	for _, e := range v.allErrors() {
+		verr.Merge(e.Validate())
+		other, ok := seen[e.Name]
+		if !ok {
+			seen[e.Name] = e
+			continue
+		}
+		if other.Status != e.Status || other.MediaType != e.MediaType {
+			verr.Add(e, "status or media type differs from %s", other.Context())
+		}
+	}
 	return verr.AsError()
 }
 
@@ -567,3 +719,117 @@ func (v *ViewDefinition) Validate() *ValidationErrors {
 	verr.Merge(v.AttributeDefinition.Validate("", v))
 	return verr.AsError()
 }
+
+// Validate checks that the security scheme definition is consistent: API key schemes define where
+// the key is located and OAuth2 schemes define a flow together with the URLs the flow requires.
+func (s *SecuritySchemeDefinition) Validate() *ValidationErrors {
+	verr := new(ValidationErrors)
+	if s.Name == "" {
+		verr.Add(s, "security scheme name cannot be empty")
+	}
+	switch s.Kind {
+	case APIKeySecurityKind, JWTSecurityKind:
+		if s.In != "header" && s.In != "query" {
+			verr.Add(s, `missing header or query string parameter name, use Header or Query`)
+		}
+	case OAuth2SecurityKind:
+		switch s.Flow {
+		case "accessCode":
+			if s.AuthorizationURL == "" || s.TokenURL == "" {
+				verr.Add(s, "access code flow requires both an authorization and a token URL")
+			}
+		case "implicit":
+			if s.AuthorizationURL == "" {
+				verr.Add(s, "implicit flow requires an authorization URL")
+			}
+		case "password", "application":
+			if s.TokenURL == "" {
+				verr.Add(s, "%s flow requires a token URL", s.Flow)
+			}
+		case "":
+			verr.Add(s, "missing OAuth2 flow")
+		default:
+			verr.Add(s, "invalid OAuth2 flow %#v", s.Flow)
+		}
+	}
+	for _, u := range []string{s.AuthorizationURL, s.TokenURL} {
+		if u != "" {
+			if _, err := url.Parse(u); err != nil {
+				verr.Add(s, "invalid URL %#v: %s", u, err)
+			}
+		}
+	}
+	return verr.AsError()
+}
+
+// Validate checks that the security requirement refers to an existing scheme and only lists
+// scopes defined by that scheme.
+func (s *SecurityDefinition) Validate() *ValidationErrors {
+	verr := new(ValidationErrors)
+	if s.IsNone() {
+		return nil
+	}
+	if Design == nil || Design.SecuritySchemes[s.Scheme.Name] != s.Scheme {
+		verr.Add(s, "unknown security scheme %#v", s.Scheme.Name)
+	}
+	if len(s.Scopes) > 0 && s.Scheme.Kind != OAuth2SecurityKind && s.Scheme.Kind != JWTSecurityKind {
+		verr.Add(s, "scopes can only be required by OAuth2 or JWT security schemes")
+		return verr
+	}
+	for _, scope := range s.Scopes {
+		if _, ok := s.Scheme.Scopes[scope]; !ok {
+			verr.Add(s, "scope %#v is not defined by %s", scope, s.Scheme.Context())
+		}
+	}
+	return verr.AsError()
+}
+
+// Validate checks that the CORS policy origin is not empty and contains at most one wildcard.
+func (cors *CORSDefinition) Validate() *ValidationErrors {
+	verr := new(ValidationErrors)
+	if cors.Origin == "" {
+		verr.Add(cors, "CORS policy origin cannot be empty")
+	} else if strings.Count(cors.Origin, "*") > 1 {
+		verr.Add(cors, "CORS policy origin can contain at most one wildcard")
+	}
+	return verr.AsError()
+}
+
+// Validate checks that the encoding lists at least one content type and that all its content types
+// are valid.
+func (enc *EncodingDefinition) Validate() *ValidationErrors {
+	verr := new(ValidationErrors)
+	if len(enc.MIMETypes) == 0 {
+		verr.Add(enc, "encoding must list at least one content type")
+	}
+	for _, m := range enc.MIMETypes {
+		if _, _, err := mime.ParseMediaType(m); err != nil {
+			verr.Add(enc, "invalid content type %#v: %s", m, err)
+		}
+	}
+	if enc.Function != "" && enc.PackagePath == "" {
+		verr.Add(enc, "function %#v must be used with a package path", enc.Function)
+	}
+	return verr.AsError()
+}
+
+// Validate checks that the deprecation replacement URL is valid.
+func (d *DeprecationDefinition) Validate() *ValidationErrors {
+	verr := new(ValidationErrors)
+	if d.URL != "" {
+		if _, err := url.ParseRequestURI(d.URL); err != nil {
+			verr.Add(d, "invalid deprecation URL value: %s", err)
+		}
+	}
+	return verr.AsError()
+}
+
+// validateEncodings validates the given consumed and produced encodings.
+func validateEncodings(verr *ValidationErrors, consumes, produces []*EncodingDefinition) {
+	for _, enc := range consumes {
+		verr.Merge(enc.Validate())
+	}
+	for _, enc := range produces {
+		verr.Merge(enc.Validate())
+	}
+}
@@ -86,11 +86,14 @@ interface shown above all return an error value. The controller or service-wide
 controller specific error handler) function is invoked whenever the value returned by a controller
 action is not nil. The handler gets both the request context and the error as argument.
 
-The default handler implementation returns a response with status code 500 containing the error
-message in the body. A different error handler can be specificied using the SetErrorHandler
-function on either a controller or service wide. goa comes with an alternative error handler - the
-TerseErrorHandler - which also returns a response with status 500 but does not write the error
-message to the body of the response.
+The default handler implementation renders the error as a RFC 7807 problem (see NewProblem): the
+response body describes the problem type, title, status, detail and - for validation errors - the
+invalid parameters. The content type of the response is negotiated using the request Accept header
+and defaults to "application/problem+json". Request validation errors produce responses with
+status code 400 while other errors produce responses with status code 500. A different error
+handler can be specificied using the SetErrorHandler function on either a controller or service
+wide. goa comes with an alternative error handler - the TerseErrorHandler - which does not write the
+error message to the body of responses with status code 500.
 
 Middleware
 
@@ -5,12 +5,17 @@ import (
 	"encoding/gob"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"mime"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
 
-	"github.com/golang/gddo/httputil"
+	"github.com/golang/gddo/httputil/header"
 )
 
 type (
@@ -71,6 +76,14 @@ type (
 	// gobFactory uses encoding/gob to act as an DecoderFactory and EncoderFactory
 	gobFactory struct{}
 
+	// formFactory uses net/url to act as a DecoderFactory for URL encoded forms
+	formFactory struct{}
+
+	// formDecoder decodes URL encoded forms, see DecodeForm.
+	formDecoder struct {
+		r io.Reader
+	}
+
 	// // Unmarshaler is the interface implemented by objects that can unmarshal themselves.
 	// // The input can be assumed to be a valid encoding that matches the Content-Type request header.
 	// // Unmarshal must copy the data if it wishes to retain the data after returning.
@@ -91,13 +104,22 @@ var (
 	// GobContentTypes is a slice of default Content-Type headers that will use stdlib
 	// encoding/gob to unmarshal unless overwritten using SetDecoder
 	GobContentTypes = []string{"application/gob"}
+
+	// FormContentTypes is a slice of default Content-Type headers that will use net/url
+	// to unmarshal unless overwritten using SetDecoder
+	FormContentTypes = []string{"application/x-www-form-urlencoded"}
+
+	// MaxFormMemory is the maximum number of bytes of a multipart form request body stored
+	// in memory, the remaining file data is stored in temporary files.
+	MaxFormMemory int64 = 32 << 20
 )
 
 // initEncoding initializes all the decoder/encoder pools with the Content-Types found
 // in JSONContentTypes and GobContentTypes. JSON is set as the default decoder.
 func (app *Application) initEncoding() {
 	// initialize maps
-	contentTypeCount := len(JSONContentTypes) + len(XMLContentTypes) + len(GobContentTypes)
+	contentTypeCount := len(JSONContentTypes) + len(XMLContentTypes) + len(GobContentTypes) +
+		len(FormContentTypes)
 	app.decoderPools = make(map[string]*decoderPool, contentTypeCount)
 	app.encoderPools = make(map[string]*encoderPool, contentTypeCount)
 
@@ -115,10 +137,14 @@ func (app *Application) initEncoding() {
 	gf := &gobFactory{}
 	app.SetDecoder(gf, false, GobContentTypes...)
 	app.SetEncoder(gf, false, GobContentTypes...)
+
+	// Add form support
+	app.SetDecoder(&formFactory{}, false, FormContentTypes...)
 }
 
 // DecodeRequest uses registered Decoders to unmarshal the request body based on
-// the request `Content-Type` header
+// the request `Content-Type` header. Multipart form values are decoded like URL encoded forms,
+// see DecodeForm, the uploaded files are available via Context.FormFile.
 func (app *Application) DecodeRequest(ctx *Context, v interface{}) error {
 	body := ctx.Request().Body
 	contentType := ctx.Request().Header.Get("Content-Type")
@@ -133,7 +159,22 @@ func (app *Application) DecodeRequest(ctx *Context, v interface{}) error {
 			contentType = mediaType
 		}
 	}
+	if contentType == "multipart/form-data" {
+		form, err := ctx.multipartForm()
+		if err == nil {
+			err = DecodeForm(form.Value, v)
+		}
+		if err != nil {
+			ctx.Error(err.Error(), "ContentType", contentType)
+		}
+		return err
+	}
 	p = app.decoderPools[contentType]
+	if p == nil {
+		if base := suffixBaseType(contentType); base != "" {
+			p = app.decoderPools[base]
+		}
+	}
 
 	// Do not attempt to decode request bodies for which no decoder has been setup.
 	// These may be handled differently by the service.
@@ -225,11 +266,58 @@ func (p *decoderPool) Put(d Decoder) {
 	p.pool.Put(d)
 }
 
-// EncodeResponse uses registered Encoders to marshal the response body based on the request
-// `Accept` header and writes it to the http.ResponseWriter
-func (app *Application) EncodeResponse(ctx *Context, v interface{}) error {
-	contentType := httputil.NegotiateContentType(ctx.Request(), app.encodableContentTypes, "*/*")
-	p := app.encoderPools[contentType]
+// EncodeResponse negotiates the response content type, sets the response `Content-Type` header and
+// writes the response with the given status code using the matching registered Encoder.
+// The content types offered to the client are:
+//
+// - the content type set in the response `Content-Type` header if any (the code generated by
+// goagen sets it to the identifier of the designed response media type),
+//
+// - followed by the content types the action produces as set by Produces or the content types
+// of all the registered encoders if the action does not restrict them.
+//
+// The first offer is used if the request has no `Accept` header. Offers with a structured syntax
+// suffix such as "application/vnd.goa.example+json" are encoded with the encoder registered for
+// the corresponding base type ("application/json") if there is no encoder registered for the
+// offer itself, and with the default encoder if there is none for the base type either.
+// EncodeResponse returns an error with ID ErrNotAcceptable and does not write the response if no
+// offer is acceptable.
+func (app *Application) EncodeResponse(ctx *Context, code int, v interface{}) error {
+	respHeader := ctx.Header()
+	var offers []string
+	if respHeader != nil {
+		if ct := respHeader.Get("Content-Type"); ct != "" {
+			if mediaType, _, err := mime.ParseMediaType(ct); err == nil {
+				ct = mediaType
+			}
+			offers = append(offers, ct)
+		}
+	}
+	produces := ctx.Produces()
+	if produces == nil {
+		produces = app.offeredContentTypes()
+	}
+	offers = append(offers, produces...)
+	contentType := ""
+	if req := ctx.Request(); req != nil {
+		contentType = negotiateContentType(req, offers)
+	} else if len(offers) > 0 {
+		contentType = offers[0]
+	}
+	if contentType == "" {
+		return &TypedError{
+			ID:   ErrNotAcceptable,
+			Mesg: fmt.Sprintf("acceptable content types are %s", strings.Join(offers, ", ")),
+		}
+	}
+	p := app.encoderPool(contentType)
+	if p == nil {
+		return fmt.Errorf("no encoder registered for content type %s", contentType)
+	}
+	if respHeader != nil {
+		respHeader.Set("Content-Type", contentType)
+	}
+	ctx.WriteHeader(code)
 
 	// the encoderPool will handle whether or not a pool is actually in use
 	encoder := p.Get(ctx)
@@ -242,6 +330,165 @@ func (app *Application) EncodeResponse(ctx *Context, v interface{}) error {
 	return nil
 }
 
+// Produces returns a middleware that restricts the content types the action may use to render
+// response bodies to the given content types, see EncodeResponse. The middleware returns an error
+// with ID ErrNotAcceptable without calling the handler if none of the content types is acceptable
+// to the client.
+// Code generated by goagen uses Produces to wrap the handlers of actions whose design define the
+// response media types.
+func Produces(contentTypes ...string) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx *Context) error {
+			if req := ctx.Request(); req != nil && req.Header.Get("Accept") != "" {
+				if negotiateContentType(req, contentTypes) == "" {
+					return &TypedError{
+						ID: ErrNotAcceptable,
+						Mesg: fmt.Sprintf("acceptable content types are %s",
+							strings.Join(contentTypes, ", ")),
+					}
+				}
+			}
+			ctx.SetValue(producesKey, contentTypes)
+			return h(ctx)
+		}
+	}
+}
+
+// Consumes returns a middleware that checks that the content type of the request body is one of
+// the given content types. The content types may use wildcards such as "application/*". The
+// middleware returns an error with ID ErrUnsupportedMediaType without calling the handler if the
+// content type of the request body is not supported. Requests with no `Content-Type` header are
+// assumed to contain JSON, see DecodeRequest.
+// Code generated by goagen uses Consumes to wrap the handlers of actions whose design define the
+// content types they consume.
+func Consumes(contentTypes ...string) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx *Context) error {
+			if err := checkContentType(ctx.Request(), contentTypes); err != nil {
+				return err
+			}
+			return h(ctx)
+		}
+	}
+}
+
+// checkContentType returns an error with ID ErrUnsupportedMediaType if the request has a body
+// whose content type is not one of the given content types, nil otherwise.
+func checkContentType(req *http.Request, contentTypes []string) error {
+	if req == nil || (req.ContentLength == 0 && len(req.TransferEncoding) == 0) {
+		return nil
+	}
+	contentType := req.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json"
+	} else if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	for _, ct := range contentTypes {
+		if matchContentType(ct, contentType) >= 0 {
+			return nil
+		}
+	}
+	return &TypedError{
+		ID: ErrUnsupportedMediaType,
+		Mesg: fmt.Sprintf("content type %s is not supported, supported content types are %s",
+			contentType, strings.Join(contentTypes, ", ")),
+	}
+}
+
+// encoderPool returns the pool of the encoder registered for the given content type, for the
+// content type structured syntax suffix base type or the default encoder in this order.
+func (app *Application) encoderPool(contentType string) *encoderPool {
+	if p, ok := app.encoderPools[contentType]; ok {
+		return p
+	}
+	if base := suffixBaseType(contentType); base != "" {
+		if p, ok := app.encoderPools[base]; ok {
+			return p
+		}
+	}
+	return app.encoderPools["*/*"]
+}
+
+// offeredContentTypes returns the content types of the registered encoders, the content type of
+// the default encoder comes first.
+func (app *Application) offeredContentTypes() []string {
+	offers := make([]string, 0, len(app.encodableContentTypes))
+	if app.defaultContentType != "" {
+		offers = append(offers, app.defaultContentType)
+	}
+	for _, ct := range app.encodableContentTypes {
+		if ct != "*/*" && ct != app.defaultContentType {
+			offers = append(offers, ct)
+		}
+	}
+	return offers
+}
+
+// negotiateContentType returns the offer that best matches the request `Accept` header, "" if
+// none is acceptable. It returns the first offer if the request has no `Accept` header. Offers with
+// a structured syntax suffix also match the suffix base type so that for example
+// "application/vnd.goa.example+json" is acceptable to clients that accept "application/json".
+// Exact matches are preferred over suffix matches which are preferred over wildcard matches when
+// the quality values are equal.
+func negotiateContentType(req *http.Request, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	specs := header.ParseAccept(req.Header, "Accept")
+	if len(specs) == 0 {
+		return offers[0]
+	}
+	bestOffer := ""
+	bestQ := 0.0
+	bestRank := 0
+	for _, offer := range offers {
+		for _, spec := range specs {
+			rank := matchContentType(spec.Value, offer)
+			if rank < 0 || spec.Q == 0 {
+				continue
+			}
+			if spec.Q > bestQ || (spec.Q == bestQ && rank < bestRank) {
+				bestOffer = offer
+				bestQ = spec.Q
+				bestRank = rank
+			}
+		}
+	}
+	return bestOffer
+}
+
+// matchContentType returns -1 if contentType does not match spec. It returns 0 if contentType
+// is spec, 1 if spec is the contentType structured syntax suffix base type, 2 if spec is a
+// "type/*" wildcard that matches contentType and 3 if spec is "*/*".
+func matchContentType(spec, contentType string) int {
+	switch {
+	case strings.EqualFold(spec, contentType):
+		return 0
+	case spec == "*/*":
+		return 3
+	case strings.HasSuffix(spec, "/*"):
+		if strings.HasPrefix(strings.ToLower(contentType), strings.ToLower(spec[:len(spec)-1])) {
+			return 2
+		}
+	case strings.EqualFold(spec, suffixBaseType(contentType)):
+		return 1
+	}
+	return -1
+}
+
+// suffixBaseType returns the base type of the given content type structured syntax suffix, e.g.
+// "application/json" for "application/vnd.goa.example+json". It returns "" if the content type
+// has no suffix.
+func suffixBaseType(contentType string) string {
+	slash := strings.Index(contentType, "/")
+	plus := strings.LastIndex(contentType, "+")
+	if slash < 0 || plus < slash {
+		return ""
+	}
+	return contentType[:slash+1] + contentType[plus+1:]
+}
+
 // SetEncoder sets a specific encoder to be used for the specified content types. If
 // a encoder is already registered, it will be overwritten.
 func (app *Application) SetEncoder(f EncoderFactory, makeDefault bool, contentTypes ...string) {
@@ -256,6 +503,13 @@ func (app *Application) SetEncoder(f EncoderFactory, makeDefault bool, contentTy
 
 	if makeDefault {
 		app.encoderPools["*/*"] = p
+		if len(contentTypes) > 0 {
+			if mediaType, _, err := mime.ParseMediaType(contentTypes[0]); err == nil {
+				app.defaultContentType = mediaType
+			} else {
+				app.defaultContentType = contentTypes[0]
+			}
+		}
 	}
 
 	// Rebuild a unique index of registered content encoders to be used in EncodeResponse
@@ -263,7 +517,7 @@ func (app *Application) SetEncoder(f EncoderFactory, makeDefault bool, contentTy
 	for contentType := range app.encoderPools {
 		app.encodableContentTypes = append(app.encodableContentTypes, contentType)
 	}
-
+	sort.Strings(app.encodableContentTypes)
 }
 
 // newEncodePool checks to see if the EncoderFactory returns reusable encoders
@@ -337,3 +591,59 @@ func (f *gobFactory) NewDecoder(r io.Reader) Decoder {
 func (f *gobFactory) NewEncoder(w io.Writer) Encoder {
 	return gob.NewEncoder(w)
 }
+
+// NewDecoder returns a new form decoder
+func (f *formFactory) NewDecoder(r io.Reader) Decoder {
+	return &formDecoder{r: r}
+}
+
+// Decode reads the URL encoded form and decodes it into v, see DecodeForm.
+func (d *formDecoder) Decode(v interface{}) error {
+	b, err := ioutil.ReadAll(d.r)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return err
+	}
+	return DecodeForm(values, v)
+}
+
+// Reset sets the reader the decoder reads the form from so that it can be reused.
+func (d *formDecoder) Reset(r io.Reader) {
+	d.r = r
+}
+
+// DecodeForm decodes the given form values into v. v must be a pointer to a url.Values, a
+// map[string][]string, a map[string]interface{} or an interface{}. Form fields that appear once
+// are decoded into strings in the two latter cases, fields that appear multiple times into slices
+// of strings.
+func DecodeForm(values url.Values, v interface{}) error {
+	switch actual := v.(type) {
+	case *url.Values:
+		*actual = values
+	case *map[string][]string:
+		*actual = values
+	case *map[string]interface{}:
+		*actual = formMap(values)
+	case *interface{}:
+		*actual = formMap(values)
+	default:
+		return fmt.Errorf("cannot decode form into value of type %T", v)
+	}
+	return nil
+}
+
+// formMap returns a map built from the given form values, see DecodeForm.
+func formMap(values url.Values) map[string]interface{} {
+	m := make(map[string]interface{}, len(values))
+	for n, vals := range values {
+		if len(vals) == 1 {
+			m[n] = vals[0]
+		} else {
+			m[n] = vals
+		}
+	}
+	return m
+}
@@ -0,0 +1,288 @@
+package goa_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+type greeting struct {
+	Hello string `json:"hello" xml:"hello"`
+}
+
+var _ = Describe("EncodeResponse", func() {
+	var accept string
+	var contentType string
+	var produces []string
+	var rw *TestResponseWriter
+	var err error
+
+	BeforeEach(func() {
+		accept = ""
+		contentType = ""
+		produces = nil
+		rw = &TestResponseWriter{ParentHeader: make(http.Header)}
+	})
+
+	JustBeforeEach(func() {
+		req, e := http.NewRequest("GET", "/goo", nil)
+		Ω(e).ShouldNot(HaveOccurred())
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if contentType != "" {
+			rw.ParentHeader.Set("Content-Type", contentType)
+		}
+		ctx := goa.NewContext(nil, goa.New("test"), req, rw, url.Values{})
+		h := func(ctx *goa.Context) error {
+			return ctx.Respond(200, &greeting{Hello: "world"})
+		}
+		if produces != nil {
+			err = goa.Produces(produces...)(h)(ctx)
+		} else {
+			err = h(ctx)
+		}
+	})
+
+	It("uses the default encoder when the request has no Accept header", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(rw.Status).Should(Equal(200))
+		Ω(rw.ParentHeader.Get("Content-Type")).Should(Equal("application/json"))
+		Ω(string(rw.Body)).Should(Equal(`{"hello":"world"}` + "\n"))
+	})
+
+	Context("with an Accept header that matches a registered encoder", func() {
+		BeforeEach(func() {
+			accept = "application/xml;q=0.9, application/gob;q=0.5"
+		})
+
+		It("uses the matching encoder", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rw.ParentHeader.Get("Content-Type")).Should(Equal("application/xml"))
+		})
+	})
+
+	Context("with an Accept header that matches no encoder", func() {
+		BeforeEach(func() {
+			accept = "text/html"
+		})
+
+		It("returns a not acceptable error and does not write the response", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(goa.NewProblem(err).Status).Should(Equal(406))
+			Ω(rw.Status).Should(Equal(0))
+			Ω(rw.Body).Should(BeEmpty())
+		})
+	})
+
+	Context("with a designed media type", func() {
+		BeforeEach(func() {
+			contentType = "application/vnd.goa.example+json; charset=utf-8"
+			produces = []string{"application/vnd.goa.example+json", "application/xml"}
+		})
+
+		It("uses the media type as content type", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rw.ParentHeader.Get("Content-Type")).Should(Equal("application/vnd.goa.example+json"))
+			Ω(string(rw.Body)).Should(Equal(`{"hello":"world"}` + "\n"))
+		})
+
+		Context("and a client that accepts the media type suffix base type", func() {
+			BeforeEach(func() {
+				accept = "application/json"
+			})
+
+			It("uses the media type as content type", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(rw.ParentHeader.Get("Content-Type")).Should(Equal("application/vnd.goa.example+json"))
+			})
+		})
+
+		Context("and a client that prefers another produced content type", func() {
+			BeforeEach(func() {
+				accept = "application/xml, application/json;q=0.5"
+			})
+
+			It("uses the preferred content type", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(rw.ParentHeader.Get("Content-Type")).Should(Equal("application/xml"))
+			})
+		})
+
+		Context("and a client that only accepts content types the action does not produce", func() {
+			BeforeEach(func() {
+				accept = "application/gob"
+			})
+
+			It("returns a not acceptable error before running the handler", func() {
+				Ω(err).Should(HaveOccurred())
+				Ω(goa.NewProblem(err).Status).Should(Equal(406))
+				Ω(rw.Status).Should(Equal(0))
+			})
+		})
+	})
+})
+
+var _ = Describe("Consumes", func() {
+	var contentType string
+	var handlerCalled bool
+	var err error
+
+	BeforeEach(func() {
+		contentType = "application/json"
+		handlerCalled = false
+	})
+
+	JustBeforeEach(func() {
+		body := []byte(`{"hello":"world"}`)
+		req, e := http.NewRequest("POST", "/goo", ioutil.NopCloser(bytes.NewReader(body)))
+		Ω(e).ShouldNot(HaveOccurred())
+		req.ContentLength = int64(len(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		ctx := goa.NewContext(nil, goa.New("test"), req, new(TestResponseWriter), url.Values{})
+		h := func(ctx *goa.Context) error {
+			handlerCalled = true
+			return nil
+		}
+		err = goa.Consumes("application/json", "text/*")(h)(ctx)
+	})
+
+	It("accepts supported content types", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(handlerCalled).Should(BeTrue())
+	})
+
+	Context("with a content type matching a wildcard", func() {
+		BeforeEach(func() {
+			contentType = "text/plain; charset=utf-8"
+		})
+
+		It("accepts the request", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(handlerCalled).Should(BeTrue())
+		})
+	})
+
+	Context("with no content type", func() {
+		BeforeEach(func() {
+			contentType = ""
+		})
+
+		It("assumes JSON", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(handlerCalled).Should(BeTrue())
+		})
+	})
+
+	Context("with an unsupported content type", func() {
+		BeforeEach(func() {
+			contentType = "application/xml"
+		})
+
+		It("returns an unsupported media type error", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(handlerCalled).Should(BeFalse())
+			Ω(goa.NewProblem(err).Status).Should(Equal(415))
+		})
+	})
+	Context("set on the route", func() {
+		var decoded bool
+		var rec *httptest.ResponseRecorder
+
+		JustBeforeEach(func() {
+			decoded = false
+			service := goa.New("test")
+			ctrl := service.NewController("GreetingController")
+			unmarshal := func(ctx *goa.Context) error {
+				decoded = true
+				var payload greeting
+				return ctx.Service().DecodeRequest(ctx, &payload)
+			}
+			route := &goa.Route{Method: "POST", Path: "/goo", Consumes: []string{"application/json"}}
+			service.ServeMux().Handle(route.Method, route.Path, ctrl.HandleRoute(route, "create", func(ctx *goa.Context) error {
+				handlerCalled = true
+				return ctx.RespondBytes(201, nil)
+			}, unmarshal))
+			req, e := http.NewRequest("POST", "/goo", strings.NewReader(`<greeting><hello>world</hello></greeting>`))
+			Ω(e).ShouldNot(HaveOccurred())
+			req.Header.Set("Content-Type", contentType)
+			rec = httptest.NewRecorder()
+			service.ServeMux().ServeHTTP(rec, req)
+		})
+
+		Context("with an unsupported content type", func() {
+			BeforeEach(func() {
+				contentType = "application/xml"
+			})
+
+			It("rejects the request with 415 before decoding the body", func() {
+				Ω(rec.Code).Should(Equal(415))
+				Ω(decoded).Should(BeFalse())
+				Ω(handlerCalled).Should(BeFalse())
+			})
+		})
+	})
+})
+
+var _ = Describe("DecodeRequest", func() {
+	var contentType string
+	var body string
+	var ctx *goa.Context
+	var form map[string]interface{}
+	var err error
+
+	BeforeEach(func() {
+		contentType = "application/x-www-form-urlencoded"
+		body = "name=goa&tags=a&tags=b"
+		form = nil
+	})
+
+	JustBeforeEach(func() {
+		req, e := http.NewRequest("POST", "/goo", strings.NewReader(body))
+		Ω(e).ShouldNot(HaveOccurred())
+		req.Header.Set("Content-Type", contentType)
+		ctx = goa.NewContext(nil, goa.New("test"), req, new(TestResponseWriter), url.Values{})
+		err = ctx.Service().DecodeRequest(ctx, &form)
+	})
+
+	It("decodes URL encoded forms", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(form).Should(Equal(map[string]interface{}{"name": "goa", "tags": []string{"a", "b"}}))
+	})
+
+	Context("with a multipart form", func() {
+		BeforeEach(func() {
+			var buf bytes.Buffer
+			w := multipart.NewWriter(&buf)
+			w.WriteField("name", "goa")
+			fw, e := w.CreateFormFile("picture", "goa.png")
+			Ω(e).ShouldNot(HaveOccurred())
+			fw.Write([]byte("png"))
+			Ω(w.Close()).ShouldNot(HaveOccurred())
+			contentType = w.FormDataContentType()
+			body = buf.String()
+		})
+
+		It("decodes the form values and makes the files available", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(form).Should(Equal(map[string]interface{}{"name": "goa"}))
+			fh, ferr := ctx.FormFile("picture")
+			Ω(ferr).ShouldNot(HaveOccurred())
+			Ω(fh).ShouldNot(BeNil())
+			Ω(fh.Filename).Should(Equal("goa.png"))
+			fh, ferr = ctx.FormFile("thumbnail")
+			Ω(ferr).ShouldNot(HaveOccurred())
+			Ω(fh).Should(BeNil())
+		})
+	})
+})
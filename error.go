@@ -13,16 +13,18 @@
 // The code generated by goagen calls the helper functions exposed in this file when it encounters
 // invalid data (wrong type, validation errors etc.) such as InvalidParamTypeError,
 // InvalidAttributeTypeError etc. These methods take and return an error which is a MultiError that
-// gets built over time. The final MultiError object then gets serialized into the response and sent
-// back to the client. The response status code is inferred from the type wrapping the error object:
-// a BadRequestError produces a 400 status code while any other error produce a 500. This behavior
-// can be overridden by setting a custom ErrorHandler in the application.
+// gets built over time. The default error handlers then render the final MultiError object as a
+// RFC 7807 problem (see NewProblem) and send it back to the client. The response status code is
+// inferred from the type wrapping the error object: a BadRequestError produces a 400 status code
+// while any other error produce a 500. This behavior can be overridden by setting a custom
+// ErrorHandler in the application.
 package goa
 
 import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 )
 
@@ -34,6 +36,9 @@ type (
 	TypedError struct {
 		ID   ErrorID
 		Mesg string
+		// Field is the name of the parameter, header or attribute the error applies to if
+		// any.
+		Field string
 	}
 
 	// MultiError records multiple errors.
@@ -43,6 +48,21 @@ type (
 	BadRequestError struct {
 		Actual error
 	}
+
+	// ResponseError is the type of the errors created by the constructors that goagen
+	// generates for the errors defined in the design. Controller actions return these errors
+	// to write the designed response, see ApplicationController.HandleError.
+	ResponseError struct {
+		// Name is the name of the error as defined in the design.
+		Name string
+		// Status is the response status code.
+		Status int
+		// ContentType is the response Content-Type header value if any.
+		ContentType string
+		// Body is the response body if any. A []byte body is written as is, other values
+		// are serialized using the service encoders.
+		Body interface{}
+	}
 )
 
 const (
@@ -97,6 +117,47 @@ const (
 	// ErrInvalidVersion is the error rendered by the default mux when a
 	// request specifies an invalid version.
 	ErrInvalidVersion
+
+	// ErrInvalidEncoding is the error produced when a request body cannot
+	// be decoded.
+	ErrInvalidEncoding
+
+	// ErrNotAcceptable is the error produced when none of the content types
+	// the action produces is acceptable to the client.
+	ErrNotAcceptable
+
+	// ErrUnsupportedMediaType is the error produced when the request body
+	// content type is not one of the content types the action consumes.
+	ErrUnsupportedMediaType
+
+	// ErrMissingFile is the error produced by the generated code when a
+	// required file is missing from a multipart form request body.
+	ErrMissingFile
+
+	// ErrInvalidFile is the error produced by the generated code when an
+	// uploaded file is larger than the maximum size or does not have one of
+	// the content types specified in the design.
+	ErrInvalidFile
+
+	// ErrMethodNotAllowed is the error rendered by the default mux when a
+	// request path matches a route registered for a different HTTP method.
+	ErrMethodNotAllowed
+
+	// ErrNotFound is the error rendered by the default mux when a request
+	// path does not match any route.
+	ErrNotFound
+
+	// ErrTooManyRequests is the error produced by the rate limiting
+	// middleware when a client exceeds the request rate limit.
+	ErrTooManyRequests
+
+	// ErrPreconditionFailed is the error produced when the conditions of a conditional
+	// request such as the If-Match header do not hold.
+	ErrPreconditionFailed
+
+	// ErrPayloadTooLarge is the error produced when the request body is
+	// larger than the maximum size of the action request bodies.
+	ErrPayloadTooLarge
 )
 
 // Title returns a human friendly error title
@@ -124,10 +185,56 @@ func (k ErrorID) Title() string {
 		return "invalid value length"
 	case ErrInvalidVersion:
 		return "invalid version"
+	case ErrInvalidEncoding:
+		return "invalid request body encoding"
+	case ErrNotAcceptable:
+		return "not acceptable"
+	case ErrUnsupportedMediaType:
+		return "unsupported media type"
+	case ErrMissingFile:
+		return "missing required file"
+	case ErrInvalidFile:
+		return "invalid file"
+	case ErrMethodNotAllowed:
+		return "method not allowed"
+	case ErrNotFound:
+		return "not found"
+	case ErrTooManyRequests:
+		return "too many requests"
+	case ErrPreconditionFailed:
+		return "precondition failed"
+	case ErrPayloadTooLarge:
+		return "payload too large"
 	}
 	return "unknown error"
 }
 
+// Status returns the HTTP status code of responses that describe errors with the given ID.
+func (k ErrorID) Status() int {
+	switch k {
+	case ErrNotAcceptable:
+		return http.StatusNotAcceptable
+	case ErrUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case ErrMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case ErrNotFound:
+		return http.StatusNotFound
+	case ErrTooManyRequests:
+		return http.StatusTooManyRequests
+	case ErrPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case ErrPayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	}
+	return 400
+}
+
+// TypeURI returns the problem type URI of errors with the given ID, see ProblemTypeBase.
+func (k ErrorID) TypeURI() string {
+	return ProblemTypeBase + strings.Replace(k.Title(), " ", "-", -1)
+}
+
 // MarshalJSON implements the json marshaler interface.
 func (t *TypedError) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
@@ -191,6 +298,28 @@ func (b *BadRequestError) Error() string {
 	return b.Actual.Error()
 }
 
+// Error returns the error name and status code.
+func (r *ResponseError) Error() string {
+	return fmt.Sprintf("%s (status %d)", r.Name, r.Status)
+}
+
+// Respond writes the error response.
+func (r *ResponseError) Respond(ctx *Context) error {
+	if r.ContentType != "" {
+		if header := ctx.Header(); header != nil {
+			header.Set("Content-Type", r.ContentType)
+		}
+	}
+	switch body := r.Body.(type) {
+	case nil:
+		return ctx.RespondBytes(r.Status, nil)
+	case []byte:
+		return ctx.RespondBytes(r.Status, body)
+	default:
+		return ctx.Respond(r.Status, body)
+	}
+}
+
 // InvalidParamTypeError appends a typed error of id ErrInvalidParamType to
 // err and returns it.
 func InvalidParamTypeError(name string, val interface{}, expected string, err error) error {
@@ -198,6 +327,7 @@ func InvalidParamTypeError(name string, val interface{}, expected string, err er
 		ID: ErrInvalidParamType,
 		Mesg: fmt.Sprintf("invalid value %#v for parameter %#v, must be a %s",
 			val, name, expected),
+		Field: name,
 	}
 	return ReportError(err, &terr)
 }
@@ -206,8 +336,31 @@ func InvalidParamTypeError(name string, val interface{}, expected string, err er
 // returns it.
 func MissingParamError(name string, err error) error {
 	terr := TypedError{
-		ID:   ErrMissingParam,
-		Mesg: fmt.Sprintf("missing required parameter %#v", name),
+		ID:    ErrMissingParam,
+		Mesg:  fmt.Sprintf("missing required parameter %#v", name),
+		Field: name,
+	}
+	return ReportError(err, &terr)
+}
+
+// MissingFileError appends a typed error of id ErrMissingFile to err and
+// returns it.
+func MissingFileError(name string, err error) error {
+	terr := TypedError{
+		ID:    ErrMissingFile,
+		Mesg:  fmt.Sprintf("missing required file %#v", name),
+		Field: name,
+	}
+	return ReportError(err, &terr)
+}
+
+// InvalidFileError appends a typed error of id ErrInvalidFile to err and
+// returns it.
+func InvalidFileError(name, reason string, err error) error {
+	terr := TypedError{
+		ID:    ErrInvalidFile,
+		Mesg:  fmt.Sprintf("invalid file %#v: %s", name, reason),
+		Field: name,
 	}
 	return ReportError(err, &terr)
 }
@@ -219,6 +372,7 @@ func InvalidAttributeTypeError(ctx string, val interface{}, expected string, err
 		ID: ErrInvalidAttributeType,
 		Mesg: fmt.Sprintf("type of %s must be %s but got value %#v", ctx,
 			expected, val),
+		Field: ctx,
 	}
 	return ReportError(err, &terr)
 }
@@ -227,8 +381,9 @@ func InvalidAttributeTypeError(ctx string, val interface{}, expected string, err
 // err and returns it.
 func MissingAttributeError(ctx, name string, err error) error {
 	terr := TypedError{
-		ID:   ErrMissingAttribute,
-		Mesg: fmt.Sprintf("attribute %#v of %s is missing and required", name, ctx),
+		ID:    ErrMissingAttribute,
+		Mesg:  fmt.Sprintf("attribute %#v of %s is missing and required", name, ctx),
+		Field: ctx + "." + name,
 	}
 	return ReportError(err, &terr)
 }
@@ -237,8 +392,9 @@ func MissingAttributeError(ctx, name string, err error) error {
 // returns it.
 func MissingHeaderError(name string, err error) error {
 	terr := TypedError{
-		ID:   ErrMissingHeader,
-		Mesg: fmt.Sprintf("missing required HTTP header %#v", name),
+		ID:    ErrMissingHeader,
+		Mesg:  fmt.Sprintf("missing required HTTP header %#v", name),
+		Field: name,
 	}
 	return ReportError(err, &terr)
 }
@@ -254,6 +410,7 @@ func InvalidEnumValueError(ctx string, val interface{}, allowed []interface{}, e
 		ID: ErrInvalidEnumValue,
 		Mesg: fmt.Sprintf("value of %s must be one of %s but got value %#v", ctx,
 			strings.Join(elems, ", "), val),
+		Field: ctx,
 	}
 	return ReportError(err, &terr)
 }
@@ -265,6 +422,7 @@ func InvalidFormatError(ctx, target string, format Format, formatError, err erro
 		ID: ErrInvalidFormat,
 		Mesg: fmt.Sprintf("%s must be formatted as a %s but got value %#v, %s",
 			ctx, format, target, formatError.Error()),
+		Field: ctx,
 	}
 	return ReportError(err, &terr)
 }
@@ -276,6 +434,7 @@ func InvalidPatternError(ctx, target string, pattern string, err error) error {
 		ID: ErrInvalidPattern,
 		Mesg: fmt.Sprintf("%s must be match the regexp %#v but got value %#v",
 			ctx, pattern, target),
+		Field: ctx,
 	}
 	return ReportError(err, &terr)
 }
@@ -291,6 +450,7 @@ func InvalidRangeError(ctx string, target interface{}, value int, min bool, err
 		ID: ErrInvalidRange,
 		Mesg: fmt.Sprintf("%s must be %s than %d but got value %#v",
 			ctx, comp, value, target),
+		Field: ctx,
 	}
 	return ReportError(err, &terr)
 }
@@ -306,6 +466,17 @@ func InvalidLengthError(ctx string, target interface{}, ln, value int, min bool,
 		ID: ErrInvalidLength,
 		Mesg: fmt.Sprintf("length of %s must be %s than %d but got value %#v (len=%d)",
 			ctx, comp, value, target, ln),
+		Field: ctx,
+	}
+	return ReportError(err, &terr)
+}
+
+// InvalidEncodingError appends a typed error of id ErrInvalidEncoding to err and
+// returns it.
+func InvalidEncodingError(decodeErr error, err error) error {
+	terr := TypedError{
+		ID:   ErrInvalidEncoding,
+		Mesg: fmt.Sprintf("failed to decode request body: %s", decodeErr),
 	}
 	return ReportError(err, &terr)
 }
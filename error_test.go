@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -11,7 +12,7 @@ import (
 )
 
 // allErrorKinds list all the existing goa.ErrorID values.
-var allErrorKinds = [10]goa.ErrorID{
+var allErrorKinds = [11]goa.ErrorID{
 	goa.ErrInvalidParamType,
 	goa.ErrMissingParam,
 	goa.ErrInvalidAttributeType,
@@ -22,6 +23,7 @@ var allErrorKinds = [10]goa.ErrorID{
 	goa.ErrInvalidPattern,
 	goa.ErrInvalidRange,
 	goa.ErrInvalidLength,
+	goa.ErrInvalidEncoding,
 }
 
 var _ = Describe("ErrorKind", func() {
@@ -806,3 +808,37 @@ var _ = Describe("ReportError", func() {
 		})
 	})
 })
+
+var _ = Describe("ResponseError", func() {
+	var rerr *goa.ResponseError
+	var rw *TestResponseWriter
+	var errorHandlerCalled bool
+
+	BeforeEach(func() {
+		rerr = &goa.ResponseError{
+			Name:        "NotFound",
+			Status:      404,
+			ContentType: "text/plain",
+			Body:        []byte("not found"),
+		}
+		errorHandlerCalled = false
+	})
+
+	JustBeforeEach(func() {
+		req, err := http.NewRequest("GET", "/foo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw = &TestResponseWriter{ParentHeader: make(http.Header)}
+		service := goa.New("test")
+		service.SetErrorHandler(TErrorHandler(&errorHandlerCalled))
+		ctx := goa.NewContext(nil, service, req, rw, nil)
+		ctrl := service.NewController("test").(*goa.ApplicationController)
+		ctrl.HandleError(ctx, rerr)
+	})
+
+	It("is written by the controller instead of calling the error handler", func() {
+		Ω(errorHandlerCalled).Should(BeFalse())
+		Ω(rw.Status).Should(Equal(404))
+		Ω(rw.ParentHeader.Get("Content-Type")).Should(Equal("text/plain"))
+		Ω(string(rw.Body)).Should(Equal("not found"))
+	})
+})
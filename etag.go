@@ -0,0 +1,263 @@
+package goa
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	// etagWriter is the response writer used by the ETag middleware. It buffers the response
+	// body so that the middleware may compute its ETag before writing it.
+	etagWriter struct {
+		rw        http.ResponseWriter
+		code      int
+		buf       bytes.Buffer
+		streaming bool
+	}
+
+	// Preconditions describes the conditions of a conditional request. Code generated by
+	// goagen uses it to make conditional requests to the actions that support ETags.
+	Preconditions struct {
+		// IfMatch is the ETag the resource must have for the request to succeed, "*" if the
+		// resource must exist.
+		IfMatch string
+		// IfNoneMatch is the ETag of the representation known to the client, the service
+		// responds with 304 Not Modified if the resource still has this ETag.
+		IfNoneMatch string
+		// IfModifiedSince is the time the representation known to the client was last
+		// modified.
+		IfModifiedSince time.Time
+		// IfUnmodifiedSince is the time the resource must not have been modified since for
+		// the request to succeed.
+		IfUnmodifiedSince time.Time
+	}
+)
+
+// ETag returns a middleware that adds an ETag to the successful responses of GET and HEAD requests
+// and implements the corresponding conditional requests. The ETag is the one set by the handler
+// with Context.SetETag if any, the middleware computes a strong ETag from the encoded response
+// body otherwise. The middleware responds with 304 Not Modified without a body if the request
+// "If-None-Match" header matches the ETag or, if the request has no "If-None-Match" header, if the
+// request "If-Modified-Since" header is not older than the "Last-Modified" response header set by
+// the handler with Context.SetLastModified.
+// Handlers of the other requests use Context.CheckPreconditions to honor the "If-Match" and
+// "If-Unmodified-Since" request headers.
+// goagen generates code that uses ETag for actions whose design uses the ETag DSL.
+func ETag() Middleware {
+	return func(h Handler) Handler {
+		return func(ctx *Context) error {
+			req := ctx.Request()
+			rw, ok := ctx.Value(respKey).(http.ResponseWriter)
+			if req == nil || !ok || (req.Method != "GET" && req.Method != "HEAD") {
+				return h(ctx)
+			}
+			ew := &etagWriter{rw: rw}
+			ctx.SetResponseWriter(ew)
+			err := h(ctx)
+			ctx.SetResponseWriter(rw)
+			if ew.code == 0 || ew.streaming {
+				return err
+			}
+			header := rw.Header()
+			if ew.code == http.StatusOK {
+				etag := header.Get("ETag")
+				if etag == "" {
+					sum := sha256.Sum256(ew.buf.Bytes())
+					etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+					header.Set("ETag", etag)
+				}
+				if notModified(req, etag, header.Get("Last-Modified")) {
+					header.Del("Content-Type")
+					header.Del("Content-Length")
+					ctx.SetValue(respStatusKey, http.StatusNotModified)
+					ctx.SetValue(respLenKey, 0)
+					rw.WriteHeader(http.StatusNotModified)
+					return err
+				}
+			}
+			rw.WriteHeader(ew.code)
+			if _, werr := rw.Write(ew.buf.Bytes()); err == nil {
+				err = werr
+			}
+			return err
+		}
+	}
+}
+
+// SetETag sets the "ETag" response header. The ETag is quoted if it is not already, use the "W/"
+// prefix for weak ETags, e.g. `W/"v42"`.
+func (ctx *Context) SetETag(etag string) {
+	if !strings.HasSuffix(etag, `"`) {
+		etag = `"` + etag + `"`
+	}
+	ctx.Header().Set("ETag", etag)
+}
+
+// SetLastModified sets the "Last-Modified" response header.
+func (ctx *Context) SetLastModified(t time.Time) {
+	ctx.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// CheckPreconditions evaluates the "If-Match" and "If-Unmodified-Since" request headers given the
+// current ETag and last modification time of the resource targeted by the request. etag is empty
+// if the resource does not exist, lastModified is the zero time if it is not known. Handlers of
+// write requests call CheckPreconditions before applying the changes so that clients do not
+// overwrite changes they have not seen. It returns an error with ID ErrPreconditionFailed if the
+// conditions do not hold.
+func (ctx *Context) CheckPreconditions(etag string, lastModified time.Time) error {
+	req := ctx.Request()
+	if ifMatch := req.Header.Get("If-Match"); ifMatch != "" {
+		if !etagMatch(ifMatch, etag, false) {
+			return &TypedError{
+				ID:   ErrPreconditionFailed,
+				Mesg: fmt.Sprintf("resource does not match If-Match %s", ifMatch),
+			}
+		}
+		return nil
+	}
+	if ius := req.Header.Get("If-Unmodified-Since"); ius != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ius)
+		if err == nil && lastModified.Truncate(time.Second).After(t) {
+			return &TypedError{
+				ID:   ErrPreconditionFailed,
+				Mesg: fmt.Sprintf("resource was modified after %s", ius),
+			}
+		}
+	}
+	return nil
+}
+
+// SetHeaders sets the request headers corresponding to the preconditions.
+func (p *Preconditions) SetHeaders(header http.Header) {
+	if p.IfMatch != "" {
+		header.Set("If-Match", p.IfMatch)
+	}
+	if p.IfNoneMatch != "" {
+		header.Set("If-None-Match", p.IfNoneMatch)
+	}
+	if !p.IfModifiedSince.IsZero() {
+		header.Set("If-Modified-Since", p.IfModifiedSince.UTC().Format(http.TimeFormat))
+	}
+	if !p.IfUnmodifiedSince.IsZero() {
+		header.Set("If-Unmodified-Since", p.IfUnmodifiedSince.UTC().Format(http.TimeFormat))
+	}
+}
+
+// Header returns the response header.
+func (ew *etagWriter) Header() http.Header {
+	return ew.rw.Header()
+}
+
+// WriteHeader records the response status code, the header is written once the handler returns.
+func (ew *etagWriter) WriteHeader(code int) {
+	if ew.streaming {
+		ew.rw.WriteHeader(code)
+		return
+	}
+	if ew.code == 0 {
+		ew.code = code
+	}
+}
+
+// Write buffers the response body.
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if ew.code == 0 {
+		ew.code = http.StatusOK
+	}
+	if ew.streaming {
+		return ew.rw.Write(b)
+	}
+	return ew.buf.Write(b)
+}
+
+// Flush writes the buffered data to the client, streamed responses do not get an ETag.
+func (ew *etagWriter) Flush() {
+	if !ew.streaming {
+		ew.streaming = true
+		if ew.code == 0 {
+			ew.code = http.StatusOK
+		}
+		ew.rw.WriteHeader(ew.code)
+		ew.rw.Write(ew.buf.Bytes())
+		ew.buf.Reset()
+	}
+	if f, ok := ew.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify returns a channel that receives a value when the client closes the connection, the
+// channel is nil if the underlying response writer cannot detect it.
+func (ew *etagWriter) CloseNotify() <-chan bool {
+	if cn, ok := ew.rw.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+// Hijack lets the caller take over the connection, e.g. to upgrade it to a WebSocket connection.
+// The response is then neither buffered nor given an ETag.
+func (ew *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	ew.streaming = true
+	return hijack(ew.rw)
+}
+
+// wrapped returns the response writer wrapped by the ETag middleware.
+func (ew *etagWriter) wrapped() http.ResponseWriter {
+	return ew.rw
+}
+
+// notModified returns true if the conditional GET request preconditions do not hold given the
+// response ETag and "Last-Modified" header value.
+func notModified(req *http.Request, etag, lastModified string) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, etag, true)
+	}
+	ims := req.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	lm, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !lm.After(t)
+}
+
+// etagMatch returns true if the given list of ETags as found in the "If-Match" and "If-None-Match"
+// headers matches etag. The comparison ignores the weak indicators if weak is true, weak ETags
+// never match otherwise.
+func etagMatch(list, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	if !weak && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+			if candidate == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+var _ = Describe("ETag", func() {
+	var method string
+	var header http.Header
+	var handler goa.Handler
+	var rec *httptest.ResponseRecorder
+	var ctx *goa.Context
+	var err error
+
+	lastModified := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		method = "GET"
+		header = make(http.Header)
+		handler = func(ctx *goa.Context) error {
+			return ctx.RespondBytes(200, []byte(`{"name":"bottle"}`))
+		}
+	})
+
+	JustBeforeEach(func() {
+		req, e := http.NewRequest(method, "/bottles/1", nil)
+		Ω(e).ShouldNot(HaveOccurred())
+		req.Header = header
+		rec = httptest.NewRecorder()
+		ctx = goa.NewContext(nil, goa.New("test"), req, rec, url.Values{})
+		err = goa.ETag()(handler)(ctx)
+	})
+
+	It("computes a strong ETag from the response body", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(rec.Code).Should(Equal(200))
+		Ω(rec.Header().Get("ETag")).Should(MatchRegexp(`^"[0-9a-f]{32}"$`))
+		Ω(rec.Body.String()).Should(Equal(`{"name":"bottle"}`))
+	})
+
+	Context("with a matching If-None-Match header", func() {
+		BeforeEach(func() {
+			req, _ := http.NewRequest("GET", "/bottles/1", nil)
+			rec := httptest.NewRecorder()
+			goa.ETag()(handler)(goa.NewContext(nil, goa.New("test"), req, rec, url.Values{}))
+			header.Set("If-None-Match", `"other", `+rec.Header().Get("ETag"))
+		})
+
+		It("responds with 304 Not Modified", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rec.Code).Should(Equal(304))
+			Ω(rec.Body.Len()).Should(Equal(0))
+			Ω(rec.Header().Get("ETag")).ShouldNot(BeEmpty())
+			Ω(ctx.ResponseStatus()).Should(Equal(304))
+			Ω(ctx.ResponseLength()).Should(Equal(0))
+		})
+	})
+
+	Context("with a handler that sets the ETag and last modification time", func() {
+		BeforeEach(func() {
+			handler = func(ctx *goa.Context) error {
+				ctx.SetETag("v42")
+				ctx.SetLastModified(lastModified)
+				return ctx.RespondBytes(200, []byte(`{"name":"bottle"}`))
+			}
+		})
+
+		It("uses the handler ETag", func() {
+			Ω(rec.Code).Should(Equal(200))
+			Ω(rec.Header().Get("ETag")).Should(Equal(`"v42"`))
+			Ω(rec.Header().Get("Last-Modified")).Should(Equal("Wed, 21 Oct 2015 07:28:00 GMT"))
+		})
+
+		Context("and a request with a recent If-Modified-Since header", func() {
+			BeforeEach(func() {
+				header.Set("If-Modified-Since", lastModified.Format(http.TimeFormat))
+			})
+
+			It("responds with 304 Not Modified", func() {
+				Ω(rec.Code).Should(Equal(304))
+			})
+		})
+
+		Context("and a request with an older If-Modified-Since header", func() {
+			BeforeEach(func() {
+				header.Set("If-Modified-Since", lastModified.Add(-time.Hour).Format(http.TimeFormat))
+			})
+
+			It("responds with the resource", func() {
+				Ω(rec.Code).Should(Equal(200))
+				Ω(rec.Body.String()).Should(Equal(`{"name":"bottle"}`))
+			})
+		})
+
+		Context("and a request with a mismatching If-None-Match header", func() {
+			BeforeEach(func() {
+				header.Set("If-None-Match", `"v41"`)
+				header.Set("If-Modified-Since", lastModified.Format(http.TimeFormat))
+			})
+
+			It("ignores If-Modified-Since and responds with the resource", func() {
+				Ω(rec.Code).Should(Equal(200))
+			})
+		})
+	})
+
+	Context("with an error response", func() {
+		BeforeEach(func() {
+			handler = func(ctx *goa.Context) error {
+				return ctx.RespondBytes(404, []byte("not found"))
+			}
+		})
+
+		It("does not add an ETag", func() {
+			Ω(rec.Code).Should(Equal(404))
+			Ω(rec.Header().Get("ETag")).Should(BeEmpty())
+			Ω(rec.Body.String()).Should(Equal("not found"))
+		})
+	})
+
+	Context("with a write request", func() {
+		BeforeEach(func() {
+			method = "PUT"
+			handler = func(ctx *goa.Context) error {
+				if err := ctx.CheckPreconditions(`"v42"`, lastModified); err != nil {
+					return err
+				}
+				return ctx.RespondBytes(200, []byte(`{"name":"bottle"}`))
+			}
+		})
+
+		It("applies the request", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rec.Code).Should(Equal(200))
+		})
+
+		Context("with a matching If-Match header", func() {
+			BeforeEach(func() {
+				header.Set("If-Match", `"v41", "v42"`)
+			})
+
+			It("applies the request", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(rec.Code).Should(Equal(200))
+			})
+		})
+
+		Context("with a mismatching If-Match header", func() {
+			BeforeEach(func() {
+				header.Set("If-Match", `"v41"`)
+			})
+
+			It("fails with a precondition failed error", func() {
+				Ω(err).Should(HaveOccurred())
+				terr, ok := err.(*goa.TypedError)
+				Ω(ok).Should(BeTrue())
+				Ω(terr.ID).Should(BeEquivalentTo(goa.ErrPreconditionFailed))
+				Ω(terr.ID.Status()).Should(Equal(412))
+			})
+		})
+
+		Context("with an older If-Unmodified-Since header", func() {
+			BeforeEach(func() {
+				header.Set("If-Unmodified-Since", lastModified.Add(-time.Hour).Format(http.TimeFormat))
+			})
+
+			It("fails with a precondition failed error", func() {
+				Ω(err).Should(HaveOccurred())
+				Ω(err.(*goa.TypedError).ID).Should(BeEquivalentTo(goa.ErrPreconditionFailed))
+			})
+		})
+	})
+})
+
+var _ = Describe("Preconditions", func() {
+	It("sets the conditional request headers", func() {
+		header := make(http.Header)
+		p := &goa.Preconditions{
+			IfMatch:           `"v42"`,
+			IfNoneMatch:       `"v41"`,
+			IfUnmodifiedSince: time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC),
+		}
+		p.SetHeaders(header)
+		Ω(header.Get("If-Match")).Should(Equal(`"v42"`))
+		Ω(header.Get("If-None-Match")).Should(Equal(`"v41"`))
+		Ω(header.Get("If-Unmodified-Since")).Should(Equal("Wed, 21 Oct 2015 07:28:00 GMT"))
+		Ω(header.Get("If-Modified-Since")).Should(BeEmpty())
+	})
+})
@@ -0,0 +1,130 @@
+package goa
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// EventStreamContentType is the content type of responses that stream server-sent events.
+const EventStreamContentType = "text/event-stream"
+
+type (
+	// EventStream sends server-sent events to the client, see Context.RespondEvents.
+	EventStream struct {
+		w    io.Writer
+		done <-chan bool
+	}
+
+	// Event is a server-sent event read by an EventReader.
+	Event struct {
+		// ID is the event identifier, empty if the server did not set it.
+		ID string
+		// Name is the event type, empty for "message" events.
+		Name string
+		// Data contains the event data.
+		Data []byte
+	}
+
+	// EventReader reads the server-sent events streamed in a response body.
+	EventReader struct {
+		body io.ReadCloser
+		r    *bufio.Reader
+	}
+)
+
+// RespondEvents writes the given HTTP status code and calls fn to send server-sent events to the
+// client. Each event is flushed to the client as soon as it is sent. Any error returned by fn is
+// returned as is, note that the response status code cannot be changed once fn sends an event.
+// This method should only be called once per request.
+func (ctx *Context) RespondEvents(code int, fn func(s *EventStream) error) error {
+	rw, ok := ctx.Value(respKey).(http.ResponseWriter)
+	if !ok {
+		return fmt.Errorf("response writer not initialized")
+	}
+	rw.Header().Set("Content-Type", EventStreamContentType)
+	rw.Header().Set("Cache-Control", "no-cache")
+	return ctx.RespondStream(code, func(w io.Writer) error {
+		s := &EventStream{w: w}
+		if cn, ok := rw.(http.CloseNotifier); ok {
+			s.done = cn.CloseNotify()
+		}
+		return fn(s)
+	})
+}
+
+// Send sends an event whose data is the JSON representation of v. Clients handle events with an
+// empty name as "message" events.
+func (s *EventStream) Send(name string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to serialize event: %s", err)
+	}
+	var buf bytes.Buffer
+	if name != "" {
+		fmt.Fprintf(&buf, "event: %s\n", name)
+	}
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	_, err = s.w.Write(buf.Bytes())
+	return err
+}
+
+// Done returns a channel that receives a value when the client closes the connection. The channel
+// is nil if the underlying response writer cannot detect it.
+func (s *EventStream) Done() <-chan bool {
+	return s.done
+}
+
+// NewEventReader returns a reader for the server-sent events streamed in the given response body.
+func NewEventReader(body io.ReadCloser) *EventReader {
+	return &EventReader{body: body, r: bufio.NewReader(body)}
+}
+
+// Next returns the next event sent by the server. It returns io.EOF once the server closes the
+// stream, incomplete events are discarded.
+func (r *EventReader) Next() (*Event, error) {
+	var ev Event
+	var data [][]byte
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			if data == nil {
+				continue
+			}
+			ev.Data = bytes.Join(data, []byte("\n"))
+			return &ev, nil
+		}
+		if line[0] == ':' {
+			continue // comment
+		}
+		field, value := line, []byte{}
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+		}
+		switch string(field) {
+		case "event":
+			ev.Name = string(value)
+		case "id":
+			ev.ID = string(value)
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
+
+// Close closes the underlying response body.
+func (r *EventReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,78 @@
+package goa_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+var _ = Describe("RespondEvents", func() {
+	var rec *httptest.ResponseRecorder
+	var ctx *goa.Context
+	var respErr error
+
+	BeforeEach(func() {
+		req, err := http.NewRequest("GET", "/events", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rec = httptest.NewRecorder()
+		ctx = goa.NewContext(nil, goa.New("test"), req, rec, url.Values{})
+	})
+
+	JustBeforeEach(func() {
+		respErr = ctx.RespondEvents(200, func(s *goa.EventStream) error {
+			if err := s.Send("", map[string]int{"value": 1}); err != nil {
+				return err
+			}
+			return s.Send("update", map[string]int{"value": 2})
+		})
+	})
+
+	It("streams the events", func() {
+		Ω(respErr).ShouldNot(HaveOccurred())
+		Ω(rec.Code).Should(Equal(200))
+		Ω(rec.Flushed).Should(BeTrue())
+		Ω(rec.Header().Get("Content-Type")).Should(Equal(goa.EventStreamContentType))
+		Ω(rec.Body.String()).Should(Equal("data: {\"value\":1}\n\nevent: update\ndata: {\"value\":2}\n\n"))
+		Ω(ctx.ResponseLength()).Should(Equal(rec.Body.Len()))
+	})
+})
+
+var _ = Describe("EventReader", func() {
+	var stream string
+	var events []*goa.Event
+	var readErr error
+
+	JustBeforeEach(func() {
+		events = nil
+		r := goa.NewEventReader(ioutil.NopCloser(strings.NewReader(stream)))
+		for {
+			ev, err := r.Next()
+			if err != nil {
+				readErr = err
+				break
+			}
+			events = append(events, ev)
+		}
+	})
+
+	BeforeEach(func() {
+		stream = ": comment\n\nid: 1\ndata: {\"value\":1}\n\nevent: update\r\ndata: line1\r\ndata:line2\r\n\r\ndata: incomplete"
+	})
+
+	It("reads the events", func() {
+		Ω(readErr).Should(Equal(io.EOF))
+		Ω(events).Should(HaveLen(2))
+		Ω(events[0].ID).Should(Equal("1"))
+		Ω(events[0].Name).Should(BeEmpty())
+		Ω(string(events[0].Data)).Should(Equal(`{"value":1}`))
+		Ω(events[1].Name).Should(Equal("update"))
+		Ω(string(events[1].Data)).Should(Equal("line1\nline2"))
+	})
+})
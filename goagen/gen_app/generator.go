@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/raphael/goa/design"
 	"github.com/raphael/goa/goagen/codegen"
@@ -106,6 +108,14 @@ func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error)
 		if err := g.generateUserTypes(verdir, v); err != nil {
 			return err
 		}
+		if err := g.generateErrors(verdir, api, v); err != nil {
+			return err
+		}
+		if v.IsDefault() {
+			if err := g.generateSecurity(verdir, api); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 	if err != nil {
@@ -148,6 +158,195 @@ func packageName(version *design.APIVersionDefinition) (pack string) {
 	return
 }
 
+// durationCode returns the Go code that initializes a time.Duration with the given value, e.g.
+// "5 * time.Second".
+func durationCode(d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d * %s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("%d * time.Nanosecond", d)
+}
+
+// deprecationCode returns the Go code that initializes a *goa.Deprecation with the given
+// deprecation, e.g. "&goa.Deprecation{Sunset: time.Date(2016, 6, 30, 0, 0, 0, 0, time.UTC)}".
+func deprecationCode(d *design.DeprecationDefinition) string {
+	var fields []string
+	if !d.Sunset.IsZero() {
+		y, m, day := d.Sunset.Date()
+		fields = append(fields, fmt.Sprintf("Sunset: time.Date(%d, %d, %d, 0, 0, 0, 0, time.UTC)", y, m, day))
+	}
+	if d.URL != "" {
+		fields = append(fields, fmt.Sprintf("Link: %q", d.URL))
+	}
+	return fmt.Sprintf("&goa.Deprecation{%s}", strings.Join(fields, ", "))
+}
+
+// producedContentTypes returns the content types of the action response bodies: the identifiers
+// of the response media types, or text/event-stream for responses that stream server-sent events,
+// followed by the content types listed with Produces. WebSocket responses have no body.
+func producedContentTypes(a *design.ActionDefinition) []string {
+	var names []string
+	responses := make(map[string]*design.ResponseDefinition)
+	for _, resps := range []map[string]*design.ResponseDefinition{a.Parent.Responses, a.Responses} {
+		for n, r := range resps {
+			if _, ok := responses[n]; !ok {
+				names = append(names, n)
+			}
+			responses[n] = r
+		}
+	}
+	sort.Strings(names)
+	var res []string
+	seen := make(map[string]bool)
+	add := func(ct string) {
+		if ct != "" && !seen[ct] {
+			seen[ct] = true
+			res = append(res, ct)
+		}
+	}
+	for _, n := range names {
+		if responses[n].Events {
+			add(design.EventStreamContentType)
+		} else if !responses[n].WebSocket {
+			add(responses[n].MediaType)
+		}
+	}
+	for _, ct := range a.EffectiveProduces() {
+		add(ct)
+	}
+	return res
+}
+
+// corsData returns the CORS policies that apply to the resource and the paths of the given API
+// version that handle the CORS preflight requests. The preflight requests sent to a path shared
+// with other resources are handled with the policies of all these resources merged by origin so
+// that the policies of the resource mounted first do not hide the policies of the others.
+func corsData(r *design.ResourceDefinition, version *design.APIVersionDefinition) ([]*design.CORSDefinition, []*PreflightTemplateData) {
+	if !r.SupportsVersion(version.Version) {
+		return nil, nil
+	}
+	origins := resourceOrigins(r)
+	if len(origins) == 0 {
+		return nil, nil
+	}
+	var preflights []*PreflightTemplateData
+	for _, path := range preflightPaths(r, version) {
+		merged := make(map[string]*design.CORSDefinition)
+		for _, cors := range origins {
+			merged[cors.Origin] = cors
+		}
+		shared := false
+		version.IterateResources(func(other *design.ResourceDefinition) error {
+			if other == r {
+				return nil
+			}
+			otherOrigins := resourceOrigins(other)
+			if len(otherOrigins) == 0 {
+				return nil
+			}
+			for _, p := range preflightPaths(other, version) {
+				if p == path {
+					shared = true
+					for _, cors := range otherOrigins {
+						merged[cors.Origin] = mergeCORS(merged[cors.Origin], cors)
+					}
+					break
+				}
+			}
+			return nil
+		})
+		preflight := &PreflightTemplateData{Path: path}
+		if shared {
+			names := make([]string, 0, len(merged))
+			for o := range merged {
+				names = append(names, o)
+			}
+			sort.Strings(names)
+			for _, o := range names {
+				preflight.Origins = append(preflight.Origins, merged[o])
+			}
+		}
+		preflights = append(preflights, preflight)
+	}
+	return origins, preflights
+}
+
+// resourceOrigins returns the CORS policies that apply to the resource sorted by origin.
+func resourceOrigins(r *design.ResourceDefinition) []*design.CORSDefinition {
+	var origins []*design.CORSDefinition
+	r.IterateOrigins(func(cors *design.CORSDefinition) error {
+		origins = append(origins, cors)
+		return nil
+	})
+	return origins
+}
+
+// preflightPaths returns the paths of the resource action routes in the given API version.
+func preflightPaths(r *design.ResourceDefinition, version *design.APIVersionDefinition) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	r.IterateActions(func(a *design.ActionDefinition) error {
+		for _, route := range a.Routes {
+			if path := route.FullPath(version); !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+		return nil
+	})
+	return paths
+}
+
+// mergeCORS returns the policy that allows the requests allowed by either of the given policies
+// defined for the same origin, cors if other is nil.
+func mergeCORS(other, cors *design.CORSDefinition) *design.CORSDefinition {
+	if other == nil {
+		return cors
+	}
+	merged := &design.CORSDefinition{
+		Parent:      other.Parent,
+		Origin:      cors.Origin,
+		Headers:     mergeNames(other.Headers, cors.Headers),
+		Exposed:     mergeNames(other.Exposed, cors.Exposed),
+		MaxAge:      other.MaxAge,
+		Credentials: other.Credentials || cors.Credentials,
+	}
+	if len(other.Methods) > 0 && len(cors.Methods) > 0 {
+		// Policies without methods allow all methods
+		merged.Methods = mergeNames(other.Methods, cors.Methods)
+	}
+	if cors.MaxAge < merged.MaxAge {
+		merged.MaxAge = cors.MaxAge
+	}
+	return merged
+}
+
+// mergeNames returns the sorted union of the given names.
+func mergeNames(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var names []string
+	for _, n := range append(append([]string{}, a...), b...) {
+		if !seen[n] {
+			seen[n] = true
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // generateContexts iterates through the version resources and actions and generates the action
 // contexts.
 func (g *Generator) generateContexts(verdir string, api *design.APIDefinition, version *design.APIVersionDefinition) error {
@@ -159,6 +358,8 @@ func (g *Generator) generateContexts(verdir string, api *design.APIDefinition, v
 	title := fmt.Sprintf("%s: Application Contexts", version.Context())
 	imports := []*codegen.ImportSpec{
 		codegen.SimpleImport("fmt"),
+		codegen.SimpleImport("io"),
+		codegen.SimpleImport("mime/multipart"),
 		codegen.SimpleImport("strconv"),
 		codegen.SimpleImport("github.com/raphael/goa"),
 	}
@@ -177,17 +378,20 @@ func (g *Generator) generateContexts(verdir string, api *design.APIDefinition, v
 		return r.IterateActions(func(a *design.ActionDefinition) error {
 			ctxName := codegen.Goify(a.Name, true) + codegen.Goify(a.Parent.Name, true) + "Context"
 			ctxData := ContextTemplateData{
-				Name:         ctxName,
-				ResourceName: r.Name,
-				ActionName:   a.Name,
-				Payload:      a.Payload,
-				Params:       a.AllParams(),
-				Headers:      r.Headers.Merge(a.Headers),
-				Routes:       a.Routes,
-				Responses:    MergeResponses(r.Responses, a.Responses),
-				API:          api,
-				Version:      version,
-				DefaultPkg:   TargetPackage,
+				Name:          ctxName,
+				ResourceName:  r.Name,
+				ActionName:    a.Name,
+				Payload:       a.Payload,
+				StreamPayload: a.StreamPayload,
+				MaxStreamSize: a.MaxStreamSize,
+				Files:         a.Files,
+				Params:        a.AllParams(),
+				Headers:       r.Headers.Merge(a.Headers),
+				Routes:        a.Routes,
+				Responses:     MergeResponses(r.Responses, a.Responses),
+				API:           api,
+				Version:       version,
+				DefaultPkg:    TargetPackage,
 			}
 			return ctxWr.Execute(&ctxData)
 		})
@@ -209,6 +413,9 @@ func (g *Generator) generateControllers(verdir string, version *design.APIVersio
 	}
 	title := fmt.Sprintf("%s: Application Controllers", version.Context())
 	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("net/url"),
+		codegen.SimpleImport("strconv"),
+		codegen.SimpleImport("strings"),
 		codegen.SimpleImport("github.com/julienschmidt/httprouter"),
 		codegen.SimpleImport("github.com/raphael/goa"),
 	}
@@ -219,22 +426,56 @@ func (g *Generator) generateControllers(verdir string, version *design.APIVersio
 		}
 		imports = append(imports, codegen.SimpleImport(appPkg))
 	}
-	ctlWr.WriteHeader(title, packageName(version), imports)
 	var controllersData []*ControllerTemplateData
-	version.IterateResources(func(r *design.ResourceDefinition) error {
+	usesTime := version.Deprecation != nil && !version.Deprecation.Sunset.IsZero()
+	err = version.IterateResources(func(r *design.ResourceDefinition) error {
 		if !r.SupportsVersion(version.Version) {
 			return nil
 		}
-		data := &ControllerTemplateData{Resource: codegen.Goify(r.Name, true)}
+		data := &ControllerTemplateData{Resource: codegen.Goify(r.Name, true), ResourceName: r.Name}
 		err := r.IterateActions(func(a *design.ActionDefinition) error {
 			context := fmt.Sprintf("%s%sContext", codegen.Goify(a.Name, true), codegen.Goify(r.Name, true))
 			unmarshal := fmt.Sprintf("unmarshal%s%sPayload", codegen.Goify(a.Name, true), codegen.Goify(r.Name, true))
+			timeout, err := a.Timeout()
+			if err != nil {
+				return err
+			}
+			var timeoutCode string
+			if timeout > 0 {
+				timeoutCode = durationCode(timeout)
+				usesTime = true
+			}
+			requests, period, err := a.RateLimit()
+			if err != nil {
+				return err
+			}
+			var rateLimitCode string
+			if requests > 0 {
+				rateLimitCode = fmt.Sprintf("%q, %d, %s", r.Name+"#"+a.Name, requests, durationCode(period))
+				usesTime = true
+			}
+			var deprecation string
+			if d := a.EffectiveDeprecation(); d != nil {
+				deprecation = deprecationCode(d)
+				usesTime = usesTime || !d.Sunset.IsZero()
+			}
 			action := map[string]interface{}{
-				"Name":      codegen.Goify(a.Name, true),
-				"Routes":    a.Routes,
-				"Context":   context,
-				"Unmarshal": unmarshal,
-				"Payload":   a.Payload,
+				"Name":         codegen.Goify(a.Name, true),
+				"DesignName":   a.Name,
+				"Routes":       a.Routes,
+				"Context":      context,
+				"Unmarshal":    unmarshal,
+				"Payload":      a.Payload,
+				"Form":         a.Form,
+				"Timeout":      timeoutCode,
+				"RateLimit":    rateLimitCode,
+				"Deprecation":  deprecation,
+				"ETag":         a.ETag,
+				"CacheControl": a.CacheControl,
+				"MaxBodySize":  a.EffectiveMaxBodySize(),
+				"Security":     a.EffectiveSecurity(),
+				"Produces":     producedContentTypes(a),
+				"Consumes":     a.EffectiveConsumes(),
 			}
 			data.Actions = append(data.Actions, action)
 			return nil
@@ -244,10 +485,21 @@ func (g *Generator) generateControllers(verdir string, version *design.APIVersio
 		}
 		if len(data.Actions) > 0 {
 			data.Version = version
+			if version.Deprecation != nil {
+				data.Deprecation = deprecationCode(version.Deprecation)
+			}
+			data.Origins, data.PreflightPaths = corsData(r, version)
 			controllersData = append(controllersData, data)
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	if usesTime {
+		imports = append(imports, codegen.SimpleImport("time"))
+	}
+	ctlWr.WriteHeader(title, packageName(version), imports)
 	g.genfiles = append(g.genfiles, ctlFile)
 	if err = ctlWr.Execute(controllersData); err != nil {
 		return err
@@ -361,3 +613,76 @@ func (g *Generator) generateUserTypes(verdir string, version *design.APIVersionD
 	}
 	return utWr.FormatCode()
 }
+
+// generateErrors iterates through the errors defined in the design and generates the error
+// constructors.
+func (g *Generator) generateErrors(verdir string, api *design.APIDefinition, version *design.APIVersionDefinition) error {
+	hasErrors := false
+	version.IterateErrors(func(*design.ResponseDefinition) error {
+		hasErrors = true
+		return nil
+	})
+	if !hasErrors {
+		return nil
+	}
+	errFile := filepath.Join(verdir, "errors.go")
+	errWr, err := NewErrorsWriter(errFile)
+	if err != nil {
+		panic(err) // bug
+	}
+	title := fmt.Sprintf("%s: Application Errors", version.Context())
+	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("github.com/raphael/goa"),
+		codegen.SimpleImport("fmt"),
+	}
+	if !version.IsDefault() {
+		appPkg, err := AppPackagePath()
+		if err != nil {
+			return err
+		}
+		imports = append(imports, codegen.SimpleImport(appPkg))
+	}
+	errWr.WriteHeader(title, packageName(version), imports)
+	err = version.IterateErrors(func(e *design.ResponseDefinition) error {
+		data := &ErrorTemplateData{
+			Error:      e,
+			API:        api,
+			Version:    version,
+			DefaultPkg: TargetPackage,
+		}
+		return errWr.Execute(data)
+	})
+	g.genfiles = append(g.genfiles, errFile)
+	if err != nil {
+		return err
+	}
+	return errWr.FormatCode()
+}
+
+// generateSecurity generates the functions used to mount the security scheme middleware. The
+// functions are generated in the default package as security schemes apply to all versions.
+func (g *Generator) generateSecurity(verdir string, api *design.APIDefinition) error {
+	var schemes []*design.SecuritySchemeDefinition
+	api.IterateSecuritySchemes(func(s *design.SecuritySchemeDefinition) error {
+		schemes = append(schemes, s)
+		return nil
+	})
+	if len(schemes) == 0 {
+		return nil
+	}
+	secFile := filepath.Join(verdir, "security.go")
+	secWr, err := NewSecurityWriter(secFile)
+	if err != nil {
+		panic(err) // bug
+	}
+	title := fmt.Sprintf("%s: Application Security", api.Context())
+	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("github.com/raphael/goa"),
+	}
+	secWr.WriteHeader(title, packageName(api.APIVersionDefinition), imports)
+	g.genfiles = append(g.genfiles, secFile)
+	if err = secWr.Execute(schemes); err != nil {
+		return err
+	}
+	return secWr.FormatCode()
+}
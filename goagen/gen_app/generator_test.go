@@ -252,6 +252,38 @@ var _ = Describe("Generate", func() {
 		})
 
 	})
+
+	Context("with an API using the tree router", func() {
+		BeforeEach(func() {
+			dsl.InitDesign()
+			dsl.API("test api", func() {
+				dsl.Router("tree")
+			})
+			dsl.Resource("user", func() {
+				dsl.BasePath("/users")
+				dsl.Action("show", func() {
+					dsl.Routing(dsl.GET("/:id"))
+				})
+				dsl.Action("me", func() {
+					dsl.Routing(dsl.GET("/me"))
+				})
+				dsl.Action("posts", func() {
+					dsl.Routing(dsl.GET("/:name/posts"))
+				})
+			})
+			Ω(dsl.RunDSL()).ShouldNot(HaveOccurred())
+			Ω(design.Design.Validate()).Should(BeNil())
+		})
+
+		It("mounts the routes that the tree router accepts", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "app", "controllers.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring(`mux.Handle("GET", "/users/:id", `))
+			Ω(string(content)).Should(ContainSubstring(`mux.Handle("GET", "/users/me", `))
+			Ω(string(content)).Should(ContainSubstring(`mux.Handle("GET", "/users/:name/posts", `))
+		})
+	})
 })
 
 const contextsCodeTmpl = `//************************************************************************//
@@ -335,7 +367,7 @@ func MountWidgetController(service goa.Service, ctrl WidgetController) {
 		}
 		return ctrl.Get(ctx)
 	}
-	mux.Handle("GET", "/:id", ctrl.HandleFunc("Get", h, nil))
+	mux.Handle("GET", "/:id", ctrl.HandleRoute(&goa.Route{Method: "GET", Path: "/:id", Resource: "Widget", Action: "get"{{if .version}}, Version: "{{.version}}"{{end}}}, "Get", h, nil))
 	service.Info("mount", "ctrl", "Widget",{{if .version}} "version", "{{.version}}",{{end}} "action", "Get", "route", "GET /:id")
 }
 `
@@ -53,20 +53,44 @@ type (
 		UserTypeTmpl *template.Template
 	}
 
+	// ErrorsWriter generate code for a goa application errors.
+	// Errors are created by controller actions to write the error responses defined in the design.
+	ErrorsWriter struct {
+		*codegen.SourceFile
+		ErrorTmpl *template.Template
+	}
+
+	// SecurityWriter generate code for the hooks that mount the security scheme middleware.
+	SecurityWriter struct {
+		*codegen.SourceFile
+	}
+
 	// ContextTemplateData contains all the information used by the template to render the context
 	// code for an action.
 	ContextTemplateData struct {
-		Name         string // e.g. "ListBottleContext"
-		ResourceName string // e.g. "bottles"
-		ActionName   string // e.g. "list"
-		Params       *design.AttributeDefinition
-		Payload      *design.UserTypeDefinition
-		Headers      *design.AttributeDefinition
-		Routes       []*design.RouteDefinition
-		Responses    map[string]*design.ResponseDefinition
-		API          *design.APIDefinition
-		Version      *design.APIVersionDefinition
-		DefaultPkg   string
+		Name          string // e.g. "ListBottleContext"
+		ResourceName  string // e.g. "bottles"
+		ActionName    string // e.g. "list"
+		Params        *design.AttributeDefinition
+		Payload       *design.UserTypeDefinition
+		StreamPayload bool                     // true if the action reads the request body as a raw stream
+		MaxStreamSize int64                    // Maximum size of the streamed request body, 0 means no limit
+		Files         []*design.FileDefinition // Files uploaded in the multipart form payload
+		Headers       *design.AttributeDefinition
+		Routes        []*design.RouteDefinition
+		Responses     map[string]*design.ResponseDefinition
+		API           *design.APIDefinition
+		Version       *design.APIVersionDefinition
+		DefaultPkg    string
+	}
+
+	// ErrorTemplateData contains all the information used by the template to render the error
+	// constructor code.
+	ErrorTemplateData struct {
+		Error      *design.ResponseDefinition
+		API        *design.APIDefinition
+		Version    *design.APIVersionDefinition
+		DefaultPkg string
 	}
 
 	// MediaTypeTemplateData contains all the information used by the template to redner the
@@ -87,9 +111,20 @@ type (
 
 	// ControllerTemplateData contains the information required to generate an action handler.
 	ControllerTemplateData struct {
-		Resource string                       // Lower case plural resource name, e.g. "bottles"
-		Actions  []map[string]interface{}     // Array of actions, each action has keys "Name", "Routes", "Context" and "Unmarshal"
-		Version  *design.APIVersionDefinition // Controller API version
+		Resource       string                       // Lower case plural resource name, e.g. "bottles"
+		ResourceName   string                       // Name of the resource as defined in the design
+		Actions        []map[string]interface{}     // Array of actions, each action has keys "Name", "DesignName", "Routes", "Context", "Unmarshal", "Payload", "Form", "Timeout", "RateLimit", "Deprecation", "ETag", "CacheControl", "MaxBodySize", "Security", "Produces" and "Consumes"
+		Version        *design.APIVersionDefinition // Controller API version
+		Deprecation    string                       // Code initializing the API version deprecation if any
+		Origins        []*design.CORSDefinition     // CORS policies that apply to the resource
+		PreflightPaths []*PreflightTemplateData     // Paths that handle CORS preflight requests
+	}
+
+	// PreflightTemplateData contains the information required to generate the handler of the CORS
+	// preflight requests sent to a path.
+	PreflightTemplateData struct {
+		Path    string                   // Path of the preflight requests
+		Origins []*design.CORSDefinition // Policies merged from all the resources that handle the path if it is shared, nil otherwise
 	}
 
 	// ResourceData contains the information required to generate the resource GoGenerator
@@ -108,6 +143,11 @@ func (c *ContextTemplateData) Versioned() bool {
 	return !c.Version.IsDefault()
 }
 
+// Versioned returns true if the error was built from an API version.
+func (e *ErrorTemplateData) Versioned() bool {
+	return !e.Version.IsDefault()
+}
+
 // IsPathParam returns true if the given parameter name corresponds to a path parameter for all
 // the context action routes. Such parameter is required but does not need to be validated as
 // httprouter takes care of that.
@@ -191,7 +231,11 @@ func (w *ControllersWriter) Execute(data []*ControllerTemplateData) error {
 		if err := w.ExecuteTemplate("mount", mountT, nil, d); err != nil {
 			return err
 		}
-		if err := w.ExecuteTemplate("unmarshal", unmarshalT, nil, d); err != nil {
+		fn := template.FuncMap{
+			"newCoerceData":  newCoerceData,
+			"arrayAttribute": arrayAttribute,
+		}
+		if err := w.ExecuteTemplate("unmarshal", unmarshalT, fn, d); err != nil {
 			return err
 		}
 	}
@@ -229,6 +273,36 @@ func (w *MediaTypesWriter) Execute(data *MediaTypeTemplateData) error {
 	return w.ExecuteTemplate("new", mediaTypeT, fn, data)
 }
 
+// NewErrorsWriter returns an errors code writer.
+// Errors are created by controller actions to write the error responses defined in the design.
+func NewErrorsWriter(filename string) (*ErrorsWriter, error) {
+	file, err := codegen.SourceFileFor(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &ErrorsWriter{SourceFile: file}, nil
+}
+
+// Execute writes the code for the error constructor to the writer.
+func (w *ErrorsWriter) Execute(data *ErrorTemplateData) error {
+	return w.ExecuteTemplate("error", errorT, nil, data)
+}
+
+// NewSecurityWriter returns a security code writer.
+// The generated code mounts the middleware that enforce the security schemes defined in the design.
+func NewSecurityWriter(filename string) (*SecurityWriter, error) {
+	file, err := codegen.SourceFileFor(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &SecurityWriter{SourceFile: file}, nil
+}
+
+// Execute writes the code for the security scheme hooks to the writer.
+func (w *SecurityWriter) Execute(schemes []*design.SecuritySchemeDefinition) error {
+	return w.ExecuteTemplate("security", securityT, nil, schemes)
+}
+
 // NewUserTypesWriter returns a contexts code writer.
 // User types contain custom data structured defined in the DSL with "Type".
 func NewUserTypesWriter(filename string) (*UserTypesWriter, error) {
@@ -283,6 +357,8 @@ type {{.Name}} struct {
 {{if .Params}}{{$ctx := .}}{{range $name, $att := .Params.Type.ToObject}}{{/*
 */}}	{{goify $name true}} {{if and $att.Type.IsPrimitive ($ctx.Params.IsPrimitivePointer $name)}}*{{end}}{{gotyperef .Type nil 0}}
 {{end}}{{end}}{{if .Payload}}	Payload {{gotyperef .Payload nil 0}}
+{{end}}{{if .StreamPayload}}	Body io.Reader
+{{end}}{{range .Files}}	{{goify .Name true}} *multipart.FileHeader
 {{end}}}
 `
 	// coerceT generates the code that coerces the generic deserialized
@@ -332,22 +408,36 @@ type {{.Name}} struct {
 
 */}}{{/* ArrayType */}}{{/*
 */}}{{tabs .Depth}}elems{{goify .Name true}} := strings.Split(raw{{goify .Name true}}, ",")
-{{if eq (arrayAttribute .Attribute).Type.Kind 4}}{{tabs .Depth}}{{.Pkg}} = elems{{goify .Name true}}
+{{template "CoerceElems" .}}{{end}}`
+
+	// coerceElemsT generates the code that coerces the raw string elements of an array held in
+	// the "elems<Name>" variable.
+	coerceElemsT = `{{if eq (arrayAttribute .Attribute).Type.Kind 4}}{{tabs .Depth}}{{.Pkg}} = elems{{goify .Name true}}
 {{else}}{{tabs .Depth}}elems{{goify .Name true}}2 := make({{gotyperef .Attribute.Type nil .Depth}}, len(elems{{goify .Name true}}))
 {{tabs .Depth}}for i, rawElem := range elems{{goify .Name true}} {
 {{template "Coerce" (newCoerceData "elem" (arrayAttribute .Attribute) false (printf "elems%s2[i]" (goify .Name true)) (add .Depth 1))}}{{tabs .Depth}}}
 {{tabs .Depth}}{{.Pkg}} = elems{{goify .Name true}}2
-{{end}}{{end}}`
+{{end}}`
 
 	// ctxNewT generates the code for the context factory method.
 	// template input: *ContextTemplateData
-	ctxNewT = `{{define "Coerce"}}` + coerceT + `{{end}}` + `
+	ctxNewT = `{{define "Coerce"}}` + coerceT + `{{end}}{{define "CoerceElems"}}` + coerceElemsT + `{{end}}` + `
 // New{{goify .Name true}} parses the incoming request URL and body, performs validations and creates the
 // context used by the {{.ResourceName}} controller {{.ActionName}} action.
 func New{{.Name}}(c *goa.Context) (*{{.Name}}, error) {
 	var err error
 	ctx := {{.Name}}{Context: c}
-{{if .Headers}}{{$headers := .Headers}}{{range $name, $_ := $headers.Type.ToObject}}{{if ($headers.IsRequired $name)}}	if c.Request().Header.Get("{{$name}}") == "" {
+{{if .StreamPayload}}	ctx.Body = c.StreamBody({{.MaxStreamSize}})
+{{end}}{{range .Files}}	fh{{goify .Name true}}, err2 := c.FormFile("{{.Name}}")
+	if err2 != nil {
+		err = goa.InvalidEncodingError(err2, err)
+	} else if fh{{goify .Name true}} {{if .Required}}== nil {
+		err = goa.MissingFileError("{{.Name}}", err)
+	} else {{else}}!= nil {{end}}{
+		ctx.{{goify .Name true}} = fh{{goify .Name true}}
+{{if or .MaxSize .ContentTypes}}		err = goa.ValidateFile("{{.Name}}", fh{{goify .Name true}}, {{.MaxSize}}, {{if .ContentTypes}}{{printf "%#v" .ContentTypes}}{{else}}nil{{end}}, err)
+{{end}}	}
+{{end}}{{if .Headers}}{{$headers := .Headers}}{{range $name, $_ := $headers.Type.ToObject}}{{if ($headers.IsRequired $name)}}	if c.Request().Header.Get("{{$name}}") == "" {
 		err = goa.MissingHeaderError("{{$name}}", err)
 	}{{end}}{{end}}
 {{end}}{{if.Params}}{{$ctx := .}}{{range $name, $att := .Params.Type.ToObject}}	raw{{goify $name true}} := c.Get("{{$name}}")
@@ -364,7 +454,72 @@ func New{{.Name}}(c *goa.Context) (*{{.Name}}, error) {
 `
 	// ctxRespT generates response helper methods GoGenerator
 	// template input: *ContextTemplateData
-	ctxRespT = `{{$ctx := .}}{{range .Responses}}{{$mt := $ctx.API.MediaTypeWithIdentifier .MediaType}}{{/*
+	ctxRespT = `{{$ctx := .}}{{range .Responses}}{{if .Stream}}{{/*
+*/}}// {{goify .Name true}} sends a HTTP response with status code {{.Status}}, fn writes the response body.
+func (ctx *{{$ctx.Name}}) {{goify .Name true}}(fn func(w io.Writer) error) error {
+{{if .MediaType}}	ctx.Header().Set("Content-Type", "{{.MediaType}}")
+{{end}}	return ctx.RespondStream({{.Status}}, fn)
+}
+
+{{else if .Events}}{{$mt := $ctx.API.MediaTypeWithIdentifier .MediaType}}{{/*
+*/}}// {{goify .Name true}} sends server-sent events with status code {{.Status}}, each value received on events
+// is validated and sent as an event. {{goify .Name true}} returns when events is closed, when the client
+// disconnects or when the context is done.
+func (ctx *{{$ctx.Name}}) {{goify .Name true}}(events <-chan {{gopkgtyperef $mt $mt.AllRequired $ctx.Versioned $ctx.DefaultPkg 0}}{{/*
+*/}}{{if gt (len $mt.ComputeViews) 1}}, view {{gopkgtypename $mt $mt.AllRequired $ctx.Versioned $ctx.DefaultPkg 0}}ViewEnum{{end}}) error {
+	return ctx.RespondEvents({{.Status}}, func(s *goa.EventStream) error {
+		for {
+			select {
+			case resp, ok := <-events:
+				if !ok {
+					return nil
+				}
+				r, err := resp.Dump({{if gt (len $mt.ComputeViews) 1}}view{{end}})
+				if err != nil {
+					return fmt.Errorf("invalid event: %s", err)
+				}
+				if err := s.Send("", r); err != nil {
+					return err
+				}
+			case <-s.Done():
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
+
+{{else if .WebSocket}}{{$mt := $ctx.API.MediaTypeWithIdentifier .MediaType}}{{/*
+*/}}// {{goify .Name true}} upgrades the connection to a WebSocket connection, each value received on messages
+// is validated and sent as a message. {{goify .Name true}} returns when messages is closed, when the client
+// closes the connection or when the context is done.
+func (ctx *{{$ctx.Name}}) {{goify .Name true}}(messages <-chan {{gopkgtyperef $mt $mt.AllRequired $ctx.Versioned $ctx.DefaultPkg 0}}{{/*
+*/}}{{if gt (len $mt.ComputeViews) 1}}, view {{gopkgtypename $mt $mt.AllRequired $ctx.Versioned $ctx.DefaultPkg 0}}ViewEnum{{end}}) error {
+	return ctx.RespondWebSocket(func(ws *goa.WebSocket) error {
+		for {
+			select {
+			case resp, ok := <-messages:
+				if !ok {
+					return nil
+				}
+				r, err := resp.Dump({{if gt (len $mt.ComputeViews) 1}}view{{end}})
+				if err != nil {
+					return fmt.Errorf("invalid message: %s", err)
+				}
+				if err := ws.Send(r); err != nil {
+					return err
+				}
+			case <-ws.Done():
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
+
+{{else}}{{$mt := $ctx.API.MediaTypeWithIdentifier .MediaType}}{{/*
 */}}// {{goify .Name true}} sends a HTTP response with status code {{.Status}}.
 func (ctx *{{$ctx.Name}}) {{goify .Name true}}({{/*
 */}}{{if $mt}}resp {{gopkgtyperef $mt $mt.AllRequired $ctx.Versioned $ctx.DefaultPkg 0}}{{if gt (len $mt.ComputeViews) 1}}, view {{gopkgtypename $mt $mt.AllRequired $ctx.Versioned $ctx.DefaultPkg 0}}ViewEnum{{end}}{{/*
@@ -377,6 +532,40 @@ func (ctx *{{$ctx.Name}}) {{goify .Name true}}({{/*
 	return ctx.Respond({{.Status}}, r){{else}}	return ctx.RespondBytes({{.Status}}, {{if and (not $mt) .MediaType}}resp{{else}}nil{{end}}){{end}}
 }
 
+{{end}}{{end}}`
+
+	// errorT generates the constructor of an error defined in the design.
+	// template input: *ErrorTemplateData
+	errorT = `{{$e := .Error}}{{$mt := .API.MediaTypeWithIdentifier $e.MediaType}}{{/*
+*/}}// Err{{goify $e.Name true}} creates a {{$e.Name}} error, the corresponding response has status code {{$e.Status}}.
+func Err{{goify $e.Name true}}({{/*
+*/}}{{if $mt}}resp {{gopkgtyperef $mt $mt.AllRequired .Versioned .DefaultPkg 0}}{{if gt (len $mt.ComputeViews) 1}}, view {{gopkgtypename $mt $mt.AllRequired .Versioned .DefaultPkg 0}}ViewEnum{{end}}{{/*
+*/}}{{else if $e.MediaType}}resp []byte{{end}}) error {
+{{if $mt}}	r, err := resp.Dump({{if gt (len $mt.ComputeViews) 1}}view{{end}})
+	if err != nil {
+		return fmt.Errorf("invalid error response: %s", err)
+	}
+{{end}}	return &goa.ResponseError{
+		Name:        "{{$e.Name}}",
+		Status:      {{$e.Status}},
+{{if $mt}}		ContentType: "{{$mt.Identifier}}; charset=utf-8",
+		Body:        r,
+{{else if $e.MediaType}}		ContentType: "{{$e.MediaType}}",
+		Body:        resp,
+{{end}}	}
+}
+
+`
+
+	// securityT generates the functions that mount the middleware enforcing the security
+	// schemes.
+	// template input: []*design.SecuritySchemeDefinition
+	securityT = `{{range .}}// Use{{goify .Name true}}Middleware mounts the middleware that enforces the "{{.Name}}" security scheme.
+{{if .Description}}// {{.Description}}
+{{end}}func Use{{goify .Name true}}Middleware(service goa.Service, m goa.Middleware) {
+	service.SetSecurityMiddleware("{{.Name}}", m)
+}
+
 {{end}}`
 
 	// payloadT generates the payload type definition GoGenerator
@@ -401,12 +590,14 @@ type {{.Resource}}Controller interface {
 
 	// mountT generates the code for a resource "Mount" function.
 	// template input: *ControllerTemplateData
-	mountT = `
+	mountT = `{{define "CORSPolicies"}}` + corsPoliciesT + `{{end}}
 // Mount{{.Resource}}Controller "mounts" a {{.Resource}} resource controller on the given service.
 func Mount{{.Resource}}Controller(service goa.Service, ctrl {{.Resource}}Controller) {
 	var h goa.Handler
 	mux := service.ServeMux(){{if not .Version.IsDefault}}.Version("{{.Version.Version}}"){{end}}
-{{$res := .Resource}}{{$ver := .Version}}{{range .Actions}}{{$action := .}}	h = func(c *goa.Context) error {
+{{with .Deprecation}}	mux.Deprecate({{.}})
+{{end}}{{if .Origins}}	cors := {{template "CORSPolicies" .Origins}}
+{{end}}{{$res := .Resource}}{{$ver := .Version}}{{$origins := .Origins}}{{range .Actions}}{{$action := .}}	h = func(c *goa.Context) error {
 		ctx, err := New{{.Context}}(c)
 {{if .Payload}}		ctx.Payload = ctx.RawPayload().(*{{gotypename .Payload nil 1}})
 {{end}}		if err != nil {
@@ -414,20 +605,63 @@ func Mount{{.Resource}}Controller(service goa.Service, ctrl {{.Resource}}Control
 		}
 		return ctrl.{{.Name}}(ctx)
 	}
-{{range .Routes}}	mux.Handle("{{.Verb}}", "{{.FullPath $ver}}", ctrl.HandleFunc("{{$action.Name}}", h, {{if $action.Payload}}{{$action.Unmarshal}}{{else}}nil{{end}}))
+{{with .CacheControl}}	h = goa.Cache("{{.}}")(h)
+{{end}}{{if .ETag}}	h = goa.ETag()(h)
+{{end}}{{with .Produces}}	h = goa.Produces({{range $i, $t := .}}{{if $i}}, {{end}}"{{$t}}"{{end}})(h)
+{{end}}{{with .RateLimit}}	h = goa.RateLimit({{.}})(h)
+{{end}}{{with .Security}}	h = goa.RequireSecurity("{{.Scheme.Name}}"{{range .Scopes}}, "{{.}}"{{end}})(h)
+{{end}}{{if .Timeout}}	h = goa.Timeout({{.Timeout}})(h)
+{{end}}{{with .Deprecation}}	h = goa.Deprecated({{.}})(h)
+{{end}}{{if $origins}}	h = goa.CORS(cors...)(h)
+{{end}}{{range .Routes}}	mux.Handle("{{.Verb}}", "{{.FullPath $ver}}", ctrl.HandleRoute(&goa.Route{Method: "{{.Verb}}", Path: "{{.FullPath $ver}}", Resource: "{{$.ResourceName}}", Action: "{{$action.DesignName}}"{{if not $ver.IsDefault}}, Version: "{{$ver.Version}}"{{end}}{{with $action.MaxBodySize}}, MaxBodySize: {{.}}{{end}}{{/*
+*/}}{{with $action.Consumes}}, Consumes: []string{ {{- range $i, $t := .}}{{if $i}}, {{end}}"{{$t}}"{{end -}} }{{end}}}, "{{$action.Name}}", h, {{if $action.Payload}}{{$action.Unmarshal}}{{else}}nil{{end}}))
 	service.Info("mount", "ctrl", "{{$res}}",{{if not $ver.IsDefault}} "version", "{{$ver.Version}}",{{end}} "action", "{{$action.Name}}", "route", "{{.Verb}} {{.FullPath $ver}}")
-{{end}}{{end}}}
+{{end}}{{end}}{{range .PreflightPaths}}	if mux.Lookup("OPTIONS", "{{.Path}}") == nil {
+{{if .Origins}}		preflight := {{template "CORSPolicies" .Origins}}
+{{end}}		mux.Handle("OPTIONS", "{{.Path}}", ctrl.HandleFunc("preflight", goa.CORSPreflight({{if .Origins}}preflight{{else}}cors{{end}}...), nil))
+		service.Info("mount", "ctrl", "{{$res}}",{{if not $ver.IsDefault}} "version", "{{$ver.Version}}",{{end}} "action", "preflight", "route", "OPTIONS {{.Path}}")
+	}
+{{end}}}
 `
 
+	// corsPoliciesT generates the code for a list of CORS policies.
+	// template input: []*design.CORSDefinition
+	corsPoliciesT = `[]*goa.CORSPolicy{
+{{range .}}		{
+			Origin:      {{printf "%q" .Origin}},
+{{if .Methods}}			Methods:     {{printf "%#v" .Methods}},
+{{end}}{{if .Headers}}			Headers:     {{printf "%#v" .Headers}},
+{{end}}{{if .Exposed}}			Exposed:     {{printf "%#v" .Exposed}},
+{{end}}{{if .MaxAge}}			MaxAge:      {{.MaxAge}},
+{{end}}{{if .Credentials}}			Credentials: true,
+{{end}}		},
+{{end}}	}`
+
 	// unmarshalT generates the code for an action payload unmarshal function.
 	// template input: *ControllerTemplateData
-	unmarshalT = `{{range .Actions}}{{if .Payload}}
+	unmarshalT = `{{define "Coerce"}}` + coerceT + `{{end}}{{define "CoerceElems"}}` + coerceElemsT + `{{end}}` + `{{range .Actions}}{{if .Payload}}{{if .Form}}
+// {{.Unmarshal}} unmarshals the request form body.
+func {{.Unmarshal}}(ctx *goa.Context) error {
+	var form url.Values
+	if err := ctx.Service().DecodeRequest(ctx, &form); err != nil {
+		return err
+	}
+	var err error
+	payload := &{{gotypename .Payload nil 1}}{}
+{{$payload := .Payload}}{{range $name, $att := .Payload.Type.ToObject}}{{if eq $att.Type.Kind 6}}	if elems{{goify $name true}} := form["{{$name}}"]; len(elems{{goify $name true}}) > 0 {
+{{template "CoerceElems" (newCoerceData $name $att false (printf "payload.%s" (goify $name true)) 2)}}	}
+{{else}}	raw{{goify $name true}} := form.Get("{{$name}}")
+	if raw{{goify $name true}} != "" {
+{{template "Coerce" (newCoerceData $name $att ($payload.IsPrimitivePointer $name) (printf "payload.%s" (goify $name true)) 2)}}	}
+{{end}}{{end}}	if err != nil {
+		return err
+	}{{else}}
 // {{.Unmarshal}} unmarshals the request body.
 func {{.Unmarshal}}(ctx *goa.Context) error {
 	payload := &{{gotypename .Payload nil 1}}{}
 	if err := ctx.Service().DecodeRequest(ctx, payload); err != nil {
 		return err
-	}
+	}{{end}}
 	if err := payload.Validate(); err != nil {
 		return err
 	}
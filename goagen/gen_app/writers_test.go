@@ -49,6 +49,9 @@ var _ = Describe("ContextsWriter", func() {
 			var payload *design.UserTypeDefinition
 			var responses map[string]*design.ResponseDefinition
 			var mediaTypes map[string]*design.MediaTypeDefinition
+			var streamPayload bool
+			var maxStreamSize int64
+			var files []*design.FileDefinition
 
 			var data *genapp.ContextTemplateData
 
@@ -58,6 +61,9 @@ var _ = Describe("ContextsWriter", func() {
 				payload = nil
 				responses = nil
 				mediaTypes = nil
+				streamPayload = false
+				maxStreamSize = 0
+				files = nil
 				data = nil
 			})
 
@@ -69,19 +75,168 @@ var _ = Describe("ContextsWriter", func() {
 					version = &design.APIVersionDefinition{}
 				}
 				data = &genapp.ContextTemplateData{
-					Name:         "ListBottleContext",
-					ResourceName: "bottles",
-					ActionName:   "list",
-					Params:       params,
-					Payload:      payload,
-					Headers:      headers,
-					Responses:    responses,
-					API:          design.Design,
-					Version:      version,
-					DefaultPkg:   "",
+					Name:          "ListBottleContext",
+					ResourceName:  "bottles",
+					ActionName:    "list",
+					Params:        params,
+					Payload:       payload,
+					StreamPayload: streamPayload,
+					MaxStreamSize: maxStreamSize,
+					Files:         files,
+					Headers:       headers,
+					Responses:     responses,
+					API:           design.Design,
+					Version:       version,
+					DefaultPkg:    "",
 				}
 			})
 
+			Context("with uploaded files", func() {
+				BeforeEach(func() {
+					files = []*design.FileDefinition{
+						{
+							Name:         "picture",
+							Required:     true,
+							MaxSize:      1024,
+							ContentTypes: []string{"image/png", "image/jpeg"},
+						},
+						{Name: "thumbnail"},
+					}
+				})
+
+				It("writes the contexts code", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).ShouldNot(BeEmpty())
+					Ω(written).Should(ContainSubstring(filesContext))
+					Ω(written).Should(ContainSubstring(filesContextFactory))
+				})
+			})
+
+			Context("with a streamed payload and response", func() {
+				BeforeEach(func() {
+					streamPayload = true
+					maxStreamSize = 1024
+					responses = map[string]*design.ResponseDefinition{
+						"OK": {
+							Name:      "OK",
+							Status:    200,
+							MediaType: "application/octet-stream",
+							Stream:    true,
+						},
+					}
+				})
+
+				It("writes the contexts code", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).ShouldNot(BeEmpty())
+					Ω(written).Should(ContainSubstring(streamContext))
+					Ω(written).Should(ContainSubstring(streamContextFactory))
+					Ω(written).Should(ContainSubstring(streamContextResponse))
+				})
+			})
+
+			Context("with a server-sent events response", func() {
+				var oldDesign *design.APIDefinition
+
+				BeforeEach(func() {
+					oldDesign = design.Design
+					attDef := &design.AttributeDefinition{
+						Type: design.Object{"value": {Type: design.Integer}},
+					}
+					mt := &design.MediaTypeDefinition{
+						UserTypeDefinition: &design.UserTypeDefinition{
+							AttributeDefinition: attDef,
+							TypeName:            "Event",
+						},
+						Identifier: "application/vnd.goa.test.event",
+					}
+					mt.Views = map[string]*design.ViewDefinition{
+						"default": {AttributeDefinition: attDef, Name: "default", Parent: mt},
+					}
+					design.Design = &design.APIDefinition{
+						APIVersionDefinition: &design.APIVersionDefinition{Name: "test"},
+						MediaTypes:           map[string]*design.MediaTypeDefinition{mt.Identifier: mt},
+					}
+					responses = map[string]*design.ResponseDefinition{
+						"OK": {
+							Name:      "OK",
+							Status:    200,
+							MediaType: mt.Identifier,
+							Events:    true,
+						},
+					}
+				})
+
+				AfterEach(func() {
+					design.Design = oldDesign
+				})
+
+				It("writes the contexts code", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).ShouldNot(BeEmpty())
+					Ω(written).Should(ContainSubstring(eventsContextResponse))
+				})
+			})
+
+			Context("with a WebSocket response", func() {
+				var oldDesign *design.APIDefinition
+
+				BeforeEach(func() {
+					oldDesign = design.Design
+					attDef := &design.AttributeDefinition{
+						Type: design.Object{"value": {Type: design.Integer}},
+					}
+					mt := &design.MediaTypeDefinition{
+						UserTypeDefinition: &design.UserTypeDefinition{
+							AttributeDefinition: attDef,
+							TypeName:            "Event",
+						},
+						Identifier: "application/vnd.goa.test.event",
+					}
+					mt.Views = map[string]*design.ViewDefinition{
+						"default": {AttributeDefinition: attDef, Name: "default", Parent: mt},
+					}
+					design.Design = &design.APIDefinition{
+						APIVersionDefinition: &design.APIVersionDefinition{Name: "test"},
+						MediaTypes:           map[string]*design.MediaTypeDefinition{mt.Identifier: mt},
+					}
+					responses = map[string]*design.ResponseDefinition{
+						"SwitchingProtocols": {
+							Name:      "SwitchingProtocols",
+							Status:    101,
+							MediaType: mt.Identifier,
+							WebSocket: true,
+						},
+					}
+				})
+
+				AfterEach(func() {
+					design.Design = oldDesign
+				})
+
+				It("writes the contexts code", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).ShouldNot(BeEmpty())
+					Ω(written).Should(ContainSubstring(webSocketContextResponse))
+				})
+			})
+
 			Context("with simple data", func() {
 				It("writes the contexts code", func() {
 					err := writer.Execute(data)
@@ -376,8 +531,18 @@ var _ = Describe("ControllersWriter", func() {
 		})
 
 		Context("with data", func() {
-			var actions, verbs, paths, contexts, unmarshals []string
+			var actions, verbs, paths, contexts, unmarshals, timeouts []string
 			var payloads []*design.UserTypeDefinition
+			var securities []*design.SecurityDefinition
+			var origins []*design.CORSDefinition
+			var preflightPaths []*genapp.PreflightTemplateData
+			var produces, consumes [][]string
+			var forms []string
+			var version *design.APIVersionDefinition
+			var deprecations, rateLimits, cacheControls []string
+			var etags []bool
+			var maxBodySizes []int64
+			var versionDeprecation string
 
 			var data []*genapp.ControllerTemplateData
 
@@ -387,34 +552,98 @@ var _ = Describe("ControllersWriter", func() {
 				paths = nil
 				contexts = nil
 				unmarshals = nil
+				timeouts = nil
 				payloads = nil
+				securities = nil
+				origins = nil
+				preflightPaths = nil
+				produces = nil
+				consumes = nil
+				forms = nil
+				version = &design.APIVersionDefinition{}
+				deprecations = nil
+				rateLimits = nil
+				etags = nil
+				cacheControls = nil
+				maxBodySizes = nil
+				versionDeprecation = ""
 			})
 
 			JustBeforeEach(func() {
 				d := &genapp.ControllerTemplateData{
-					Resource: "Bottles",
-					Version:  &design.APIVersionDefinition{},
+					Resource:       "Bottles",
+					ResourceName:   "bottles",
+					Version:        version,
+					Deprecation:    versionDeprecation,
+					Origins:        origins,
+					PreflightPaths: preflightPaths,
 				}
 				as := make([]map[string]interface{}, len(actions))
 				for i, a := range actions {
-					var unmarshal string
+					var unmarshal, timeout string
 					var payload *design.UserTypeDefinition
+					var security *design.SecurityDefinition
+					var prod, cons []string
+					var form, deprecation, rateLimit, cacheControl string
+					var etag bool
+					var maxBodySize int64
 					if i < len(unmarshals) {
 						unmarshal = unmarshals[i]
 					}
+					if i < len(timeouts) {
+						timeout = timeouts[i]
+					}
 					if i < len(payloads) {
 						payload = payloads[i]
 					}
+					if i < len(securities) {
+						security = securities[i]
+					}
+					if i < len(produces) {
+						prod = produces[i]
+					}
+					if i < len(consumes) {
+						cons = consumes[i]
+					}
+					if i < len(forms) {
+						form = forms[i]
+					}
+					if i < len(deprecations) {
+						deprecation = deprecations[i]
+					}
+					if i < len(rateLimits) {
+						rateLimit = rateLimits[i]
+					}
+					if i < len(etags) {
+						etag = etags[i]
+					}
+					if i < len(cacheControls) {
+						cacheControl = cacheControls[i]
+					}
+					if i < len(maxBodySizes) {
+						maxBodySize = maxBodySizes[i]
+					}
 					as[i] = map[string]interface{}{
-						"Name": a,
+						"Name":       a,
+						"DesignName": a,
 						"Routes": []*design.RouteDefinition{
 							&design.RouteDefinition{
 								Verb: verbs[i],
 								Path: paths[i],
 							}},
-						"Context":   contexts[i],
-						"Unmarshal": unmarshal,
-						"Payload":   payload,
+						"Context":      contexts[i],
+						"Unmarshal":    unmarshal,
+						"Payload":      payload,
+						"Form":         form,
+						"Timeout":      timeout,
+						"RateLimit":    rateLimit,
+						"Deprecation":  deprecation,
+						"ETag":         etag,
+						"CacheControl": cacheControl,
+						"MaxBodySize":  maxBodySize,
+						"Security":     security,
+						"Produces":     prod,
+						"Consumes":     cons,
 					}
 				}
 				if len(as) > 0 {
@@ -487,6 +716,283 @@ var _ = Describe("ControllersWriter", func() {
 				})
 			})
 
+			Context("with actions that take a form payload", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"POST"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					unmarshals = []string{"unmarshalListBottlePayload"}
+					forms = []string{design.URLEncodedFormContentType}
+					payloads = []*design.UserTypeDefinition{
+						&design.UserTypeDefinition{
+							TypeName: "ListBottlePayload",
+							AttributeDefinition: &design.AttributeDefinition{
+								Type: design.Object{
+									"name": &design.AttributeDefinition{
+										Type: design.String,
+									},
+									"years": &design.AttributeDefinition{
+										Type: &design.Array{
+											ElemType: &design.AttributeDefinition{Type: design.Integer},
+										},
+									},
+								},
+								Validations: []design.ValidationDefinition{
+									&design.RequiredValidationDefinition{Names: []string{"name"}},
+								},
+							},
+						},
+					}
+				})
+
+				It("writes the form payload unmarshal function", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(formUnmarshal))
+				})
+			})
+
+			Context("with actions that define a timeout", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					timeouts = []string{"5 * time.Second"}
+				})
+
+				It("wraps the action handler with the timeout middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(timeoutMount))
+				})
+			})
+
+			Context("with actions that define a rate limit", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					rateLimits = []string{`"bottle#list", 100, 1 * time.Minute`}
+				})
+
+				It("wraps the action handler with the rate limiting middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(`	h = goa.RateLimit("bottle#list", 100, 1 * time.Minute)(h)
+	mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.HandleRoute(&goa.Route{Method: "GET", Path: "/accounts/:accountID/bottles", Resource: "bottles", Action: "list"}, "list", h, nil))`))
+				})
+			})
+
+			Context("with actions that support ETags", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					etags = []bool{true}
+				})
+
+				It("wraps the action handler with the ETag middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(`		return ctrl.list(ctx)
+	}
+	h = goa.ETag()(h)
+`))
+				})
+			})
+
+			Context("with actions that limit the size of request bodies", func() {
+				BeforeEach(func() {
+					actions = []string{"create"}
+					verbs = []string{"POST"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"CreateBottleContext"}
+					maxBodySizes = []int64{1024}
+				})
+
+				It("sets the route maximum body size", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(`	mux.Handle("POST", "/accounts/:accountID/bottles", ctrl.HandleRoute(&goa.Route{Method: "POST", Path: "/accounts/:accountID/bottles", Resource: "bottles", Action: "create", MaxBodySize: 1024}, "create", h, nil))`))
+				})
+			})
+
+			Context("with actions that define a Cache-Control header", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					cacheControls = []string{"public, max-age=60"}
+					etags = []bool{true}
+				})
+
+				It("wraps the action handler with the cache middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(`		return ctrl.list(ctx)
+	}
+	h = goa.Cache("public, max-age=60")(h)
+	h = goa.ETag()(h)
+`))
+				})
+			})
+
+			Context("with deprecated actions", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					version = &design.APIVersionDefinition{Version: "v1"}
+					versionDeprecation = `&goa.Deprecation{Sunset: time.Date(2016, 6, 30, 0, 0, 0, 0, time.UTC)}`
+					deprecations = []string{`&goa.Deprecation{Link: "http://example.com/v2/bottles"}`}
+				})
+
+				It("sets the deprecation headers", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(deprecatedMount))
+				})
+			})
+
+			Context("with secured actions", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					securities = []*design.SecurityDefinition{
+						{
+							Scheme: &design.SecuritySchemeDefinition{
+								Kind:   design.JWTSecurityKind,
+								Name:   "jwt",
+								Scopes: map[string]string{"read": "", "write": ""},
+							},
+							Scopes: []string{"read", "write"},
+						},
+					}
+				})
+
+				It("wraps the action handler with the security middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(securedMount))
+				})
+
+				Context("and a rate limit", func() {
+					BeforeEach(func() {
+						rateLimits = []string{`"bottle#list", 100, 1 * time.Minute`}
+					})
+
+					It("applies the rate limit once the credentials are validated", func() {
+						err := writer.Execute(data)
+						Ω(err).ShouldNot(HaveOccurred())
+						b, err := ioutil.ReadFile(filename)
+						Ω(err).ShouldNot(HaveOccurred())
+						written := string(b)
+						Ω(written).Should(ContainSubstring(`	h = goa.RateLimit("bottle#list", 100, 1 * time.Minute)(h)
+	h = goa.RequireSecurity("jwt", "read", "write")(h)
+	mux.Handle(`))
+					})
+				})
+			})
+
+			Context("with actions that define content types", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					produces = [][]string{{"application/vnd.bottle+json", "application/xml"}}
+					consumes = [][]string{{"application/json"}}
+				})
+
+				It("wraps the action handler with the content negotiation middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(encodingMount))
+				})
+			})
+
+			Context("with CORS policies", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					origins = []*design.CORSDefinition{
+						{
+							Origin:      "http://example.com",
+							Methods:     []string{"GET", "POST"},
+							Headers:     []string{"X-Shared-Secret"},
+							Exposed:     []string{"X-Time"},
+							MaxAge:      600,
+							Credentials: true,
+						},
+						{Origin: "*"},
+					}
+					preflightPaths = []*genapp.PreflightTemplateData{{Path: "/accounts/:accountID/bottles"}}
+				})
+
+				It("wraps the action handler with the CORS middleware and mounts the preflight handler", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(corsMount))
+				})
+
+				Context("on a path shared with other resources", func() {
+					BeforeEach(func() {
+						preflightPaths[0].Origins = []*design.CORSDefinition{
+							{Origin: "http://example.com", Methods: []string{"DELETE", "GET", "POST"}},
+						}
+					})
+
+					It("mounts the preflight handler with the merged policies", func() {
+						err := writer.Execute(data)
+						Ω(err).ShouldNot(HaveOccurred())
+						b, err := ioutil.ReadFile(filename)
+						Ω(err).ShouldNot(HaveOccurred())
+						written := string(b)
+						Ω(written).Should(ContainSubstring(sharedPreflightMount))
+					})
+				})
+			})
+
 			Context("with multiple controllers", func() {
 				BeforeEach(func() {
 					actions = []string{"list", "show"}
@@ -510,6 +1016,126 @@ var _ = Describe("ControllersWriter", func() {
 	})
 })
 
+var _ = Describe("ErrorsWriter", func() {
+	var writer *genapp.ErrorsWriter
+	var workspace *codegen.Workspace
+	var filename string
+
+	BeforeEach(func() {
+		var err error
+		workspace, err = codegen.NewWorkspace("test")
+		Ω(err).ShouldNot(HaveOccurred())
+		pkg, err := workspace.NewPackage("controllers")
+		Ω(err).ShouldNot(HaveOccurred())
+		src := pkg.CreateSourceFile("test.go")
+		filename = src.Abs()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		writer, err = genapp.NewErrorsWriter(filename)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		workspace.Delete()
+	})
+
+	Context("with data", func() {
+		var mediaType string
+		var data *genapp.ErrorTemplateData
+
+		BeforeEach(func() {
+			mediaType = ""
+		})
+
+		JustBeforeEach(func() {
+			data = &genapp.ErrorTemplateData{
+				Error: &design.ResponseDefinition{
+					Name:      "NotFound",
+					Status:    404,
+					MediaType: mediaType,
+				},
+				API:        &design.APIDefinition{},
+				Version:    &design.APIVersionDefinition{},
+				DefaultPkg: "app",
+			}
+		})
+
+		Context("with no media type", func() {
+			It("writes the error constructor", func() {
+				err := writer.Execute(data)
+				Ω(err).ShouldNot(HaveOccurred())
+				b, err := ioutil.ReadFile(filename)
+				Ω(err).ShouldNot(HaveOccurred())
+				written := string(b)
+				Ω(written).Should(ContainSubstring(simpleError))
+			})
+		})
+
+		Context("with a generic media type", func() {
+			BeforeEach(func() {
+				mediaType = "application/json"
+			})
+
+			It("writes the error constructor that accepts the response body", func() {
+				err := writer.Execute(data)
+				Ω(err).ShouldNot(HaveOccurred())
+				b, err := ioutil.ReadFile(filename)
+				Ω(err).ShouldNot(HaveOccurred())
+				written := string(b)
+				Ω(written).Should(ContainSubstring(mediaTypeError))
+			})
+		})
+	})
+})
+
+var _ = Describe("SecurityWriter", func() {
+	var writer *genapp.SecurityWriter
+	var workspace *codegen.Workspace
+	var filename string
+
+	BeforeEach(func() {
+		var err error
+		workspace, err = codegen.NewWorkspace("test")
+		Ω(err).ShouldNot(HaveOccurred())
+		pkg, err := workspace.NewPackage("controllers")
+		Ω(err).ShouldNot(HaveOccurred())
+		src := pkg.CreateSourceFile("test.go")
+		filename = src.Abs()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		writer, err = genapp.NewSecurityWriter(filename)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		workspace.Delete()
+	})
+
+	Context("with security schemes", func() {
+		var schemes []*design.SecuritySchemeDefinition
+
+		BeforeEach(func() {
+			schemes = []*design.SecuritySchemeDefinition{
+				{Kind: design.BasicAuthSecurityKind, Name: "basic", Description: "Account credentials"},
+				{Kind: design.JWTSecurityKind, Name: "jwt"},
+			}
+		})
+
+		It("writes the functions that mount the security middleware", func() {
+			err := writer.Execute(schemes)
+			Ω(err).ShouldNot(HaveOccurred())
+			b, err := ioutil.ReadFile(filename)
+			Ω(err).ShouldNot(HaveOccurred())
+			written := string(b)
+			Ω(written).Should(ContainSubstring(securityHooks))
+		})
+	})
+})
+
 var _ = Describe("HrefWriter", func() {
 	var writer *genapp.ResourcesWriter
 	var workspace *codegen.Workspace
@@ -639,6 +1265,112 @@ func NewListBottleContext(c *goa.Context) (*ListBottleContext, error) {
 	ctx := ListBottleContext{Context: c}
 	return &ctx, err
 }
+`
+
+	filesContext = `
+type ListBottleContext struct {
+	*goa.Context
+	Picture *multipart.FileHeader
+	Thumbnail *multipart.FileHeader
+}
+`
+
+	filesContextFactory = `
+func NewListBottleContext(c *goa.Context) (*ListBottleContext, error) {
+	var err error
+	ctx := ListBottleContext{Context: c}
+	fhPicture, err2 := c.FormFile("picture")
+	if err2 != nil {
+		err = goa.InvalidEncodingError(err2, err)
+	} else if fhPicture == nil {
+		err = goa.MissingFileError("picture", err)
+	} else {
+		ctx.Picture = fhPicture
+		err = goa.ValidateFile("picture", fhPicture, 1024, []string{"image/png", "image/jpeg"}, err)
+	}
+	fhThumbnail, err2 := c.FormFile("thumbnail")
+	if err2 != nil {
+		err = goa.InvalidEncodingError(err2, err)
+	} else if fhThumbnail != nil {
+		ctx.Thumbnail = fhThumbnail
+	}
+	return &ctx, err
+}
+`
+
+	streamContext = `
+type ListBottleContext struct {
+	*goa.Context
+	Body io.Reader
+}
+`
+
+	streamContextFactory = `
+func NewListBottleContext(c *goa.Context) (*ListBottleContext, error) {
+	var err error
+	ctx := ListBottleContext{Context: c}
+	ctx.Body = c.StreamBody(1024)
+	return &ctx, err
+}
+`
+
+	streamContextResponse = `
+func (ctx *ListBottleContext) OK(fn func(w io.Writer) error) error {
+	ctx.Header().Set("Content-Type", "application/octet-stream")
+	return ctx.RespondStream(200, fn)
+}
+`
+
+	eventsContextResponse = `
+func (ctx *ListBottleContext) OK(events <-chan *Event) error {
+	return ctx.RespondEvents(200, func(s *goa.EventStream) error {
+		for {
+			select {
+			case resp, ok := <-events:
+				if !ok {
+					return nil
+				}
+				r, err := resp.Dump()
+				if err != nil {
+					return fmt.Errorf("invalid event: %s", err)
+				}
+				if err := s.Send("", r); err != nil {
+					return err
+				}
+			case <-s.Done():
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
+`
+
+	webSocketContextResponse = `
+func (ctx *ListBottleContext) SwitchingProtocols(messages <-chan *Event) error {
+	return ctx.RespondWebSocket(func(ws *goa.WebSocket) error {
+		for {
+			select {
+			case resp, ok := <-messages:
+				if !ok {
+					return nil
+				}
+				r, err := resp.Dump()
+				if err != nil {
+					return fmt.Errorf("invalid message: %s", err)
+				}
+				if err := ws.Send(r); err != nil {
+					return err
+				}
+			case <-ws.Done():
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
 `
 
 	intContext = `
@@ -863,6 +1595,40 @@ func unmarshalListBottlePayload(ctx *goa.Context) error {
 	ctx.SetPayload(payload)
 	return nil
 }
+`
+
+	formUnmarshal = `
+func unmarshalListBottlePayload(ctx *goa.Context) error {
+	var form url.Values
+	if err := ctx.Service().DecodeRequest(ctx, &form); err != nil {
+		return err
+	}
+	var err error
+	payload := &ListBottlePayload{}
+	rawName := form.Get("name")
+	if rawName != "" {
+		payload.Name = rawName
+	}
+	if elemsYears := form["years"]; len(elemsYears) > 0 {
+		elemsYears2 := make([]int, len(elemsYears))
+		for i, rawElem := range elemsYears {
+			if elem, err2 := strconv.Atoi(rawElem); err2 == nil {
+				elemsYears2[i] = int(elem)
+			} else {
+				err = goa.InvalidParamTypeError("elem", rawElem, "integer", err)
+			}
+		}
+		payload.Years = elemsYears2
+	}
+	if err != nil {
+		return err
+	}
+	if err := payload.Validate(); err != nil {
+		return err
+	}
+	ctx.SetPayload(payload)
+	return nil
+}
 `
 
 	simpleController = `// BottlesController is the controller interface for the Bottles actions.
@@ -882,9 +1648,147 @@ type BottlesController interface {
 		}
 		return ctrl.list(ctx)
 	}
-	mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.HandleFunc("list", h, nil))
+	mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.HandleRoute(&goa.Route{Method: "GET", Path: "/accounts/:accountID/bottles", Resource: "bottles", Action: "list"}, "list", h, nil))
 	service.Info("mount", "ctrl", "Bottles", "action", "list", "route", "GET /accounts/:accountID/bottles")
 }
+`
+
+	simpleError = `// ErrNotFound creates a NotFound error, the corresponding response has status code 404.
+func ErrNotFound() error {
+	return &goa.ResponseError{
+		Name:        "NotFound",
+		Status:      404,
+	}
+}
+`
+
+	mediaTypeError = `// ErrNotFound creates a NotFound error, the corresponding response has status code 404.
+func ErrNotFound(resp []byte) error {
+	return &goa.ResponseError{
+		Name:        "NotFound",
+		Status:      404,
+		ContentType: "application/json",
+		Body:        resp,
+	}
+}
+`
+
+	securedMount = `func MountBottlesController(service goa.Service, ctrl BottlesController) {
+	var h goa.Handler
+	mux := service.ServeMux()
+	h = func(c *goa.Context) error {
+		ctx, err := NewListBottleContext(c)
+		if err != nil {
+			return goa.NewBadRequestError(err)
+		}
+		return ctrl.list(ctx)
+	}
+	h = goa.RequireSecurity("jwt", "read", "write")(h)
+	mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.HandleRoute(&goa.Route{Method: "GET", Path: "/accounts/:accountID/bottles", Resource: "bottles", Action: "list"}, "list", h, nil))
+`
+
+	encodingMount = `func MountBottlesController(service goa.Service, ctrl BottlesController) {
+	var h goa.Handler
+	mux := service.ServeMux()
+	h = func(c *goa.Context) error {
+		ctx, err := NewListBottleContext(c)
+		if err != nil {
+			return goa.NewBadRequestError(err)
+		}
+		return ctrl.list(ctx)
+	}
+	h = goa.Produces("application/vnd.bottle+json", "application/xml")(h)
+	mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.HandleRoute(&goa.Route{Method: "GET", Path: "/accounts/:accountID/bottles", Resource: "bottles", Action: "list", Consumes: []string{"application/json"}}, "list", h, nil))
+`
+
+	corsMount = `func MountBottlesController(service goa.Service, ctrl BottlesController) {
+	var h goa.Handler
+	mux := service.ServeMux()
+	cors := []*goa.CORSPolicy{
+		{
+			Origin:      "http://example.com",
+			Methods:     []string{"GET", "POST"},
+			Headers:     []string{"X-Shared-Secret"},
+			Exposed:     []string{"X-Time"},
+			MaxAge:      600,
+			Credentials: true,
+		},
+		{
+			Origin:      "*",
+		},
+	}
+	h = func(c *goa.Context) error {
+		ctx, err := NewListBottleContext(c)
+		if err != nil {
+			return goa.NewBadRequestError(err)
+		}
+		return ctrl.list(ctx)
+	}
+	h = goa.CORS(cors...)(h)
+	mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.HandleRoute(&goa.Route{Method: "GET", Path: "/accounts/:accountID/bottles", Resource: "bottles", Action: "list"}, "list", h, nil))
+	service.Info("mount", "ctrl", "Bottles", "action", "list", "route", "GET /accounts/:accountID/bottles")
+	if mux.Lookup("OPTIONS", "/accounts/:accountID/bottles") == nil {
+		mux.Handle("OPTIONS", "/accounts/:accountID/bottles", ctrl.HandleFunc("preflight", goa.CORSPreflight(cors...), nil))
+		service.Info("mount", "ctrl", "Bottles", "action", "preflight", "route", "OPTIONS /accounts/:accountID/bottles")
+	}
+}
+`
+
+	sharedPreflightMount = `	if mux.Lookup("OPTIONS", "/accounts/:accountID/bottles") == nil {
+		preflight := []*goa.CORSPolicy{
+		{
+			Origin:      "http://example.com",
+			Methods:     []string{"DELETE", "GET", "POST"},
+		},
+	}
+		mux.Handle("OPTIONS", "/accounts/:accountID/bottles", ctrl.HandleFunc("preflight", goa.CORSPreflight(preflight...), nil))
+		service.Info("mount", "ctrl", "Bottles", "action", "preflight", "route", "OPTIONS /accounts/:accountID/bottles")
+	}
+`
+
+	securityHooks = `// UseBasicMiddleware mounts the middleware that enforces the "basic" security scheme.
+// Account credentials
+func UseBasicMiddleware(service goa.Service, m goa.Middleware) {
+	service.SetSecurityMiddleware("basic", m)
+}
+
+// UseJwtMiddleware mounts the middleware that enforces the "jwt" security scheme.
+func UseJwtMiddleware(service goa.Service, m goa.Middleware) {
+	service.SetSecurityMiddleware("jwt", m)
+}
+`
+
+	timeoutMount = `func MountBottlesController(service goa.Service, ctrl BottlesController) {
+	var h goa.Handler
+	mux := service.ServeMux()
+	h = func(c *goa.Context) error {
+		ctx, err := NewListBottleContext(c)
+		if err != nil {
+			return goa.NewBadRequestError(err)
+		}
+		return ctrl.list(ctx)
+	}
+	h = goa.Timeout(5 * time.Second)(h)
+	mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.HandleRoute(&goa.Route{Method: "GET", Path: "/accounts/:accountID/bottles", Resource: "bottles", Action: "list"}, "list", h, nil))
+	service.Info("mount", "ctrl", "Bottles", "action", "list", "route", "GET /accounts/:accountID/bottles")
+}
+`
+
+	deprecatedMount = `func MountBottlesController(service goa.Service, ctrl BottlesController) {
+	var h goa.Handler
+	mux := service.ServeMux().Version("v1")
+	mux.Deprecate(&goa.Deprecation{Sunset: time.Date(2016, 6, 30, 0, 0, 0, 0, time.UTC)})
+	h = func(c *goa.Context) error {
+		ctx, err := NewListBottleContext(c)
+		if err != nil {
+			return goa.NewBadRequestError(err)
+		}
+		return ctrl.list(ctx)
+	}
+	h = goa.Deprecated(&goa.Deprecation{Link: "http://example.com/v2/bottles"})(h)
+	mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.HandleRoute(&goa.Route{Method: "GET", Path: "/accounts/:accountID/bottles", Resource: "bottles", Action: "list", Version: "v1"}, "list", h, nil))
+	service.Info("mount", "ctrl", "Bottles", "version", "v1", "action", "list", "route", "GET /accounts/:accountID/bottles")
+}
 `
 
 	multiController = `// BottlesController is the controller interface for the Bottles actions.
@@ -905,7 +1809,7 @@ type BottlesController interface {
 		}
 		return ctrl.list(ctx)
 	}
-	mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.HandleFunc("list", h, nil))
+	mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.HandleRoute(&goa.Route{Method: "GET", Path: "/accounts/:accountID/bottles", Resource: "bottles", Action: "list"}, "list", h, nil))
 	service.Info("mount", "ctrl", "Bottles", "action", "list", "route", "GET /accounts/:accountID/bottles")
 	h = func(c *goa.Context) error {
 		ctx, err := NewShowBottleContext(c)
@@ -914,7 +1818,7 @@ type BottlesController interface {
 		}
 		return ctrl.show(ctx)
 	}
-	mux.Handle("GET", "/accounts/:accountID/bottles/:id", ctrl.HandleFunc("show", h, nil))
+	mux.Handle("GET", "/accounts/:accountID/bottles/:id", ctrl.HandleRoute(&goa.Route{Method: "GET", Path: "/accounts/:accountID/bottles/:id", Resource: "bottles", Action: "show"}, "show", h, nil))
 	service.Info("mount", "ctrl", "Bottles", "action", "show", "route", "GET /accounts/:accountID/bottles/:id")
 }
 `
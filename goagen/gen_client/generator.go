@@ -61,6 +61,7 @@ func makeToolDir(g *Generator, apiName string) (toolDir string, err error) {
 func (g *Generator) generateMain(mainFile string, clientPkg string, funcs template.FuncMap, api *design.APIDefinition) error {
 	imports := []*codegen.ImportSpec{
 		codegen.SimpleImport("os"),
+		codegen.SimpleImport("github.com/raphael/goa"),
 		codegen.SimpleImport(clientPkg),
 		codegen.SimpleImport("gopkg.in/alecthomas/kingpin.v2"),
 	}
@@ -81,7 +82,7 @@ func (g *Generator) generateMain(mainFile string, clientPkg string, funcs templa
 		"Signers": Signers,
 		"Version": Version,
 	}
-	if err := file.ExecuteTemplate("main", mainTmpl, nil, data); err != nil {
+	if err := file.ExecuteTemplate("main", mainTmpl, funcs, data); err != nil {
 		return err
 	}
 
@@ -158,6 +159,7 @@ func (g *Generator) generateClient(clientFile string, clientPkg string, funcs te
 	imports := []*codegen.ImportSpec{
 		codegen.SimpleImport("net/http"),
 		codegen.SimpleImport("github.com/raphael/goa"),
+		codegen.SimpleImport("golang.org/x/net/context"),
 		codegen.SimpleImport("gopkg.in/alecthomas/kingpin.v2"),
 	}
 	if err := file.WriteHeader("", "client", imports); err != nil {
@@ -179,6 +181,7 @@ func (g *Generator) generateClientResources(clientPkg string, funcs template.Fun
 		codegen.SimpleImport("encoding/json"),
 		codegen.SimpleImport("fmt"),
 		codegen.SimpleImport("net/http"),
+		codegen.SimpleImport("github.com/raphael/goa"),
 	}
 
 	return api.IterateResources(func(res *design.ResourceDefinition) error {
@@ -225,12 +228,15 @@ func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error)
 		"nativeType":   codegen.GoNativeType,
 		"joinNames":    joinNames,
 		"join":         join,
+		"joinArgs":     joinArgs,
 		"toString":     toString,
 		"tempvar":      codegen.Tempvar,
 		"title":        strings.Title,
 		"flagType":     flagType,
 		"enumOptions":  enumOptions,
 		"defaultPath":  defaultPath,
+		"signer":       signer,
+		"versionParam": func() string { return api.MediaTypeVersionParam },
 	}
 	clientPkg, err := codegen.PackagePath(codegen.OutputDir)
 	if err != nil {
@@ -328,6 +334,26 @@ func join(att *design.AttributeDefinition) string {
 	return strings.Join(elems, ", ")
 }
 
+// joinArgs is a code generation helper function that generates the list of arguments passed to
+// a function whose signature was generated by join.
+func joinArgs(att *design.AttributeDefinition) string {
+	if att == nil {
+		return ""
+	}
+	obj := att.Type.ToObject()
+	elems := make([]string, len(obj))
+	i := 0
+	for n, a := range obj {
+		elems[i] = fmt.Sprintf("%s %s", n, codegen.GoNativeType(a.Type))
+		i++
+	}
+	sort.Strings(elems)
+	for i, e := range elems {
+		elems[i] = e[:strings.Index(e, " ")]
+	}
+	return strings.Join(elems, ", ")
+}
+
 // gotTypeRefExt computes the type reference for a type in a different package.
 func goTypeRefExt(t design.DataType, tabs int, pkg string) string {
 	ref := codegen.GoTypeRef(t, nil, tabs)
@@ -439,6 +465,31 @@ func defaultPath(action *design.ActionDefinition) string {
 	return ""
 }
 
+// signer returns the Go code that initializes the request signer of the given security scheme. The
+// names of the signer command line flags are prefixed with the scheme name so that the flags of
+// multiple schemes do not clash.
+func signer(scheme *design.SecuritySchemeDefinition) string {
+	prefix := scheme.Name + "-"
+	switch scheme.Kind {
+	case design.BasicAuthSecurityKind:
+		return fmt.Sprintf("&goa.BasicSigner{FlagPrefix: %q}", prefix)
+	case design.APIKeySecurityKind:
+		if scheme.In == "query" {
+			return fmt.Sprintf("&goa.APIKeySigner{Query: %q, FlagPrefix: %q}", scheme.ParamName, prefix)
+		}
+		return fmt.Sprintf("&goa.APIKeySigner{Header: %q, FlagPrefix: %q}", scheme.ParamName, prefix)
+	case design.JWTSecurityKind:
+		if scheme.In == "query" {
+			return fmt.Sprintf("&goa.JWTSigner{Query: %q, FlagPrefix: %q}", scheme.ParamName, prefix)
+		}
+		return fmt.Sprintf("&goa.JWTSigner{Header: %q, FlagPrefix: %q}", scheme.ParamName, prefix)
+	case design.OAuth2SecurityKind:
+		return fmt.Sprintf("&goa.OAuth2Signer{FlagPrefix: %q}", prefix)
+	default:
+		panic("no signer for security scheme " + scheme.Name) // bug
+	}
+}
+
 const mainTmpl = `
 // PrettyPrint is true if the tool output should be formatted for human consumption.
 var PrettyPrint bool
@@ -448,12 +499,16 @@ func main() {
 	app := kingpin.New("{{.API.Name}}-cli", "CLI client for the {{.API.Name}} service{{if .API.Docs}} ({{.API.Docs.URL}}){{end}}")
 	c := client.New()
 {{if .Signers}}	c.Signers = RegisterSigners(app)
+{{end}}{{range $name, $scheme := .API.SecuritySchemes}}{{$tmp := tempvar}}	{{$tmp}} := {{signer $scheme}}
+	{{$tmp}}.RegisterFlags(app)
+	c.{{goify $name true}}Signer = {{$tmp}}
 {{end}}	c.UserAgent = "{{.API.Name}}-cli/{{.Version}}"
 	app.Flag("scheme", "Set the requests scheme").Short('s'){{if .API.Schemes}}.Default("{{index .API.Schemes 0}}"){{end}}.StringVar(&c.Scheme)
 	app.Flag("host", "API hostname").Short('h'){{if .API.Host}}.Default("{{.API.Host}}"){{end}}.StringVar(&c.Host)
 	app.Flag("timeout", "Set the request timeout, defaults to 20s").Short('t').Default("20s").DurationVar(&c.Timeout)
 	app.Flag("dump", "Dump HTTP request and response.").BoolVar(&c.Dump)
-	app.Flag("pp", "Pretty print response body").BoolVar(&PrettyPrint)
+{{if .API.MediaTypeVersionParam}}	app.Flag("api-version", "API version sent in the request media types").StringVar(&c.Version)
+{{end}}	app.Flag("pp", "Pretty print response body").BoolVar(&PrettyPrint)
 	commands := RegisterCommands(app)
 	// Make "client-cli <action> [<resource>] --help" equivalent to
 	// "client-cli help <action> [<resource>]"
@@ -474,6 +529,29 @@ func main() {
 		kingpin.Fatalf("request failed: %s", err)
 	}
 	defer resp.Body.Close()
+	if resp.Header.Get("Deprecation") != "" {
+		// Let user know the endpoint may go away
+		warning := "warning: the endpoint is deprecated"
+		if sunset := resp.Header.Get("Sunset"); sunset != "" {
+			warning += " and may stop responding after " + sunset
+		}
+		if link := resp.Header.Get("Link"); link != "" {
+			warning += ", see " + strings.Trim(strings.SplitN(link, ";", 2)[0], " <>")
+		}
+		fmt.Fprintln(os.Stderr, warning)
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), goa.EventStreamContentType) {
+		// Print the server-sent events as they are received
+		events := goa.NewEventReader(resp.Body)
+		for {
+			ev, err := events.Next()
+			if err != nil {
+				break
+			}
+			fmt.Println(string(ev.Data))
+		}
+		os.Exit(0)
+	}
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		kingpin.Fatalf("failed to read body: %s", err)
@@ -550,7 +628,11 @@ const commandTypesTmpl = `{{$cmdName := goify (printf "%s%s%s" .Name (title .Par
 {{end}}		{{goify $name true}} {{nativeType $att.Type}}
 {{end}}{{end}}{{$headers := .Headers}}{{if $headers}}{{range $name, $att := $headers.Type.ToObject}}{{if $att.Description}}		// {{$att.Description}}
 {{end}}		{{goify $name true}} string
-{{end}}{{end}}	}
+{{end}}{{end}}{{if .ETag}}		// IfMatch is the ETag the resource must have for the request to succeed.
+		IfMatch string
+		// IfNoneMatch is the ETag of the representation known to the client.
+		IfNoneMatch string
+{{end}}	}
 `
 
 const commandsTmpl = `
@@ -566,7 +648,8 @@ func (cmd *{{$cmdName}}) Run(c *client.Client) (*http.Response, error) {
 	}
 {{end}}	return c.{{goify (printf "%s%s" .Action.Name (title .Resource.Name)) true}}(cmd.Path{{if .Action.Payload}}, {{if or .Action.Payload.Type.IsObject .Action.Payload.IsPrimitive}}&{{end}}payload{{else}}{{end}}{{/*
 	*/}}{{$params := joinNames .Action.QueryParams}}{{if $params}}, {{$params}}{{end}}{{/*
-	*/}}{{$headers := joinNames .Action.Headers}}{{if $headers}}, {{$headers}}{{end}})
+	*/}}{{$headers := joinNames .Action.Headers}}{{if $headers}}, {{$headers}}{{end}}{{/*
+	*/}}{{if .Action.ETag}}, &goa.Preconditions{IfMatch: cmd.IfMatch, IfNoneMatch: cmd.IfNoneMatch}{{end}})
 }
 
 // RegisterFlags registers the command flags with the command line.
@@ -581,7 +664,9 @@ func (cmd *{{$cmdName}}) RegisterFlags(cc *kingpin.CmdClause) {
 	*/}}{{if $headers.IsRequired $name}}.Required(){{end}}{{/*
 	*/}}{{if $header.DefaultValue}}.Default({{printf "%#v" $header.DefaultValue}}){{end}}{{/*
 	*/}}.StringVar(&cmd.{{goify $name true}})
-{{end}}{{end}}}
+{{end}}{{end}}{{if .Action.ETag}}	cc.Flag("if-match", "Send the request only if the resource ETag matches").StringVar(&cmd.IfMatch)
+	cc.Flag("if-none-match", "Respond with 304 Not Modified if the resource ETag matches").StringVar(&cmd.IfNoneMatch)
+{{end}}}
 `
 
 const clientsTmpl = `{{$payload := goify (printf "%s%sPayload" .Name (title .Parent.Name)) true}}{{if .Payload}}// {{$payload}} is the data structure used to initialize the {{.Parent.Name}} {{.Name}} request body.
@@ -590,7 +675,7 @@ type {{$payload}} {{gotypedef .Payload false "" 1 false}}
 {{end}}{{$funcName := goify (printf "%s%s" .Name (title .Parent.Name)) true}}{{$desc := .Description}}{{if $desc}}// {{$desc}}{{else}}// {{$funcName}} makes a request to the {{.Name}} action endpoint of the {{.Parent.Name}} resource{{end}}
 func (c *Client) {{$funcName}}(path string{{if .Payload}}, payload {{if .Payload.Type.IsObject}}*{{end}}{{$payload}}{{end}}{{/*
 	*/}}{{$params := join .QueryParams}}{{if $params}}, {{$params}}{{end}}{{/*
-	*/}}{{$headers := join .Headers}}{{if $headers}}, {{$headers}}{{end}}) (*http.Response, error) {
+	*/}}{{$headers := join .Headers}}{{if $headers}}, {{$headers}}{{end}}{{if .ETag}}, pre *goa.Preconditions{{end}}) (*http.Response, error) {
 	var body io.Reader
 {{if .Payload}}	b, err := json.Marshal(payload)
 	if err != nil {
@@ -612,15 +697,80 @@ func (c *Client) {{$funcName}}(path string{{if .Payload}}, payload {{if .Payload
 {{else}}{{$tmp := tempvar}}{{toString (goify $name false) $tmp $att}}
 	header.Set("{{$name}}", {{$tmp}})
 {{end}}{{end}}{{end}}	header.Set("Content-Type", "application/json")
-	return c.Client.Do(req)
+{{if .EventsResponse}}	header.Set("Accept", "text/event-stream")
+{{end}}{{with versionParam}}	if c.Version != "" {
+		header.Set("Content-Type", "application/json; {{.}}="+c.Version)
+		header.Set("Accept", "{{if $.EventsResponse}}text/event-stream{{else}}application/json{{end}}; {{.}}="+c.Version)
+	}
+{{end}}{{if .ETag}}	if pre != nil {
+		pre.SetHeaders(header)
+	}
+{{end}}{{with .EffectiveSecurity}}{{$signer := printf "%sSigner" (goify .Scheme.Name true)}}	if c.{{$signer}} != nil {
+		if err := c.{{$signer}}.Sign(req); err != nil {
+			return nil, fmt.Errorf("failed to sign request: %s", err)
+		}
+	}
+{{end}}	return c.Client.Do(req)
 }
-`
+{{$action := .}}{{with .EventsResponse}}
+// {{$funcName}}Events makes a request to the {{$action.Name}} action endpoint of the {{$action.Parent.Name}} resource and
+// returns a reader for the server-sent events streamed in the response body. The caller must close the reader.
+func (c *Client) {{$funcName}}Events(path string{{if $action.Payload}}, payload {{if $action.Payload.Type.IsObject}}*{{end}}{{$payload}}{{end}}{{/*
+	*/}}{{$params := join $action.QueryParams}}{{if $params}}, {{$params}}{{end}}{{/*
+	*/}}{{$headers := join $action.Headers}}{{if $headers}}, {{$headers}}{{end}}) (*goa.EventReader, error) {
+	resp, err := c.{{$funcName}}(path{{if $action.Payload}}, payload{{end}}{{/*
+	*/}}{{$params := joinArgs $action.QueryParams}}{{if $params}}, {{$params}}{{end}}{{/*
+	*/}}{{$headers := joinArgs $action.Headers}}{{if $headers}}, {{$headers}}{{end}}{{if $action.ETag}}, nil{{end}})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != {{.Status}} {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return goa.NewEventReader(resp.Body), nil
+}
+{{end}}{{if .WebSocketResponse}}
+// {{$funcName}}WebSocket opens a WebSocket connection to the {{.Name}} action endpoint of the {{.Parent.Name}} resource.
+// The caller must close the connection.
+func (c *Client) {{$funcName}}WebSocket(path string{{/*
+	*/}}{{$params := join .QueryParams}}{{if $params}}, {{$params}}{{end}}{{/*
+	*/}}{{$headers := join .Headers}}{{if $headers}}, {{$headers}}{{end}}) (*goa.WebSocket, error) {
+	u := url.URL{Host: c.Host, Scheme: "ws", Path: path}
+	if c.Scheme == "https" {
+		u.Scheme = "wss"
+	}
+{{$params := .QueryParams}}{{if $params}}{{if gt (len $params.Type.ToObject) 0}}	values := u.Query()
+{{range $name, $att := $params.Type.ToObject}}{{if (eq $att.Type.Kind 4)}}	values.Set("{{$name}}", {{goify $name false}})
+{{else}}{{$tmp := tempvar}}{{toString (goify $name false) $tmp $att}}
+	values.Set("{{$name}}", {{$tmp}})
+{{end}}{{end}}	u.RawQuery = values.Encode()
+{{end}}{{end}}	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+{{$headers := .Headers}}{{if $headers}}	header := req.Header
+{{range $name, $att := $headers.Type.ToObject}}{{if (eq $att.Type.Kind 4)}}	header.Set("{{$name}}", {{goify $name false}})
+{{else}}{{$tmp := tempvar}}{{toString (goify $name false) $tmp $att}}
+	header.Set("{{$name}}", {{$tmp}})
+{{end}}{{end}}{{end}}{{with .EffectiveSecurity}}{{$signer := printf "%sSigner" (goify .Scheme.Name true)}}	if c.{{$signer}} != nil {
+		if err := c.{{$signer}}.Sign(req); err != nil {
+			return nil, fmt.Errorf("failed to sign request: %s", err)
+		}
+	}
+{{end}}	return goa.DialWebSocket(req.URL.String(), req.Header)
+}
+{{end}}`
 
 const clientTmpl = `type (
 	// Client is the {{.Name}} service client.
 	Client struct {
 		*goa.Client
-	}
+{{range $name, $scheme := .SecuritySchemes}}		// {{goify $name true}}Signer signs the requests made to actions secured by the "{{$name}}" security scheme.
+		{{goify $name true}}Signer goa.Signer
+{{end}}{{if .MediaTypeVersionParam}}		// Version is the API version sent in the "{{.MediaTypeVersionParam}}" parameter of the request media types.
+		Version string
+{{end}}	}
 
 	// ActionCommand represents a single action command as defined on the command line.
 	// Each command is associated with a generated client method and contains the logic to
@@ -637,6 +787,15 @@ const clientTmpl = `type (
 func New() *Client {
 	return &Client{Client: goa.NewClient()}
 }
+
+// WithContext returns a copy of the client that propagates the trace of the given context to the
+// requests it makes. Use it to call the API while handling a request, e.g. with the goa.Context
+// given to the handler.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	cc := *c
+	cc.Client = c.Client.WithContext(ctx)
+	return &cc
+}
 `
 
 // Takes map[string][]*design.ActionDefinition as input
@@ -98,8 +98,178 @@ var _ = Describe("Generate", func() {
 			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "main.go"))
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(len(strings.Split(string(content), "\n"))).Should(BeNumerically(">=", 16))
+			Ω(string(content)).Should(ContainSubstring(`if resp.Header.Get("Deprecation") != "" {`))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring("func (c *Client) WithContext(ctx context.Context) *Client {"))
 			_, err = gexec.Build(filepath.Join(testgenPackagePath, "client", "testapi-cli"))
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 	})
+
+	Context("with multiple security schemes", func() {
+		BeforeEach(func() {
+			dsl.InitDesign()
+			dsl.API("testapi", func() {
+				dsl.Host("localhost")
+				dsl.BasicAuthSecurity("basic")
+				dsl.APIKeySecurity("key", func() {
+					dsl.Header("X-API-Key")
+				})
+				dsl.APIKeySecurity("partner", func() {
+					dsl.Query("partner_key")
+				})
+				dsl.JWTSecurity("jwt", func() {
+					dsl.Query("token")
+				})
+			})
+			Ω(dsl.RunDSL()).ShouldNot(HaveOccurred())
+		})
+
+		It("prefixes the signer flags with the scheme names", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "main.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring(`&goa.BasicSigner{FlagPrefix: "basic-"}`))
+			Ω(string(content)).Should(ContainSubstring(`&goa.APIKeySigner{Header: "X-API-Key", FlagPrefix: "key-"}`))
+			Ω(string(content)).Should(ContainSubstring(`&goa.APIKeySigner{Query: "partner_key", FlagPrefix: "partner-"}`))
+			Ω(string(content)).Should(ContainSubstring(`&goa.JWTSigner{Query: "token", FlagPrefix: "jwt-"}`))
+		})
+	})
+
+	Context("with an action streaming server-sent events", func() {
+		BeforeEach(func() {
+			dsl.InitDesign()
+			dsl.API("testapi", func() {
+				dsl.Host("localhost")
+			})
+			dsl.MediaType("application/vnd.goa.test.event", func() {
+				dsl.Attributes(func() {
+					dsl.Attribute("value", design.Integer)
+				})
+				dsl.View("default", func() {
+					dsl.Attribute("value")
+				})
+			})
+			dsl.Resource("dashboard", func() {
+				dsl.Action("watch", func() {
+					dsl.Routing(dsl.GET("/events"))
+					dsl.Params(func() {
+						dsl.Param("since", design.Integer)
+					})
+					dsl.Response(dsl.OK, func() {
+						dsl.Media("application/vnd.goa.test.event")
+						dsl.ServerSentEvents()
+					})
+				})
+			})
+			Ω(dsl.RunDSL()).ShouldNot(HaveOccurred())
+		})
+
+		It("generates a method returning an event reader", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "dashboard.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring(`header.Set("Accept", "text/event-stream")`))
+			Ω(string(content)).Should(ContainSubstring("func (c *Client) WatchDashboardEvents(path string, since int) (*goa.EventReader, error) {"))
+			Ω(string(content)).Should(ContainSubstring("resp, err := c.WatchDashboard(path, since)"))
+		})
+	})
+
+	Context("with a WebSocket action", func() {
+		BeforeEach(func() {
+			dsl.InitDesign()
+			dsl.API("testapi", func() {
+				dsl.Host("localhost")
+			})
+			dsl.MediaType("application/vnd.goa.test.event", func() {
+				dsl.Attributes(func() {
+					dsl.Attribute("value", design.Integer)
+				})
+				dsl.View("default", func() {
+					dsl.Attribute("value")
+				})
+			})
+			dsl.Resource("dashboard", func() {
+				dsl.Action("live", func() {
+					dsl.Routing(dsl.GET("/live"))
+					dsl.Params(func() {
+						dsl.Param("since", design.Integer)
+					})
+					dsl.Response(dsl.SwitchingProtocols, func() {
+						dsl.Media("application/vnd.goa.test.event")
+						dsl.WebSocket()
+					})
+				})
+			})
+			Ω(dsl.RunDSL()).ShouldNot(HaveOccurred())
+		})
+
+		It("generates a method opening the WebSocket connection", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "dashboard.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring("func (c *Client) LiveDashboardWebSocket(path string, since int) (*goa.WebSocket, error) {"))
+			Ω(string(content)).Should(ContainSubstring(`u := url.URL{Host: c.Host, Scheme: "ws", Path: path}`))
+			Ω(string(content)).Should(ContainSubstring("return goa.DialWebSocket(req.URL.String(), req.Header)"))
+		})
+	})
+
+	Context("with an action that supports ETags", func() {
+		BeforeEach(func() {
+			dsl.InitDesign()
+			dsl.API("testapi", func() {
+				dsl.Host("localhost")
+			})
+			dsl.Resource("bottle", func() {
+				dsl.Action("show", func() {
+					dsl.Routing(dsl.GET("/:id"))
+					dsl.ETag()
+					dsl.Response(dsl.OK)
+				})
+			})
+			Ω(dsl.RunDSL()).ShouldNot(HaveOccurred())
+		})
+
+		It("generates methods and commands that make conditional requests", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "bottle.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring("func (c *Client) ShowBottle(path string, pre *goa.Preconditions) (*http.Response, error) {"))
+			Ω(string(content)).Should(ContainSubstring("pre.SetHeaders(header)"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "commands.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring("&goa.Preconditions{IfMatch: cmd.IfMatch, IfNoneMatch: cmd.IfNoneMatch}"))
+			Ω(string(content)).Should(ContainSubstring(`cc.Flag("if-none-match"`))
+		})
+	})
+
+	Context("with an API using media type versioning", func() {
+		BeforeEach(func() {
+			dsl.InitDesign()
+			dsl.API("testapi", func() {
+				dsl.MediaTypeVersioning("version")
+			})
+			dsl.Resource("bottle", func() {
+				dsl.Action("list", func() {
+					dsl.Routing(dsl.GET("/bottles"))
+					dsl.Response(dsl.OK)
+				})
+			})
+			Ω(dsl.RunDSL()).ShouldNot(HaveOccurred())
+		})
+
+		It("sends the version in the request media types", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "client", "client.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring("Version string"))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "bottle.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring(`header.Set("Accept", "application/json; version="+c.Version)`))
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "client", "testapi-cli", "main.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring(`app.Flag("api-version"`))
+		})
+	})
 })
@@ -317,7 +317,49 @@ const jsFuncsT = `{{$params := params .Action}}
     }
     return client(cfg);
   }
-`
+{{if and .Action.EventsResponse (eq (index .Action.Routes 0).Verb "GET")}}
+  // {{$name}}Events listens to the server-sent events streamed by the {{.Action.Name}} action of the {{.Action.Parent.Name}} resource.
+  // path is the request path, the format is "{{(index .Action.Routes 0).FullPath .Version}}"
+  {{if $params}}// {{join $params ", "}} {{if gt (len $params) 1}}are{{else}}is{{end}} used to build the request query string.
+  {{end}}// onEvent is called with the data of each event parsed as JSON.
+  // This function returns the EventSource object receiving the events, call its close method to stop listening.
+  client.{{$name}}Events = function (path{{if $params}}, {{join $params ", "}}{{end}}, onEvent) {
+    var url = urlPrefix + path;
+{{if $params}}    var query = [];
+{{range $params}}    if ({{.}} !== undefined && {{.}} !== null) {
+      query.push('{{.}}=' + encodeURIComponent({{.}}));
+    }
+{{end}}    if (query.length > 0) {
+      url += '?' + query.join('&');
+    }
+{{end}}    var source = new EventSource(url);
+    source.onmessage = function (e) {
+      onEvent(JSON.parse(e.data));
+    };
+    return source;
+  }
+{{end}}{{if .Action.WebSocketResponse}}
+  // {{$name}}WebSocket opens a WebSocket connection to the {{.Action.Name}} action of the {{.Action.Parent.Name}} resource.
+  // path is the request path, the format is "{{(index .Action.Routes 0).FullPath .Version}}"
+  {{if $params}}// {{join $params ", "}} {{if gt (len $params) 1}}are{{else}}is{{end}} used to build the request query string.
+  {{end}}// onMessage is called with the data of each message parsed as JSON.
+  // This function returns the WebSocket object receiving the messages, call its close method to close the connection.
+  client.{{$name}}WebSocket = function (path{{if $params}}, {{join $params ", "}}{{end}}, onMessage) {
+    var url = urlPrefix.replace(/^http/, 'ws') + path;
+{{if $params}}    var query = [];
+{{range $params}}    if ({{.}} !== undefined && {{.}} !== null) {
+      query.push('{{.}}=' + encodeURIComponent({{.}}));
+    }
+{{end}}    if (query.length > 0) {
+      url += '?' + query.join('&');
+    }
+{{end}}    var socket = new WebSocket(url);
+    socket.onmessage = function (e) {
+      onMessage(JSON.parse(e.data));
+    };
+    return socket;
+  }
+{{end}}`
 
 const exampleT = `<!doctype html>
 <html>
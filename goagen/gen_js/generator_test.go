@@ -97,4 +97,75 @@ var _ = Describe("Generate", func() {
 			Ω(len(strings.Split(string(content), "\n"))).Should(BeNumerically(">=", 13))
 		})
 	})
+
+	Context("with an action streaming server-sent events", func() {
+		BeforeEach(func() {
+			dsl.InitDesign()
+			dsl.API("testapi", func() {
+				dsl.Host("localhost")
+			})
+			dsl.MediaType("application/vnd.goa.test.event", func() {
+				dsl.Attributes(func() {
+					dsl.Attribute("value", design.Integer)
+				})
+				dsl.View("default", func() {
+					dsl.Attribute("value")
+				})
+			})
+			dsl.Resource("dashboard", func() {
+				dsl.Action("watch", func() {
+					dsl.Routing(dsl.GET("/events"))
+					dsl.Response(dsl.OK, func() {
+						dsl.Media("application/vnd.goa.test.event")
+						dsl.ServerSentEvents()
+					})
+				})
+			})
+			Ω(dsl.RunDSL()).ShouldNot(HaveOccurred())
+		})
+
+		It("generates a function listening to the events", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "js", "client.js"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring("client.watchDashboardEvents = function (path, onEvent) {"))
+			Ω(string(content)).Should(ContainSubstring("var source = new EventSource(url);"))
+		})
+	})
+
+	Context("with a WebSocket action", func() {
+		BeforeEach(func() {
+			dsl.InitDesign()
+			dsl.API("testapi", func() {
+				dsl.Host("localhost")
+			})
+			dsl.MediaType("application/vnd.goa.test.event", func() {
+				dsl.Attributes(func() {
+					dsl.Attribute("value", design.Integer)
+				})
+				dsl.View("default", func() {
+					dsl.Attribute("value")
+				})
+			})
+			dsl.Resource("dashboard", func() {
+				dsl.Action("live", func() {
+					dsl.Routing(dsl.GET("/live"))
+					dsl.Response(dsl.SwitchingProtocols, func() {
+						dsl.Media("application/vnd.goa.test.event")
+						dsl.WebSocket()
+					})
+				})
+			})
+			Ω(dsl.RunDSL()).ShouldNot(HaveOccurred())
+		})
+
+		It("generates a function opening the WebSocket connection", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "js", "client.js"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring("client.liveDashboardWebSocket = function (path, onMessage) {"))
+			Ω(string(content)).Should(ContainSubstring("var url = urlPrefix.replace(/^http/, 'ws') + path;"))
+			Ω(string(content)).Should(ContainSubstring("var socket = new WebSocket(url);"))
+		})
+	})
 })
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 	"unicode"
@@ -58,6 +59,15 @@ func newControllerVersion(ctrl *design.ResourceDefinition, version string) *cont
 	}
 }
 
+// encoderTemplateData contains the information required to generate the registration of an
+// encoder or decoder implemented by a Go package.
+type encoderTemplateData struct {
+	PackagePath string   // Path to the Go package, e.g. "github.com/example/msgpack"
+	PackageName string   // Name used to refer to the package in the generated code
+	Function    string   // Name of the package function that creates the factory
+	MIMETypes   []string // Content types handled by the encoder or decoder
+}
+
 // Generate produces the skeleton main.
 func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error) {
 	go utils.Catch(nil, func() { g.Cleanup() })
@@ -96,6 +106,7 @@ func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error)
 		swaggerPkg := path.Join(outPkg, "swagger")
 		imports := []*codegen.ImportSpec{
 			codegen.SimpleImport("github.com/raphael/goa"),
+			codegen.SimpleImport("github.com/raphael/goa/middleware"),
 			codegen.SimpleImport(appPkg),
 			codegen.SimpleImport(swaggerPkg),
 			codegen.NewImport("log", "gopkg.in/inconshreveable/log15.v2"),
@@ -104,10 +115,22 @@ func (g *Generator) Generate(api *design.APIDefinition) (_ []string, err error)
 			jsonSchemaPkg := path.Join(outPkg, "schema")
 			imports = append(imports, codegen.SimpleImport(jsonSchemaPkg))
 		}
+		encoderPkgs := make(map[string]string)
+		encoders, decoders := encoderData(api, encoderPkgs)
+		pkgPaths := make([]string, 0, len(encoderPkgs))
+		for p := range encoderPkgs {
+			pkgPaths = append(pkgPaths, p)
+		}
+		sort.Strings(pkgPaths)
+		for _, p := range pkgPaths {
+			imports = append(imports, codegen.NewImport(encoderPkgs[p], p))
+		}
 		file.WriteHeader("", "main", imports)
 		data := map[string]interface{}{
-			"Name": AppName,
-			"API":  api,
+			"Name":     AppName,
+			"API":      api,
+			"Encoders": encoders,
+			"Decoders": decoders,
 		}
 		if err = file.ExecuteTemplate("main", mainT, funcs, data); err != nil {
 			return nil, err
@@ -250,15 +273,106 @@ func snakeCase(name string) string {
 	return b.String()
 }
 
+// encoderData returns the encoders and decoders implemented by Go packages that main registers
+// with the service. pkgNames maps the package paths to the names used to refer to the packages in
+// the generated code, encoderData adds the packages to it.
+func encoderData(api *design.APIDefinition, pkgNames map[string]string) ([]*encoderTemplateData, []*encoderTemplateData) {
+	produces := append([]*design.EncodingDefinition{}, api.Produces...)
+	consumes := append([]*design.EncodingDefinition{}, api.Consumes...)
+	api.IterateResources(func(r *design.ResourceDefinition) error {
+		produces = append(produces, r.Produces...)
+		consumes = append(consumes, r.Consumes...)
+		return r.IterateActions(func(a *design.ActionDefinition) error {
+			produces = append(produces, a.Produces...)
+			consumes = append(consumes, a.Consumes...)
+			return nil
+		})
+	})
+	return buildEncoderData(produces, pkgNames), buildEncoderData(consumes, pkgNames)
+}
+
+// buildEncoderData merges the encodings implemented by the same package function.
+func buildEncoderData(encs []*design.EncodingDefinition, pkgNames map[string]string) []*encoderTemplateData {
+	var res []*encoderTemplateData
+	for _, enc := range encs {
+		if enc.PackagePath == "" {
+			continue
+		}
+		var data *encoderTemplateData
+		for _, d := range res {
+			if d.PackagePath == enc.PackagePath && d.Function == enc.FactoryFunction() {
+				data = d
+				break
+			}
+		}
+		if data == nil {
+			data = &encoderTemplateData{
+				PackagePath: enc.PackagePath,
+				PackageName: encoderPackageName(enc.PackagePath, pkgNames),
+				Function:    enc.FactoryFunction(),
+			}
+			res = append(res, data)
+		}
+	mimes:
+		for _, m := range enc.MIMETypes {
+			for _, e := range data.MIMETypes {
+				if e == m {
+					continue mimes
+				}
+			}
+			data.MIMETypes = append(data.MIMETypes, m)
+		}
+	}
+	return res
+}
+
+// encoderPackageName returns the name used to refer to the package with the given path in the
+// generated code. The name is derived from the last element of the path and does not clash with
+// the names of the other imported packages.
+func encoderPackageName(pkgPath string, pkgNames map[string]string) string {
+	if name, ok := pkgNames[pkgPath]; ok {
+		return name
+	}
+	base := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r - 'A' + 'a'
+		}
+		return -1
+	}, filepath.Base(pkgPath))
+	if base == "" || base[0] >= '0' && base[0] <= '9' {
+		base = "enc" + base
+	}
+	taken := map[string]bool{"goa": true, "middleware": true, "app": true, "swagger": true, "schema": true, "log": true}
+	for _, n := range pkgNames {
+		taken[n] = true
+	}
+	name := base
+	for i := 2; taken[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	pkgNames[pkgPath] = name
+	return name
+}
+
 const mainT = `
 func main() {
 	// Create service
 	service := goa.New("{{.Name}}")
-
+{{if eq .API.Router "tree"}}	service.SetServeMux(goa.NewMuxWithRouter(goa.NewTreeRouter))
+{{end}}{{with .API.MediaTypeVersionParam}}	if mux, ok := service.ServeMux().(*goa.DefaultMux); ok {
+		// Read the API version from the request media types
+		mux.SelectVersion(goa.MediaTypeSelectVersionFunc("{{.}}"))
+	}
+{{end}}{{range .Decoders}}	service.SetDecoder({{.PackageName}}.{{.Function}}(), false{{range .MIMETypes}}, "{{.}}"{{end}})
+{{end}}{{range .Encoders}}	service.SetEncoder({{.PackageName}}.{{.Function}}(), false{{range .MIMETypes}}, "{{.}}"{{end}})
+{{end}}
 	// Setup middleware
 	service.Use(middleware.RequestID())
 	service.Use(middleware.LogRequest())
-	service.Use(middleware.Recover())
+	service.Use(goa.Recover())
 {{$api := .API}}
 {{range $name, $res := $api.Resources}}{{if $res.SupportsNoVersion}}{{$name := goify $res.Name true}}	// Mount "{{$res.Name}}" controller
 	{{$tmp := tempvar}}{{$tmp}} := New{{$name}}Controller(service)
@@ -102,4 +102,59 @@ var _ = Describe("Generate", func() {
 			Ω(err).ShouldNot(HaveOccurred())
 		})
 	})
+
+	Context("with an API using the tree router", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				APIVersionDefinition: &design.APIVersionDefinition{Name: "test api"},
+				Router:               "tree",
+			}
+		})
+
+		It("sets up the service mux with the tree router", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "main.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring("service.SetServeMux(goa.NewMuxWithRouter(goa.NewTreeRouter))"))
+		})
+	})
+
+	Context("with encoders and decoders implemented by Go packages", func() {
+		BeforeEach(func() {
+			msgpack := &design.EncodingDefinition{
+				MIMETypes:   []string{"application/msgpack"},
+				PackagePath: "github.com/example/msgpack",
+			}
+			design.Design = &design.APIDefinition{
+				APIVersionDefinition: &design.APIVersionDefinition{Name: "test api"},
+				Produces:             []*design.EncodingDefinition{msgpack},
+				Consumes:             []*design.EncodingDefinition{msgpack},
+			}
+		})
+
+		It("registers them with the service once", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "main.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(content)).Should(ContainSubstring(`"github.com/example/msgpack"`))
+			Ω(strings.Count(string(content), `service.SetDecoder(msgpack.NewFactory(), false, "application/msgpack")`)).Should(Equal(1))
+			Ω(strings.Count(string(content), `service.SetEncoder(msgpack.NewFactory(), false, "application/msgpack")`)).Should(Equal(1))
+		})
+	})
+
+	Context("with an API using media type versioning", func() {
+		BeforeEach(func() {
+			design.Design = &design.APIDefinition{
+				APIVersionDefinition: &design.APIVersionDefinition{Name: "test api", MediaTypeVersionParam: "version"},
+			}
+		})
+
+		It("selects the version using the request media types once", func() {
+			Ω(genErr).Should(BeNil())
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "main.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(strings.Count(string(content), "SelectVersion(")).Should(Equal(1))
+			Ω(string(content)).Should(ContainSubstring(`mux.SelectVersion(goa.MediaTypeSelectVersionFunc("version"))`))
+		})
+	})
 })
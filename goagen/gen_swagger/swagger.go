@@ -3,6 +3,8 @@ package genswagger
 import (
 	"encoding/json"
 	"fmt"
+	"mime"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -207,7 +209,7 @@ type (
 		Name string `json:"name,omitempty"`
 		// In is the location of the API key when type is "apiKey".
 		// Valid values are "query" or "header".
-		In string `json:"in"`
+		In string `json:"in,omitempty"`
 		// Flow is the flow used by the OAuth2 security scheme when type is "oauth2"
 		// Valid values are "implicit", "password", "application" or "accessCode".
 		Flow string `json:"flow,omitempty"`
@@ -215,14 +217,9 @@ type (
 		AuthorizationURL string `json:"authorizationUrl,omitempty"`
 		// TokenURL  is the token URL to be used for this flow.
 		TokenURL string `json:"tokenUrl,omitempty"`
-		// Scopes list the  available scopes for the OAuth2 security scheme.
-		Scopes map[string]*Scope `json:"scopes,omitempty"`
-	}
-
-	// Scope corresponds to an available scope for an OAuth2 security scheme.
-	Scope struct {
-		// Description for scope
-		Description string `json:"description,omitempty"`
+		// Scopes list the available scopes for the OAuth2 security scheme indexed by name.
+		// The values are the scope descriptions.
+		Scopes map[string]string `json:"scopes,omitempty"`
 	}
 
 	// ExternalDocs allows referencing an external resource for extended documentation.
@@ -311,12 +308,22 @@ func New(api *design.APIDefinition) (*Swagger, error) {
 		BasePath:     api.BasePath,
 		Paths:        make(map[string]*Path),
 		Schemes:      api.Schemes,
-		Consumes:     []string{"application/json"},
-		Produces:     []string{"application/json"},
+		Consumes:     mimeTypes(api.Consumes),
+		Produces:     mimeTypes(api.Produces),
 		Parameters:   paramMap,
 		Tags:         tags,
 		ExternalDocs: docsFromDefinition(api.Docs),
 	}
+	api.IterateSecuritySchemes(func(scheme *design.SecuritySchemeDefinition) error {
+		if s.SecurityDefinitions == nil {
+			s.SecurityDefinitions = make(map[string]*SecurityDefinition)
+		}
+		s.SecurityDefinitions[scheme.Name] = securityDefinitionFromDefinition(scheme)
+		return nil
+	})
+	if api.Security != nil && !api.Security.IsNone() {
+		s.Security = securityFromDefinition(api.Security)
+	}
 
 	err = api.IterateResponses(func(r *design.ResponseDefinition) error {
 		res, err := responseSpecFromDefinition(s, api, r)
@@ -502,6 +509,24 @@ func headersFromDefinition(headers *design.AttributeDefinition) (map[string]*Hea
 	return res, nil
 }
 
+// deprecated returns true if the action or its resource is deprecated or if all the API versions
+// that expose the action are.
+func deprecated(api *design.APIDefinition, action *design.ActionDefinition) bool {
+	if action.EffectiveDeprecation() != nil {
+		return true
+	}
+	versions := action.Parent.Versions()
+	if len(versions) == 0 {
+		return false
+	}
+	for _, v := range versions {
+		if ver, ok := api.APIVersions[v]; !ok || ver.Deprecation == nil {
+			return false
+		}
+	}
+	return true
+}
+
 func buildPathFromDefinition(s *Swagger, api *design.APIDefinition, route *design.RouteDefinition) error {
 	action := route.Parent
 	tagNames, err := tagNamesFromDefinition([]design.MetadataDefinition{action.Parent.Metadata, action.Metadata})
@@ -520,7 +545,9 @@ func buildPathFromDefinition(s *Swagger, api *design.APIDefinition, route *desig
 		}
 		responses[strconv.Itoa(r.Status)] = resp
 	}
-	if action.Payload != nil {
+	if action.Form != "" {
+		params = append(params, formParamsFromDefinition(action)...)
+	} else if action.Payload != nil {
 		payloadSchema := genschema.TypeSchema(api, action.Payload)
 		pp := &Parameter{
 			Name:        "payload",
@@ -546,17 +573,26 @@ func buildPathFromDefinition(s *Swagger, api *design.APIDefinition, route *desig
 	if len(schemes) == 0 {
 		schemes = api.Schemes
 	}
+	security := action.Security
+	if security == nil {
+		security = action.Parent.Security
+	}
+	produces := responseMIMETypes(action, orDefaultMIMETypes(action.EffectiveProduces()))
+	if action.EventsResponse() != nil {
+		produces = append(produces, design.EventStreamContentType)
+	}
 	operation := &Operation{
 		Tags:         tagNames,
 		Description:  action.Description,
 		ExternalDocs: docsFromDefinition(action.Docs),
 		OperationID:  operationID,
-		Consumes:     []string{"application/json"},
-		Produces:     []string{"application/json"},
+		Consumes:     orDefaultMIMETypes(action.EffectiveConsumes()),
+		Produces:     produces,
 		Parameters:   params,
 		Responses:    responses,
 		Schemes:      schemes,
-		Deprecated:   false,
+		Deprecated:   deprecated(api, action),
+		Security:     securityFromDefinition(security),
 	}
 	key := design.WildcardRegex.ReplaceAllStringFunc(
 		route.FullPath(design.Design.APIVersionDefinition),
@@ -593,6 +629,43 @@ func buildPathFromDefinition(s *Swagger, api *design.APIDefinition, route *desig
 	return nil
 }
 
+func securityDefinitionFromDefinition(scheme *design.SecuritySchemeDefinition) *SecurityDefinition {
+	def := &SecurityDefinition{Description: scheme.Description}
+	switch scheme.Kind {
+	case design.BasicAuthSecurityKind:
+		def.Type = "basic"
+	case design.APIKeySecurityKind, design.JWTSecurityKind:
+		// Swagger 2.0 has no specific type for JWT, describe the token location instead.
+		def.Type = "apiKey"
+		def.Name = scheme.ParamName
+		def.In = scheme.In
+	case design.OAuth2SecurityKind:
+		def.Type = "oauth2"
+		def.Flow = scheme.Flow
+		def.AuthorizationURL = scheme.AuthorizationURL
+		def.TokenURL = scheme.TokenURL
+		def.Scopes = scheme.Scopes
+	}
+	return def
+}
+
+// securityFromDefinition returns the security requirement object for the given requirement.
+// NoSecurity produces a requirement with an empty object which makes authentication optional.
+func securityFromDefinition(sec *design.SecurityDefinition) []map[string][]string {
+	if sec == nil {
+		return nil
+	}
+	if sec.IsNone() {
+		return []map[string][]string{{}}
+	}
+	// Swagger only allows listing scopes for OAuth2 schemes.
+	scopes := []string{}
+	if sec.Scheme.Kind == design.OAuth2SecurityKind {
+		scopes = append(scopes, sec.Scopes...)
+	}
+	return []map[string][]string{{sec.Scheme.Name: scopes}}
+}
+
 func docsFromDefinition(docs *design.DocsDefinition) *ExternalDocs {
 	if docs == nil {
 		return nil
@@ -712,3 +785,87 @@ func initValidations(attr *design.AttributeDefinition, def interface{}) {
 		}
 	}
 }
+
+// formParamsFromDefinition returns the formData parameters that describe the fields of the action
+// form payload and the uploaded files.
+func formParamsFromDefinition(action *design.ActionDefinition) []*Parameter {
+	var res []*Parameter
+	if action.Payload != nil {
+		payload := action.Payload.AttributeDefinition
+		payload.Type.ToObject().IterateAttributes(func(n string, at *design.AttributeDefinition) error {
+			param := &Parameter{
+				Name:        n,
+				Default:     at.DefaultValue,
+				Description: at.Description,
+				Required:    payload.IsRequired(n),
+				In:          "formData",
+				Type:        at.Type.Name(),
+			}
+			if at.Type.IsArray() {
+				param.Items = itemsFromDefinition(at)
+				param.CollectionFormat = "multi"
+			}
+			initValidations(at, param)
+			res = append(res, param)
+			return nil
+		})
+	}
+	for _, f := range action.Files {
+		res = append(res, &Parameter{
+			Name:        f.Name,
+			Description: f.Description,
+			Required:    f.Required,
+			In:          "formData",
+			Type:        "file",
+		})
+	}
+	return res
+}
+
+// mimeTypes returns the content types listed by the given encodings, "application/json" if there
+// are none.
+func mimeTypes(encs []*design.EncodingDefinition) []string {
+	var res []string
+	for _, enc := range encs {
+		res = append(res, enc.MIMETypes...)
+	}
+	return orDefaultMIMETypes(res)
+}
+
+// responseMIMETypes returns the given content types followed by the media types of the action
+// responses that render a media type and are not already listed.
+func responseMIMETypes(action *design.ActionDefinition, mimeTypes []string) []string {
+	res := append([]string{}, mimeTypes...)
+	seen := make(map[string]bool, len(res))
+	for _, mt := range res {
+		seen[mt] = true
+	}
+	names := make([]string, 0, len(action.Responses))
+	for name := range action.Responses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r := action.Responses[name]
+		if r.MediaType == "" || r.Stream || r.Events || r.WebSocket {
+			continue
+		}
+		mt := r.MediaType
+		if base, _, err := mime.ParseMediaType(mt); err == nil {
+			mt = base
+		}
+		if !seen[mt] {
+			seen[mt] = true
+			res = append(res, mt)
+		}
+	}
+	return res
+}
+
+// orDefaultMIMETypes returns the given content types or "application/json" if there are none.
+func orDefaultMIMETypes(mimeTypes []string) []string {
+	if len(mimeTypes) == 0 {
+		return []string{"application/json"}
+	}
+	return mimeTypes
+}
@@ -400,6 +400,191 @@ var _ = Describe("New", func() {
 		})
 	})
 
+	Context("with security schemes", func() {
+		BeforeEach(func() {
+			API("secured", func() {
+				BasicAuthSecurity("basic")
+				OAuth2Security("oauth2", func() {
+					AccessCodeFlow("https://example.com/auth", "https://example.com/token")
+					Scope("read", "Read access")
+				})
+				Security("basic")
+			})
+			Resource("res", func() {
+				Action("show", func() {
+					Routing(GET("/:id"))
+					Security("oauth2", func() {
+						Scope("read")
+					})
+				})
+				Action("health", func() {
+					Routing(GET("/health"))
+					NoSecurity()
+				})
+			})
+		})
+
+		It("sets the security definitions and requirements", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			Ω(swagger.SecurityDefinitions).Should(Equal(map[string]*genswagger.SecurityDefinition{
+				"basic": {Type: "basic"},
+				"oauth2": {
+					Type:             "oauth2",
+					Flow:             "accessCode",
+					AuthorizationURL: "https://example.com/auth",
+					TokenURL:         "https://example.com/token",
+					Scopes:           map[string]string{"read": "Read access"},
+				},
+			}))
+			Ω(swagger.Security).Should(Equal([]map[string][]string{{"basic": {}}}))
+			Ω(swagger.Paths["/{id}"].Get.Security).Should(Equal([]map[string][]string{{"oauth2": {"read"}}}))
+			Ω(swagger.Paths["/health"].Get.Security).Should(Equal([]map[string][]string{{}}))
+		})
+
+		It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+	})
+
+	Context("with encodings", func() {
+		BeforeEach(func() {
+			API("encoded", func() {
+				Consumes("application/json", "application/xml")
+				Produces("application/json")
+			})
+			MediaType("application/vnd.goa.test.thing+json", func() {
+				Attributes(func() {
+					Attribute("id", Integer)
+				})
+				View("default", func() {
+					Attribute("id")
+				})
+			})
+			Resource("res", func() {
+				Action("show", func() {
+					Routing(GET("/:id"))
+					Produces("application/msgpack", func() {
+						EncodingPackage("github.com/example/msgpack")
+					})
+					Response(OK, func() {
+						Media("application/vnd.goa.test.thing+json")
+					})
+				})
+			})
+		})
+
+		It("sets the consumed and produced content types", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			Ω(swagger.Consumes).Should(Equal([]string{"application/json", "application/xml"}))
+			Ω(swagger.Produces).Should(Equal([]string{"application/json"}))
+			Ω(swagger.Paths["/{id}"].Get.Consumes).Should(Equal([]string{"application/json", "application/xml"}))
+		})
+
+		It("lists the response media types in the operation produced content types", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			Ω(swagger.Paths["/{id}"].Get.Produces).Should(Equal([]string{"application/msgpack", "application/vnd.goa.test.thing+json"}))
+		})
+
+		It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+	})
+
+	Context("with a multipart form payload", func() {
+		BeforeEach(func() {
+			API("forms", nil)
+			Resource("res", func() {
+				Action("upload", func() {
+					Routing(POST("/"))
+					MultipartForm()
+					Payload(func() {
+						Member("title", String, "Picture title")
+						Required("title")
+					})
+					File("picture", func() {
+						Description("Picture")
+						Required()
+						MaxSize(1024)
+					})
+				})
+			})
+		})
+
+		It("describes the form fields and files with formData parameters", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			op := swagger.Paths["/"].Post
+			Ω(op.Consumes).Should(Equal([]string{"multipart/form-data"}))
+			Ω(op.Parameters).Should(Equal([]*genswagger.Parameter{
+				{Name: "title", In: "formData", Description: "Picture title", Required: true, Type: "string"},
+				{Name: "picture", In: "formData", Description: "Picture", Required: true, Type: "file"},
+			}))
+		})
+
+		It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+	})
+
+	Context("with a server-sent events response", func() {
+		BeforeEach(func() {
+			API("events", nil)
+			MediaType("application/vnd.goa.test.event", func() {
+				Attributes(func() {
+					Attribute("value", Integer)
+				})
+				View("default", func() {
+					Attribute("value")
+				})
+			})
+			Resource("res", func() {
+				Action("watch", func() {
+					Routing(GET("/"))
+					Response(OK, func() {
+						Media("application/vnd.goa.test.event")
+						ServerSentEvents()
+					})
+				})
+			})
+		})
+
+		It("produces the event stream content type", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			op := swagger.Paths["/"].Get
+			Ω(op.Produces).Should(Equal([]string{"application/json", "text/event-stream"}))
+		})
+
+		It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+	})
+
+	Context("with deprecated actions", func() {
+		BeforeEach(func() {
+			API("deprecated", nil)
+			Version("1.0", func() {
+				Deprecated()
+			})
+			Resource("old", func() {
+				APIVersion("1.0")
+				Action("show", func() {
+					Routing(GET("/old"))
+				})
+			})
+			Resource("res", func() {
+				Action("list", func() {
+					Routing(GET("/"))
+				})
+				Action("show", func() {
+					Routing(GET("/:id"))
+					Deprecated(func() {
+						URL("http://example.com/docs")
+					})
+				})
+			})
+		})
+
+		It("marks the operations as deprecated", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			Ω(swagger.Paths["/old"].Get.Deprecated).Should(BeTrue())
+			Ω(swagger.Paths["/"].Get.Deprecated).Should(BeFalse())
+			Ω(swagger.Paths["/{id}"].Get.Deprecated).Should(BeTrue())
+		})
+
+		It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+	})
+
 	Context("using the cellar example API definition", func() {
 		BeforeEach(func() {
 			Design = cellarDesign
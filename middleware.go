@@ -1,13 +1,62 @@
 package goa
 
 import (
+	"bufio"
 	"fmt"
+	"net"
 	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
 )
 
 type (
 	// Middleware represents the canonical goa middleware signature.
 	Middleware func(Handler) Handler
+
+	// PanicError is the error produced by the Recover middleware when a handler panics.
+	// It records the value given to panic as well as the stack trace at the time of the panic.
+	PanicError struct {
+		// Value is the value given to panic.
+		Value interface{}
+		// Stack is the formatted stack trace of the goroutine that panicked.
+		Stack []byte
+	}
+
+	// TimeoutError is the error produced by the Timeout middleware when the deadline expires
+	// before the handler starts writing the response.
+	TimeoutError struct {
+		// Timeout is the duration that elapsed before the request timed out.
+		Timeout time.Duration
+	}
+
+	// timeoutWriter is the response writer given to handlers wrapped by the Timeout
+	// middleware. It buffers the response headers and discards writes once the request timed
+	// out so that the error handler can safely write the response concurrently.
+	timeoutWriter struct {
+		ctx         context.Context
+		w           http.ResponseWriter
+		h           http.Header
+		mu          sync.Mutex
+		timedOut    bool
+		wroteHeader bool
+	}
+
+	// timeoutResult is the outcome of a handler run by the Timeout middleware.
+	timeoutResult struct {
+		err      error
+		panicked bool
+		value    interface{}
+	}
+
+	// timeoutContext is the context set by the Timeout middleware once the handler returns. It
+	// carries the values set by the handler but not the handler deadline.
+	timeoutContext struct {
+		context.Context
+		values context.Context
+	}
 )
 
 // NewMiddleware creates a middleware from the given argument. The allowed types for the
@@ -77,3 +126,221 @@ func httpHandlerToMiddleware(m http.HandlerFunc) Middleware {
 		}
 	}
 }
+
+// Recover is a middleware that recovers from panics raised by the handlers (including the
+// middleware) that follow it in the chain. The panic is logged using the context logger and is
+// converted into a *PanicError which is returned to the caller so that the controller or
+// application error handler writes the response (status code 500 with the default handlers).
+// Recover can also be enabled service-wide with the Service SetRecover method in which case
+// it wraps the entire middleware chain of all controllers.
+func Recover() Middleware {
+	return func(h Handler) Handler {
+		return func(ctx *Context) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					perr := &PanicError{Value: r, Stack: debug.Stack()}
+					if ctx.Logger != nil {
+						ctx.Error("panic", "err", perr.Error(), "stack", string(perr.Stack))
+					}
+					err = perr
+				}
+			}()
+			return h(ctx)
+		}
+	}
+}
+
+// Error returns the panic message. It does not include the stack trace so that it may safely be
+// written to response bodies.
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Timeout is a middleware that sets a deadline on the context of the handlers that follow it
+// in the chain. The handlers should watch the context Done channel and abort when it gets closed.
+// Timeout runs them in a separate goroutine with a copy of the request context whose values,
+// including the ones they set, are merged back into the request context once they return.
+// If the deadline expires before a response is written then Timeout returns a *TimeoutError right
+// away, without waiting for the handler to return, so that the controller or application error
+// handler writes the response (status code 503 with the default handlers). Writes made by the
+// handler after that point are discarded.
+// If the handler had already started writing the response when the deadline expired then
+// Timeout lets it complete. Panics raised by the handler before Timeout returns are raised again
+// in the request goroutine so that the Recover middleware handles them.
+// goagen generates code that uses Timeout for actions whose design defines the "timeout"
+// metadata, for example:
+//
+//	Action("show", func() {
+//		Metadata("timeout", "5s")
+//	})
+func Timeout(timeout time.Duration) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx *Context) error {
+			parent := ctx.Context
+			gctx, cancel := context.WithTimeout(parent, timeout)
+			defer cancel()
+			tw := &timeoutWriter{ctx: gctx, h: make(http.Header)}
+			tw.w, _ = ctx.Value(respKey).(http.ResponseWriter)
+			// Run the handler with its own context so that it does not race with the
+			// error handler if the deadline expires.
+			hctx := &Context{Context: gctx, Logger: ctx.Logger}
+			hctx.SetResponseWriter(tw)
+			done := make(chan timeoutResult, 1)
+			go func() {
+				var res timeoutResult
+				defer func() {
+					if r := recover(); r != nil {
+						res.panicked, res.value = true, r
+					}
+					done <- res
+				}()
+				res.err = h(hctx)
+			}()
+			var res timeoutResult
+			select {
+			case res = <-done:
+			case <-gctx.Done():
+				if gctx.Err() == context.DeadlineExceeded && tw.timeout() {
+					return &TimeoutError{Timeout: timeout}
+				}
+				res = <-done
+			}
+			ctx.Context = &timeoutContext{Context: parent, values: hctx.Context}
+			ctx.SetResponseWriter(tw.w)
+			tw.restore()
+			if res.panicked {
+				panic(res.value)
+			}
+			if gctx.Err() == context.DeadlineExceeded && tw.timeout() {
+				return &TimeoutError{Timeout: timeout}
+			}
+			return res.err
+		}
+	}
+}
+
+// Error returns the timeout error message.
+func (t *TimeoutError) Error() string {
+	return fmt.Sprintf("request timed out after %s", t.Timeout)
+}
+
+// Header returns the response headers, they are copied to the actual response when the header
+// is written.
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+// WriteHeader writes the response headers unless the request already timed out.
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(code)
+}
+
+// Write writes the response body unless the request already timed out.
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(http.StatusOK)
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.w == nil {
+		return 0, fmt.Errorf("response writer not initialized")
+	}
+	return tw.w.Write(b)
+}
+
+// writeHeader copies the buffered headers and writes the status code to the underlying
+// response writer the first time it is called unless the deadline expired. tw.mu must be held by
+// the caller.
+func (tw *timeoutWriter) writeHeader(code int) {
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	if tw.ctx.Err() == context.DeadlineExceeded {
+		tw.timedOut = true
+		return
+	}
+	tw.wroteHeader = true
+	if tw.w == nil {
+		return
+	}
+	dst := tw.w.Header()
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(code)
+}
+
+// restore copies the headers set by the handler to the underlying response writer if the handler
+// returned without writing the response so that the error handler may write them.
+func (tw *timeoutWriter) restore() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader || tw.w == nil {
+		return
+	}
+	dst := tw.w.Header()
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+}
+
+// Value returns the value set by the handler or its parents for the given key.
+func (c *timeoutContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+// Flush flushes the response to the client unless the request already timed out.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(http.StatusOK)
+	if tw.timedOut {
+		return
+	}
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify returns a channel that receives a value when the client closes the connection, the
+// channel is nil if the underlying response writer cannot detect it.
+func (tw *timeoutWriter) CloseNotify() <-chan bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if cn, ok := tw.w.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+// Hijack lets the caller take over the connection, e.g. to upgrade it to a WebSocket connection,
+// unless the request already timed out. The deadline does not apply to hijacked connections.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	tw.wroteHeader = true
+	return hijack(tw.w)
+}
+
+// wrapped returns the response writer wrapped by the Timeout middleware.
+func (tw *timeoutWriter) wrapped() http.ResponseWriter {
+	return tw.w
+}
+
+// timeout flags the writer as timed out if no response was written yet and returns true in
+// this case, false otherwise.
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.wroteHeader {
+		return false
+	}
+	tw.timedOut = true
+	return true
+}
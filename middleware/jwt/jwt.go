@@ -0,0 +1,319 @@
+// Package jwt provides a goa middleware that validates JSON Web Tokens. The middleware complements
+// the client goa.JWTSigner: by default it reads the token from the "Authorization" header using
+// the "Bearer %s" format.
+// Mount the middleware on the security scheme defined in the design with the generated hook:
+//
+//	spec := &jwt.Specification{
+//		Issuer:      "https://auth.example.com",
+//		KeyResolver: jwt.StaticKeys(key),
+//	}
+//	app.UseJwtMiddleware(service, jwt.New(spec))
+//
+// Handlers retrieve the validated token claims with ContextClaims.
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/raphael/goa"
+)
+
+type (
+	// KeyResolver returns the keys that may have been used to sign the given token. The token
+	// signature is checked against each key in turn which makes it possible to rotate keys:
+	// return both the new and the old keys while clients transition.
+	// HMAC keys must be given as []byte, RSA keys as *rsa.PublicKey and ECDSA keys as
+	// *ecdsa.PublicKey. The token has not been validated when KeyResolver is called, its
+	// header may be used to select the keys (e.g. using the "kid" field).
+	KeyResolver func(token *jwt.Token) ([]interface{}, error)
+
+	// Specification describes how the middleware validates tokens.
+	Specification struct {
+		// Scheme is the name of the security scheme used in the errors returned by the
+		// middleware. The default is "jwt".
+		Scheme string
+		// Header is the name of the HTTP header which contains the token.
+		// The default is "Authorization".
+		Header string
+		// Query is the name of the URL query string parameter which contains the token. It
+		// is only used if Header is empty, it matches JWT schemes whose design reads the
+		// token from the query string.
+		Query string
+		// Format is the format used to render the token in the header or query string
+		// parameter. The default is "Bearer %s" for headers and "%s" for query strings.
+		Format string
+		// KeyResolver returns the keys used to validate the token signatures.
+		KeyResolver KeyResolver
+		// Issuer is the expected value of the "iss" claim if not empty.
+		Issuer string
+		// Audience is the value the "aud" claim must contain if not empty.
+		Audience string
+		// ScopesClaim is the name of the claim that lists the scopes granted by the token,
+		// either as a space separated string or as an array of strings. The default is
+		// "scopes".
+		ScopesClaim string
+		// Leeway is the clock skew tolerated when validating the "exp" and "nbf" claims.
+		Leeway time.Duration
+	}
+
+	// key is the type used to store internal values in the context.
+	key int
+)
+
+// tokenKey is the context key used to store the validated token.
+const tokenKey key = iota + 1
+
+// New returns a middleware that validates the JWT contained in the request. The middleware checks
+// the token signature using the keys returned by the specification key resolver as well as the
+// "exp", "nbf", "iss" and "aud" claims. It also checks that the token grants the scopes required
+// by the action (see goa.RequireSecurity).
+// The middleware returns a *goa.UnauthorizedError if the token is missing or invalid and a
+// *goa.ForbiddenError if it does not grant the required scopes. On success the validated token
+// is stored in the context, see ContextJWT and ContextClaims.
+func New(spec *Specification) goa.Middleware {
+	scheme := spec.Scheme
+	if scheme == "" {
+		scheme = "jwt"
+	}
+	header, query := spec.Header, ""
+	if header == "" {
+		if spec.Query != "" {
+			query = spec.Query
+		} else {
+			header = "Authorization"
+		}
+	}
+	format := spec.Format
+	if format == "" {
+		if query != "" {
+			format = "%s"
+		} else {
+			format = "Bearer %s"
+		}
+	}
+	scopesClaim := spec.ScopesClaim
+	if scopesClaim == "" {
+		scopesClaim = "scopes"
+	}
+	unauthorized := func(format string, vals ...interface{}) error {
+		return &goa.UnauthorizedError{Scheme: scheme, Reason: fmt.Sprintf(format, vals...)}
+	}
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx *goa.Context) error {
+			var val, location string
+			if query != "" {
+				val, location = ctx.Request().URL.Query().Get(query), query+" query string parameter"
+			} else {
+				val, location = ctx.Request().Header.Get(header), header+" header"
+			}
+			if val == "" {
+				return unauthorized("missing %s", location)
+			}
+			raw, ok := extractToken(val, format)
+			if !ok {
+				return unauthorized("invalid %s format", location)
+			}
+			token, err := parse(raw, spec.KeyResolver)
+			if err != nil {
+				return unauthorized("invalid token: %s", err)
+			}
+			claims := token.Claims.(jwt.MapClaims)
+			if err := validateClaims(claims, spec); err != nil {
+				return unauthorized("%s", err)
+			}
+			if required := ctx.RequiredScopes(); len(required) > 0 {
+				granted := scopes(claims[scopesClaim])
+				for _, r := range required {
+					if !granted[r] {
+						return &goa.ForbiddenError{Scheme: scheme, Scopes: required}
+					}
+				}
+			}
+			ctx.SetValue(tokenKey, token)
+			return h(ctx)
+		}
+	}
+}
+
+// StaticKeys returns a key resolver that always returns the given keys.
+func StaticKeys(keys ...interface{}) KeyResolver {
+	return func(*jwt.Token) ([]interface{}, error) {
+		return keys, nil
+	}
+}
+
+// ContextJWT returns the token validated by the middleware, nil if there is none.
+func ContextJWT(ctx *goa.Context) *jwt.Token {
+	if t := ctx.Value(tokenKey); t != nil {
+		return t.(*jwt.Token)
+	}
+	return nil
+}
+
+// ContextClaims returns the claims of the token validated by the middleware, nil if there is
+// none.
+func ContextClaims(ctx *goa.Context) jwt.MapClaims {
+	if t := ContextJWT(ctx); t != nil {
+		return t.Claims.(jwt.MapClaims)
+	}
+	return nil
+}
+
+// SubjectRateLimitKey is a goa.RateLimitKeyFunc that identifies clients by the "sub" claim of the
+// token validated by the middleware. The rate limiter must run inside the security middleware, as
+// it does in the code generated by goagen. Requests that do not carry a validated token are not
+// rate limited.
+func SubjectRateLimitKey(ctx *goa.Context) string {
+	sub, _ := ContextClaims(ctx)["sub"].(string)
+	return sub
+}
+
+// extractToken returns the token contained in the given header value rendered with format.
+func extractToken(val, format string) (string, bool) {
+	elems := strings.SplitN(format, "%s", 2)
+	if len(elems) != 2 {
+		return "", false
+	}
+	prefix, suffix := elems[0], elems[1]
+	if len(val) <= len(prefix)+len(suffix) ||
+		!strings.EqualFold(val[:len(prefix)], prefix) || !strings.HasSuffix(val, suffix) {
+		return "", false
+	}
+	return val[len(prefix) : len(val)-len(suffix)], true
+}
+
+// parse parses the token and validates its signature against each key returned by resolver.
+// The claims are validated separately by validateClaims.
+func parse(raw string, resolver KeyResolver) (*jwt.Token, error) {
+	if resolver == nil {
+		return nil, fmt.Errorf("no key resolver")
+	}
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	unverified, _, err := parser.ParseUnverified(raw, jwt.MapClaims{})
+	if err != nil {
+		return nil, err
+	}
+	keys, err := resolver(unverified)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no key to validate token")
+	}
+	for _, k := range keys {
+		var token *jwt.Token
+		token, err = parser.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+			if err := checkKey(t.Method, k); err != nil {
+				return nil, err
+			}
+			return k, nil
+		})
+		if err == nil {
+			return token, nil
+		}
+	}
+	return nil, err
+}
+
+// checkKey makes sure that the key type matches the token signing method so that a token
+// signed with HMAC using a public RSA or ECDSA key as secret is rejected.
+func checkKey(method jwt.SigningMethod, k interface{}) error {
+	var ok bool
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		_, ok = k.([]byte)
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok = k.(*rsa.PublicKey)
+	case *jwt.SigningMethodECDSA:
+		_, ok = k.(*ecdsa.PublicKey)
+	}
+	if !ok {
+		return fmt.Errorf("unexpected signing method %s", method.Alg())
+	}
+	return nil
+}
+
+// validateClaims checks the "exp", "nbf", "iss" and "aud" claims.
+func validateClaims(claims jwt.MapClaims, spec *Specification) error {
+	now := jwt.TimeFunc()
+	exp, ok, err := timeClaim(claims, "exp")
+	if err != nil {
+		return err
+	}
+	if ok && now.After(exp.Add(spec.Leeway)) {
+		return fmt.Errorf("token is expired")
+	}
+	nbf, ok, err := timeClaim(claims, "nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Before(nbf.Add(-spec.Leeway)) {
+		return fmt.Errorf("token is not valid yet")
+	}
+	if spec.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != spec.Issuer {
+			return fmt.Errorf("invalid issuer %#v", iss)
+		}
+	}
+	if spec.Audience != "" {
+		found := false
+		switch aud := claims["aud"].(type) {
+		case string:
+			found = aud == spec.Audience
+		case []interface{}:
+			for _, a := range aud {
+				if s, ok := a.(string); ok && s == spec.Audience {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid audience")
+		}
+	}
+	return nil
+}
+
+// timeClaim returns the value of the given NumericDate claim and whether the claim is present. It
+// returns an error if the claim is present but its value is not a number.
+func timeClaim(claims jwt.MapClaims, name string) (time.Time, bool, error) {
+	v, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	switch n := v.(type) {
+	case float64:
+		return time.Unix(int64(n), 0), true, nil
+	case json.Number:
+		f, err := n.Float64()
+		if err == nil {
+			return time.Unix(int64(f), 0), true, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("invalid %s claim %#v", name, v)
+}
+
+// scopes returns the set of scopes contained in the given claim value.
+func scopes(claim interface{}) map[string]bool {
+	res := make(map[string]bool)
+	switch s := claim.(type) {
+	case string:
+		for _, scope := range strings.Fields(s) {
+			res[scope] = true
+		}
+	case []interface{}:
+		for _, scope := range s {
+			if str, ok := scope.(string); ok {
+				res[str] = true
+			}
+		}
+	}
+	return res
+}
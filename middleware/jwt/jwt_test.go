@@ -0,0 +1,311 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+	"github.com/raphael/goa/middleware/jwt"
+)
+
+var _ = Describe("New", func() {
+	var hmacKey = []byte("secret")
+
+	var spec *jwt.Specification
+	var method jwtgo.SigningMethod
+	var signKey interface{}
+	var claims jwtgo.MapClaims
+	var header string
+	var query string
+	var scopes []string
+
+	var claimsInHandler jwtgo.MapClaims
+	var keyInHandler string
+	var err error
+
+	BeforeEach(func() {
+		spec = &jwt.Specification{KeyResolver: jwt.StaticKeys(hmacKey)}
+		method = jwtgo.SigningMethodHS256
+		signKey = hmacKey
+		claims = jwtgo.MapClaims{
+			"sub": "user",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		header = ""
+		query = ""
+		scopes = nil
+		claimsInHandler = nil
+		keyInHandler = ""
+	})
+
+	JustBeforeEach(func() {
+		req, e := http.NewRequest("GET", "/goo", nil)
+		Ω(e).ShouldNot(HaveOccurred())
+		token, e := jwtgo.NewWithClaims(method, claims).SignedString(signKey)
+		Ω(e).ShouldNot(HaveOccurred())
+		if spec.Query != "" {
+			if query == "" {
+				query = token
+			}
+			req.URL.RawQuery = url.Values{spec.Query: {query}}.Encode()
+		} else {
+			if header == "" {
+				header = "Bearer " + token
+			}
+			req.Header.Set("Authorization", header)
+		}
+		service := goa.New("test")
+		service.SetSecurityMiddleware("jwt", jwt.New(spec))
+		ctx := goa.NewContext(nil, service, req, httptest.NewRecorder(), nil)
+		h := func(ctx *goa.Context) error {
+			claimsInHandler = jwt.ContextClaims(ctx)
+			keyInHandler = jwt.SubjectRateLimitKey(ctx)
+			return nil
+		}
+		err = goa.RequireSecurity("jwt", scopes...)(h)(ctx)
+	})
+
+	It("validates the token and stores the claims in the context", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(claimsInHandler).ShouldNot(BeNil())
+		Ω(claimsInHandler["sub"]).Should(Equal("user"))
+	})
+
+	It("identifies rate limited clients by the token subject", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(keyInHandler).Should(Equal("user"))
+	})
+
+	Context("with an invalid header format", func() {
+		BeforeEach(func() {
+			header = "Basic Zm9vOmJhcg=="
+		})
+
+		It("returns an unauthorized error", func() {
+			Ω(err).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+			Ω(claimsInHandler).Should(BeNil())
+		})
+	})
+
+	Context("with a token in the query string", func() {
+		BeforeEach(func() {
+			spec.Query = "token"
+		})
+
+		It("validates the token", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(claimsInHandler["sub"]).Should(Equal("user"))
+		})
+
+		Context("that is invalid", func() {
+			BeforeEach(func() {
+				query = "invalid"
+			})
+
+			It("returns an unauthorized error", func() {
+				Ω(err).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+			})
+		})
+	})
+
+	Context("with a token signed with another key", func() {
+		BeforeEach(func() {
+			signKey = []byte("other")
+		})
+
+		It("returns an unauthorized error", func() {
+			Ω(err).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+		})
+
+		Context("that is returned by the key resolver", func() {
+			BeforeEach(func() {
+				spec.KeyResolver = jwt.StaticKeys(hmacKey, []byte("other"))
+			})
+
+			It("validates the token", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+		})
+	})
+
+	Context("with an expired token", func() {
+		BeforeEach(func() {
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+		})
+
+		It("returns an unauthorized error", func() {
+			Ω(err).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+			Ω(err.Error()).Should(ContainSubstring("expired"))
+		})
+
+		Context("within the leeway", func() {
+			BeforeEach(func() {
+				spec.Leeway = 2 * time.Minute
+			})
+
+			It("validates the token", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+		})
+	})
+
+	Context("with a non numeric expiration time", func() {
+		BeforeEach(func() {
+			claims["exp"] = "tomorrow"
+		})
+
+		It("returns an unauthorized error", func() {
+			Ω(err).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+			Ω(err.Error()).Should(ContainSubstring("invalid exp claim"))
+		})
+	})
+
+	Context("with a non numeric not before time", func() {
+		BeforeEach(func() {
+			claims["nbf"] = true
+		})
+
+		It("returns an unauthorized error", func() {
+			Ω(err).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+		})
+	})
+
+	Context("with a token that is not valid yet", func() {
+		BeforeEach(func() {
+			claims["nbf"] = time.Now().Add(time.Hour).Unix()
+		})
+
+		It("returns an unauthorized error", func() {
+			Ω(err).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+		})
+	})
+
+	Context("with an issuer and an audience", func() {
+		BeforeEach(func() {
+			spec.Issuer = "issuer"
+			spec.Audience = "api"
+			claims["iss"] = "issuer"
+			claims["aud"] = []string{"other", "api"}
+		})
+
+		It("validates the token", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		Context("that do not match", func() {
+			BeforeEach(func() {
+				claims["aud"] = "other"
+			})
+
+			It("returns an unauthorized error", func() {
+				Ω(err).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+			})
+		})
+	})
+
+	Context("with required scopes", func() {
+		BeforeEach(func() {
+			scopes = []string{"read", "write"}
+			claims["scopes"] = "read write"
+		})
+
+		It("validates the token", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		Context("that the token does not grant", func() {
+			BeforeEach(func() {
+				claims["scopes"] = []string{"read"}
+			})
+
+			It("returns a forbidden error", func() {
+				Ω(err).Should(BeAssignableToTypeOf(&goa.ForbiddenError{}))
+			})
+		})
+	})
+
+	Context("with a RSA key", func() {
+		BeforeEach(func() {
+			key, e := rsa.GenerateKey(rand.Reader, 1024)
+			Ω(e).ShouldNot(HaveOccurred())
+			method = jwtgo.SigningMethodRS256
+			signKey = key
+			spec.KeyResolver = jwt.StaticKeys(&key.PublicKey)
+		})
+
+		It("validates the token", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("with an ECDSA key", func() {
+		BeforeEach(func() {
+			key, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Ω(e).ShouldNot(HaveOccurred())
+			method = jwtgo.SigningMethodES256
+			signKey = key
+			spec.KeyResolver = jwt.StaticKeys(&key.PublicKey)
+		})
+
+		It("validates the token", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		Context("and a token signed with HMAC", func() {
+			BeforeEach(func() {
+				method = jwtgo.SigningMethodHS256
+				signKey = hmacKey
+			})
+
+			It("returns an unauthorized error", func() {
+				Ω(err).Should(BeAssignableToTypeOf(&goa.UnauthorizedError{}))
+			})
+		})
+	})
+})
+
+var _ = Describe("SubjectRateLimitKey", func() {
+	var hmacKey = []byte("secret")
+	var service goa.Service
+
+	serve := func(sub string) *httptest.ResponseRecorder {
+		token, err := jwtgo.NewWithClaims(jwtgo.SigningMethodHS256, jwtgo.MapClaims{"sub": sub}).SignedString(hmacKey)
+		Ω(err).ShouldNot(HaveOccurred())
+		req, err := http.NewRequest("GET", "/bottles", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		service.ServeMux().ServeHTTP(rec, req)
+		return rec
+	}
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.SetSecurityMiddleware("jwt", jwt.New(&jwt.Specification{KeyResolver: jwt.StaticKeys(hmacKey)}))
+		limiter := &goa.RateLimiter{Store: goa.NewMemoryRateLimitStore(10), Key: jwt.SubjectRateLimitKey}
+		// Wrap the handler the same way the code generated by goagen does.
+		var h goa.Handler = func(ctx *goa.Context) error {
+			return ctx.RespondBytes(200, []byte("ok"))
+		}
+		h = limiter.Limit("bottle#list", 1, time.Hour)(h)
+		h = goa.RequireSecurity("jwt")(h)
+		ctrl := service.NewController("BottleController")
+		route := &goa.Route{Method: "GET", Path: "/bottles"}
+		service.ServeMux().Handle(route.Method, route.Path, ctrl.HandleRoute(route, "list", h, nil))
+	})
+
+	It("limits the requests of each token subject", func() {
+		Ω(serve("alice").Code).Should(Equal(200))
+		Ω(serve("alice").Code).Should(Equal(429))
+		Ω(serve("bob").Code).Should(Equal(200))
+	})
+})
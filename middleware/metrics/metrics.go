@@ -0,0 +1,299 @@
+// Package metrics provides a goa middleware that records request metrics and a handler that
+// exposes them in the Prometheus text exposition format. The metrics are labeled with the names
+// of the designed resource, action and API version that handle the requests (as returned by the
+// goa.Context ResourceName, ActionName and APIVersion methods) and with the response status:
+//
+//	registry := metrics.New()
+//	service.Use(registry.Middleware())
+//	registry.Mount(service, "/metrics")
+//
+// The registry keeps the metrics in memory and does not depend on an external collector, point
+// a Prometheus server at the mounted endpoint to collect them.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raphael/goa"
+)
+
+type (
+	// Registry records the metrics of the requests handled by its middleware. The fields must
+	// be set before the middleware handles requests.
+	Registry struct {
+		// Namespace is the prefix of the metric names, e.g. "goa" produces metrics named
+		// "goa_http_requests_total".
+		Namespace string
+		// DurationBuckets are the upper bounds in seconds of the request duration histogram
+		// buckets in increasing order.
+		DurationBuckets []float64
+		// SizeBuckets are the upper bounds in bytes of the response size histogram buckets in
+		// increasing order.
+		SizeBuckets []float64
+
+		mu       sync.Mutex
+		requests map[series]*requestMetrics
+		inFlight map[endpoint]int
+	}
+
+	// endpoint identifies the action that handles requests.
+	endpoint struct {
+		resource, action, version string
+	}
+
+	// series identifies the requests handled by an action that got responses with a given
+	// status.
+	series struct {
+		endpoint
+		status int
+	}
+
+	// requestMetrics contains the metrics recorded for a series.
+	requestMetrics struct {
+		count    uint64
+		duration *histogram
+		size     *histogram
+	}
+
+	// histogram counts observations in buckets, counts[i] is the number of observations less
+	// than or equal to bounds[i].
+	histogram struct {
+		bounds []float64
+		counts []uint64
+		sum    float64
+		count  uint64
+	}
+)
+
+var (
+	// DefaultDurationBuckets are the request duration histogram buckets used by New.
+	DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// DefaultSizeBuckets are the response size histogram buckets used by New.
+	DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+// ContentType is the content type of the responses written by the registry ServeHTTP method.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// New returns a registry with the "goa" namespace and the default histogram buckets.
+func New() *Registry {
+	return &Registry{
+		Namespace:       "goa",
+		DurationBuckets: DefaultDurationBuckets,
+		SizeBuckets:     DefaultSizeBuckets,
+	}
+}
+
+// Middleware returns a middleware that records the number of requests, their duration, the size
+// of the response bodies and the number of requests being handled. Errors returned by the next
+// handlers without writing a response are recorded with the status of the problem created by
+// goa.NewProblem, i.e. the status of the response written by the default error handler.
+// Mount the middleware first so that it measures the time spent in the other middleware.
+func (r *Registry) Middleware() goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx *goa.Context) error {
+			ep := endpoint{resource: ctx.ResourceName(), action: ctx.ActionName(), version: ctx.APIVersion()}
+			r.begin(ep)
+			defer r.end(ep)
+			startedAt := time.Now()
+			err := h(ctx)
+			status := ctx.ResponseStatus()
+			if status == 0 && err != nil {
+				status = goa.NewProblem(err).Status
+			}
+			r.observe(series{endpoint: ep, status: status}, time.Since(startedAt), ctx.ResponseLength())
+			return err
+		}
+	}
+}
+
+// Mount registers a handler that serves the metrics with GET requests sent to the given path.
+// The requests go through the service middleware like requests sent to the controllers.
+func (r *Registry) Mount(service goa.Service, path string) {
+	ctrl := service.NewController("Metrics")
+	handle := ctrl.HandleFunc("metrics", func(ctx *goa.Context) error {
+		r.ServeHTTP(ctx, ctx.Request())
+		return nil
+	}, nil)
+	service.ServeMux().Handle("GET", path, handle)
+	service.Info("mount", "ctrl", "Metrics", "route", "GET "+path)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (r *Registry) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	var buf bytes.Buffer
+	r.WriteTo(&buf)
+	rw.Header().Set("Content-Type", ContentType)
+	rw.WriteHeader(200)
+	rw.Write(buf.Bytes())
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format to w.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	r.mu.Lock()
+	all := make([]series, 0, len(r.requests))
+	for s := range r.requests {
+		all = append(all, s)
+	}
+	sort.Sort(byLabels(all))
+	endpoints := make([]series, 0, len(r.inFlight))
+	for ep := range r.inFlight {
+		endpoints = append(endpoints, series{endpoint: ep})
+	}
+	sort.Sort(byLabels(endpoints))
+
+	name := r.name("http_requests_total")
+	writeType(&buf, name, "counter", "Number of HTTP requests handled.")
+	for _, s := range all {
+		fmt.Fprintf(&buf, "%s{%s} %d\n", name, s.labels(), r.requests[s].count)
+	}
+	name = r.name("http_request_duration_seconds")
+	writeType(&buf, name, "histogram", "Duration of the HTTP requests in seconds.")
+	for _, s := range all {
+		r.requests[s].duration.write(&buf, name, s.labels())
+	}
+	name = r.name("http_response_size_bytes")
+	writeType(&buf, name, "histogram", "Size of the HTTP response bodies in bytes.")
+	for _, s := range all {
+		r.requests[s].size.write(&buf, name, s.labels())
+	}
+	name = r.name("http_requests_in_flight")
+	writeType(&buf, name, "gauge", "Number of HTTP requests being handled.")
+	for _, s := range endpoints {
+		fmt.Fprintf(&buf, "%s{%s} %d\n", name, s.endpoint.labels(), r.inFlight[s.endpoint])
+	}
+	r.mu.Unlock()
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// begin records the start of a request handled by the given endpoint.
+func (r *Registry) begin(ep endpoint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.inFlight == nil {
+		r.inFlight = make(map[endpoint]int)
+	}
+	r.inFlight[ep]++
+}
+
+// end records the end of a request handled by the given endpoint. The endpoint in-flight gauge
+// keeps reporting 0 once all its requests complete.
+func (r *Registry) end(ep endpoint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight[ep]--
+}
+
+// observe records the duration and response size of a request.
+func (r *Registry) observe(s series, d time.Duration, size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.requests == nil {
+		r.requests = make(map[series]*requestMetrics)
+	}
+	m, ok := r.requests[s]
+	if !ok {
+		m = &requestMetrics{
+			duration: newHistogram(r.DurationBuckets),
+			size:     newHistogram(r.SizeBuckets),
+		}
+		r.requests[s] = m
+	}
+	m.count++
+	m.duration.observe(d.Seconds())
+	m.size.observe(float64(size))
+}
+
+// name returns the full name of the metric with the given name.
+func (r *Registry) name(metric string) string {
+	if r.Namespace == "" {
+		return metric
+	}
+	return r.Namespace + "_" + metric
+}
+
+// newHistogram returns a histogram with the given bucket upper bounds.
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+// observe adds an observation to the histogram.
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// write writes the histogram buckets, sum and count samples.
+func (h *histogram) write(buf *bytes.Buffer, name, labels string) {
+	for i, bound := range h.bounds {
+		fmt.Fprintf(buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bound), h.counts[i])
+	}
+	fmt.Fprintf(buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(buf, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(buf, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+// labels returns the series labels in the Prometheus text format.
+func (s series) labels() string {
+	return fmt.Sprintf(`%s,status="%d"`, s.endpoint.labels(), s.status)
+}
+
+// labels returns the endpoint labels in the Prometheus text format.
+func (ep endpoint) labels() string {
+	return fmt.Sprintf(`resource="%s",action="%s",version="%s"`,
+		escape(ep.resource), escape(ep.action), escape(ep.version))
+}
+
+// byLabels sorts series by resource, action, version and status.
+type byLabels []series
+
+func (b byLabels) Len() int      { return len(b) }
+func (b byLabels) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byLabels) Less(i, j int) bool {
+	x, y := b[i], b[j]
+	if x.resource != y.resource {
+		return x.resource < y.resource
+	}
+	if x.action != y.action {
+		return x.action < y.action
+	}
+	if x.version != y.version {
+		return x.version < y.version
+	}
+	return x.status < y.status
+}
+
+// writeType writes the HELP and TYPE lines of a metric.
+func writeType(buf *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// labelEscaper escapes label values as required by the Prometheus text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape escapes the given label value.
+func escape(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+// formatFloat formats a sample value or bucket bound.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,103 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+	"github.com/raphael/goa/middleware/metrics"
+)
+
+var _ = Describe("Registry", func() {
+	var service goa.Service
+	var registry *metrics.Registry
+	var handler goa.Handler
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", path, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rec := httptest.NewRecorder()
+		service.ServeMux().ServeHTTP(rec, req)
+		return rec
+	}
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		registry = metrics.New()
+		registry.DurationBuckets = []float64{60}
+		registry.SizeBuckets = []float64{10, 1000}
+		service.Use(registry.Middleware())
+		handler = func(ctx *goa.Context) error {
+			return ctx.RespondBytes(200, []byte("[]"))
+		}
+	})
+
+	JustBeforeEach(func() {
+		ctrl := service.NewController("BottleController")
+		route := &goa.Route{Method: "GET", Path: "/bottles", Resource: "bottle", Action: "list", Version: "v1"}
+		service.ServeMux().Handle(route.Method, route.Path, ctrl.HandleRoute(route, "List", func(ctx *goa.Context) error {
+			return handler(ctx)
+		}, nil))
+		registry.Mount(service, "/metrics")
+	})
+
+	It("exposes the metrics of the requests labeled by resource, action and version", func() {
+		get("/bottles")
+		get("/bottles")
+		rec := get("/metrics")
+		Ω(rec.Code).Should(Equal(200))
+		Ω(rec.Header().Get("Content-Type")).Should(Equal(metrics.ContentType))
+		body := rec.Body.String()
+		labels := `resource="bottle",action="list",version="v1",status="200"`
+		Ω(body).Should(ContainSubstring("# TYPE goa_http_requests_total counter\n"))
+		Ω(body).Should(ContainSubstring(`goa_http_requests_total{` + labels + "} 2\n"))
+		Ω(body).Should(ContainSubstring("# TYPE goa_http_request_duration_seconds histogram\n"))
+		Ω(body).Should(ContainSubstring(`goa_http_request_duration_seconds_bucket{` + labels + `,le="60"} 2` + "\n"))
+		Ω(body).Should(ContainSubstring(`goa_http_request_duration_seconds_count{` + labels + "} 2\n"))
+		Ω(body).Should(ContainSubstring(`goa_http_response_size_bytes_bucket{` + labels + `,le="10"} 2` + "\n"))
+		Ω(body).Should(ContainSubstring(`goa_http_response_size_bytes_bucket{` + labels + `,le="+Inf"} 2` + "\n"))
+		Ω(body).Should(ContainSubstring(`goa_http_response_size_bytes_sum{` + labels + "} 4\n"))
+	})
+
+	It("reports the requests being handled", func() {
+		var inFlight string
+		handler = func(ctx *goa.Context) error {
+			inFlight = get("/metrics").Body.String()
+			return ctx.RespondBytes(200, []byte("[]"))
+		}
+		get("/bottles")
+		Ω(inFlight).Should(ContainSubstring(`goa_http_requests_in_flight{resource="bottle",action="list",version="v1"} 1` + "\n"))
+		Ω(get("/metrics").Body.String()).Should(ContainSubstring(`goa_http_requests_in_flight{resource="bottle",action="list",version="v1"} 0` + "\n"))
+	})
+
+	It("labels the requests with the status of the error responses", func() {
+		handler = func(ctx *goa.Context) error {
+			return &goa.TypedError{ID: goa.ErrTooManyRequests, Mesg: "slow down"}
+		}
+		rec := get("/bottles")
+		Ω(rec.Code).Should(Equal(429))
+		Ω(get("/metrics").Body.String()).Should(ContainSubstring(
+			`goa_http_requests_total{resource="bottle",action="list",version="v1",status="429"} 1` + "\n"))
+	})
+
+	Context("with a middleware that returns an error", func() {
+		BeforeEach(func() {
+			service.Use(func(h goa.Handler) goa.Handler {
+				return func(ctx *goa.Context) error {
+					if ctx.ActionName() == "list" {
+						return &goa.TypedError{ID: goa.ErrTooManyRequests, Mesg: "slow down"}
+					}
+					return h(ctx)
+				}
+			})
+		})
+
+		It("records the status of the problem describing the error", func() {
+			get("/bottles")
+			Ω(get("/metrics").Body.String()).Should(ContainSubstring(
+				`goa_http_requests_total{resource="bottle",action="list",version="v1",status="429"} 1` + "\n"))
+		})
+	})
+})
@@ -0,0 +1,114 @@
+// Package middleware contains stock goa middleware that handle common needs such as assigning
+// request IDs and logging requests.
+// Use the goa Service or Controller Use method to mount middleware:
+//
+//	service := goa.New("my api")
+//	service.Use(middleware.RequestID())
+//	service.Use(middleware.LogRequest())
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/raphael/goa"
+)
+
+// RequestIDHeader is the name of the header used to transmit the request ID.
+const RequestIDHeader = "X-Request-Id"
+
+// key is the type used to store internal values in the context.
+type key int
+
+// reqIDKey is the context key used to store the request ID value.
+const reqIDKey key = iota + 1
+
+// RequestID is a middleware that injects a request ID into the context of each request.
+// The ID is read from the incoming request X-Request-Id header if present, a random ID is
+// generated otherwise. The ID is also written to the X-Request-Id response header.
+// Retrieve the ID with ContextRequestID.
+func RequestID() goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx *goa.Context) error {
+			id := initRequestID(ctx)
+			if header := ctx.Header(); header != nil {
+				header.Set(RequestIDHeader, id)
+			}
+			return h(ctx)
+		}
+	}
+}
+
+// LogRequest is a middleware that logs incoming requests and their outcome. The request ID
+// (as computed by RequestID if mounted or read from the X-Request-Id header otherwise) is added to
+// the context logger so that all log entries written by the next handlers include it.
+// LogRequest logs the request method and URL, its parameters and payload when it starts
+// and the response status, length and duration when it completes.
+func LogRequest() goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx *goa.Context) error {
+			if ctx.Logger == nil {
+				ctx.Logger = goa.Log
+			}
+			ctx.Logger = ctx.Logger.New("id", initRequestID(ctx))
+			startedAt := time.Now()
+			r := ctx.Request()
+			ctx.Info("started", r.Method, r.URL.String())
+			names := ctx.GetNames()
+			if len(names) > 0 {
+				logCtx := make([]interface{}, 2*len(names))
+				for i, n := range names {
+					logCtx[2*i] = n
+					logCtx[2*i+1] = interface{}(ctx.GetMany(n))
+				}
+				ctx.Debug("params", logCtx...)
+			}
+			if payload := ctx.RawPayload(); payload != nil {
+				if js, err := json.Marshal(payload); err == nil {
+					ctx.Debug("payload", "raw", string(js))
+				}
+			}
+			err := h(ctx)
+			ctx.Info("completed", "status", ctx.ResponseStatus(),
+				"bytes", ctx.ResponseLength(), "time", time.Since(startedAt).String())
+			return err
+		}
+	}
+}
+
+// ContextRequestID returns the request ID associated with the given context, empty string if
+// there isn't one (e.g. if neither the RequestID nor the LogRequest middleware are mounted).
+func ContextRequestID(ctx *goa.Context) string {
+	if id := ctx.Value(reqIDKey); id != nil {
+		return id.(string)
+	}
+	return ""
+}
+
+// initRequestID returns the request ID stored in the context. It initializes it first if needed
+// either from the request X-Request-Id header or by generating a random ID.
+func initRequestID(ctx *goa.Context) string {
+	id := ContextRequestID(ctx)
+	if id != "" {
+		return id
+	}
+	if r := ctx.Request(); r != nil {
+		id = r.Header.Get(RequestIDHeader)
+	}
+	if id == "" {
+		id = shortID()
+	}
+	ctx.SetValue(reqIDKey, id)
+	return id
+}
+
+// shortID produces a "unique" 6 bytes long string.
+// Do not use as a reliable way to get unique IDs, instead use for things like logging.
+func shortID() string {
+	b := make([]byte, 6)
+	io.ReadFull(rand.Reader, b)
+	return base64.StdEncoding.EncodeToString(b)
+}
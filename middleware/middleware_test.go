@@ -0,0 +1,108 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+	"github.com/raphael/goa/middleware"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+var _ = Describe("RequestID", func() {
+	var ctx *goa.Context
+	var rw *TestResponseWriter
+	var reqID string
+
+	BeforeEach(func() {
+		reqID = ""
+	})
+
+	JustBeforeEach(func() {
+		req, err := http.NewRequest("GET", "/goo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		if reqID != "" {
+			req.Header.Set(middleware.RequestIDHeader, reqID)
+		}
+		rw = &TestResponseWriter{ParentHeader: make(http.Header)}
+		ctx = goa.NewContext(nil, goa.New("test"), req, rw, nil)
+		h := func(ctx *goa.Context) error { return ctx.RespondBytes(200, nil) }
+		Ω(middleware.RequestID()(h)(ctx)).ShouldNot(HaveOccurred())
+	})
+
+	It("generates a request ID", func() {
+		Ω(middleware.ContextRequestID(ctx)).ShouldNot(BeEmpty())
+		Ω(rw.ParentHeader.Get(middleware.RequestIDHeader)).Should(Equal(middleware.ContextRequestID(ctx)))
+	})
+
+	Context("with a request ID header", func() {
+		BeforeEach(func() {
+			reqID = "foo"
+		})
+
+		It("uses the header value", func() {
+			Ω(middleware.ContextRequestID(ctx)).Should(Equal(reqID))
+			Ω(rw.ParentHeader.Get(middleware.RequestIDHeader)).Should(Equal(reqID))
+		})
+	})
+})
+
+var _ = Describe("LogRequest", func() {
+	var ctx *goa.Context
+	var records []*log15.Record
+
+	BeforeEach(func() {
+		records = nil
+		req, err := http.NewRequest("POST", "/goo?param=value", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set(middleware.RequestIDHeader, "foo")
+		rw := &TestResponseWriter{ParentHeader: make(http.Header)}
+		params := url.Values{"param": []string{"value"}}
+		payload := map[string]interface{}{"payload": 42}
+		ctx = goa.NewContext(nil, goa.New("test"), req, rw, params)
+		ctx.SetPayload(payload)
+		logger := log15.New()
+		logger.SetHandler(log15.FuncHandler(func(r *log15.Record) error {
+			records = append(records, r)
+			return nil
+		}))
+		ctx.Logger = logger
+		h := func(ctx *goa.Context) error { return ctx.RespondBytes(200, []byte("ok")) }
+		Ω(middleware.LogRequest()(h)(ctx)).ShouldNot(HaveOccurred())
+	})
+
+	It("logs the request start and completion", func() {
+		Ω(records).Should(HaveLen(4))
+		Ω(records[0].Msg).Should(Equal("started"))
+		Ω(records[0].Ctx).Should(ContainElement("foo"))
+		Ω(records[0].Ctx).Should(ContainElement("POST"))
+		Ω(records[1].Msg).Should(Equal("params"))
+		Ω(records[1].Ctx).Should(ContainElement("param"))
+		Ω(records[2].Msg).Should(Equal("payload"))
+		Ω(records[2].Ctx).Should(ContainElement(`{"payload":42}`))
+		Ω(records[3].Msg).Should(Equal("completed"))
+		Ω(records[3].Ctx).Should(ContainElement(200))
+		Ω(records[3].Ctx).Should(ContainElement(2))
+	})
+})
+
+type TestResponseWriter struct {
+	ParentHeader http.Header
+	Body         []byte
+	Status       int
+}
+
+func (t *TestResponseWriter) Header() http.Header {
+	return t.ParentHeader
+}
+
+func (t *TestResponseWriter) Write(b []byte) (int, error) {
+	t.Body = append(t.Body, b...)
+	return len(b), nil
+}
+
+func (t *TestResponseWriter) WriteHeader(s int) {
+	t.Status = s
+}
@@ -2,8 +2,11 @@ package goa_test
 
 import (
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -142,3 +145,219 @@ var _ = Describe("NewMiddleware", func() {
 
 	})
 })
+
+var _ = Describe("Recover", func() {
+	var h goa.Handler
+	var ctx *goa.Context
+	var err error
+
+	BeforeEach(func() {
+		req, e := http.NewRequest("GET", "/goo", nil)
+		Ω(e).ShouldNot(HaveOccurred())
+		ctx = goa.NewContext(nil, goa.New("test"), req, new(TestResponseWriter), nil)
+	})
+
+	JustBeforeEach(func() {
+		err = goa.Recover()(h)(ctx)
+	})
+
+	Context("with a handler that does not panic", func() {
+		BeforeEach(func() {
+			h = func(ctx *goa.Context) error { return nil }
+		})
+
+		It("does not return an error", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("with a handler that panics", func() {
+		BeforeEach(func() {
+			h = func(ctx *goa.Context) error { panic("boom") }
+		})
+
+		It("returns a panic error containing the stack trace", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err).Should(BeAssignableToTypeOf(&goa.PanicError{}))
+			perr := err.(*goa.PanicError)
+			Ω(perr.Value).Should(Equal("boom"))
+			Ω(perr.Stack).ShouldNot(BeEmpty())
+			Ω(perr.Error()).Should(Equal("panic: boom"))
+		})
+	})
+})
+
+var _ = Describe("Timeout", func() {
+	var h goa.Handler
+	var ctx *goa.Context
+	var rw *TestResponseWriter
+	var err error
+
+	BeforeEach(func() {
+		req, e := http.NewRequest("GET", "/goo", nil)
+		Ω(e).ShouldNot(HaveOccurred())
+		rw = &TestResponseWriter{ParentHeader: make(http.Header)}
+		ctx = goa.NewContext(nil, goa.New("test"), req, rw, nil)
+	})
+
+	JustBeforeEach(func() {
+		err = goa.Timeout(10 * time.Millisecond)(h)(ctx)
+	})
+
+	Context("with a handler that responds in time", func() {
+		BeforeEach(func() {
+			h = func(ctx *goa.Context) error {
+				ctx.Header().Set("X-Foo", "bar")
+				return ctx.RespondBytes(200, []byte("ok"))
+			}
+		})
+
+		It("writes the response", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rw.Status).Should(Equal(200))
+			Ω(string(rw.Body)).Should(Equal("ok"))
+			Ω(rw.ParentHeader.Get("X-Foo")).Should(Equal("bar"))
+			Ω(ctx.ResponseStatus()).Should(Equal(200))
+			Ω(ctx.ResponseLength()).Should(Equal(2))
+		})
+	})
+
+	Context("with a handler that does not respond in time", func() {
+		BeforeEach(func() {
+			h = func(ctx *goa.Context) error {
+				<-ctx.Done()
+				return ctx.RespondBytes(200, []byte("late"))
+			}
+		})
+
+		It("returns a timeout error and discards the handler response", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err).Should(BeAssignableToTypeOf(&goa.TimeoutError{}))
+			Ω(err.(*goa.TimeoutError).Timeout).Should(Equal(10 * time.Millisecond))
+			Ω(rw.Status).Should(Equal(0))
+			Ω(rw.Body).Should(BeEmpty())
+		})
+
+		It("lets the default error handler write a 503 response", func() {
+			goa.DefaultErrorHandler(ctx, err)
+			Ω(rw.Status).Should(Equal(503))
+		})
+	})
+
+	Context("with a handler that sets context values", func() {
+		var hctx *goa.Context
+
+		BeforeEach(func() {
+			h = func(c *goa.Context) error {
+				hctx = c
+				c.SetValue("answer", 42)
+				return nil
+			}
+		})
+
+		It("runs it with the request context values and keeps the values it sets", func() {
+			Ω(hctx.Request()).Should(BeIdenticalTo(ctx.Request()))
+			Ω(hctx.Service()).Should(BeIdenticalTo(ctx.Service()))
+			Ω(ctx.Value("answer")).Should(Equal(42))
+		})
+
+		It("removes the deadline once the handler returns", func() {
+			_, ok := ctx.Deadline()
+			Ω(ok).Should(BeFalse())
+			Ω(ctx.Err()).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("with a handler that returns an error without responding", func() {
+		BeforeEach(func() {
+			h = func(ctx *goa.Context) error {
+				ctx.Header().Set("X-Foo", "bar")
+				return fmt.Errorf("boom")
+			}
+		})
+
+		It("keeps the response headers for the error handler", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(rw.ParentHeader.Get("X-Foo")).Should(Equal("bar"))
+		})
+	})
+
+	Context("with a handler that ignores the deadline", func() {
+		var release chan struct{}
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			h = func(ctx *goa.Context) error {
+				<-release
+				return ctx.RespondBytes(200, []byte("late"))
+			}
+		})
+
+		AfterEach(func() {
+			close(release)
+		})
+
+		It("returns a timeout error without waiting for the handler", func() {
+			Ω(err).Should(BeAssignableToTypeOf(&goa.TimeoutError{}))
+			goa.DefaultErrorHandler(ctx, err)
+			Ω(rw.Status).Should(Equal(503))
+		})
+	})
+
+	Context("with a handler that panics", func() {
+		var panicking goa.Handler
+
+		BeforeEach(func() {
+			h = func(ctx *goa.Context) error { return nil }
+			panicking = func(ctx *goa.Context) error { panic("boom") }
+		})
+
+		It("raises the panic in the request goroutine", func() {
+			Ω(func() { goa.Timeout(time.Second)(panicking)(ctx) }).Should(Panic())
+		})
+
+		It("lets the Recover middleware recover it", func() {
+			err := goa.Recover()(goa.Timeout(time.Second)(panicking))(ctx)
+			Ω(err).Should(BeAssignableToTypeOf(&goa.PanicError{}))
+			Ω(err.(*goa.PanicError).Value).Should(Equal("boom"))
+		})
+	})
+
+	Context("with a handler that streams the response", func() {
+		var rec *httptest.ResponseRecorder
+
+		BeforeEach(func() {
+			rec = httptest.NewRecorder()
+			ctx = goa.NewContext(nil, goa.New("test"), ctx.Request(), rec, nil)
+			h = func(ctx *goa.Context) error {
+				return ctx.RespondStream(200, func(w io.Writer) error {
+					_, err := w.Write([]byte("event"))
+					return err
+				})
+			}
+		})
+
+		It("flushes the data to the client", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rec.Flushed).Should(BeTrue())
+			Ω(rec.Body.String()).Should(Equal("event"))
+		})
+	})
+
+	Context("with a handler that starts responding before the deadline", func() {
+		BeforeEach(func() {
+			h = func(ctx *goa.Context) error {
+				ctx.WriteHeader(200)
+				<-ctx.Done()
+				_, err := ctx.Write([]byte("slow"))
+				return err
+			}
+		})
+
+		It("lets the handler complete the response", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rw.Status).Should(Equal(200))
+			Ω(string(rw.Body)).Should(Equal("slow"))
+		})
+	})
+})
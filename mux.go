@@ -3,11 +3,13 @@ package goa
 import (
 	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
+	"strings"
 
-	"github.com/julienschmidt/httprouter"
 	"github.com/raphael/goa/design"
 )
 
@@ -25,6 +27,17 @@ type (
 		Version(version string) VersionMux
 		// HandleMissingVersion handles requests that specify a non-existing API version.
 		HandleMissingVersion(rw http.ResponseWriter, req *http.Request, version string)
+		// Routes returns the routes registered with all the version muxes sorted by
+		// version, path and method.
+		Routes() []Route
+		// HandleNotFound sets the handler called by all the version muxes for requests
+		// whose path matches no route.
+		HandleNotFound(handle HandleFunc)
+		// HandleMethodNotAllowed sets the handler called by all the version muxes for
+		// requests whose path matches routes registered for other HTTP methods. The "Allow"
+		// response header lists these methods when the handler is called. OPTIONS requests
+		// are answered by the mux and never given to the handler.
+		HandleMethodNotAllowed(handle HandleFunc)
 	}
 
 	// VersionMux is the interface implemented by API version specific request mux.
@@ -36,6 +49,30 @@ type (
 		Handle(method, path string, handle HandleFunc)
 		// Lookup returns the HandleFunc associated with the given HTTP method and path.
 		Lookup(method, path string) HandleFunc
+		// Deprecate marks the API version as deprecated, the mux adds the deprecation
+		// headers to the responses of all the requests it handles.
+		Deprecate(d *Deprecation)
+	}
+
+	// Route describes a route registered with a mux.
+	Route struct {
+		// Method is the route HTTP method.
+		Method string
+		// Path is the route path, it may contain wildcards.
+		Path string
+		// Resource is the name of the designed resource whose action handles the route, empty
+		// if the handler does not implement a designed action.
+		Resource string
+		// Action is the name of the designed action that handles the route.
+		Action string
+		// Version is the API version of the mux that registered the route, empty for the
+		// unversioned mux.
+		Version string
+		// MaxBodySize is the maximum size in bytes of the request bodies, 0 if not limited.
+		MaxBodySize int64
+		// Consumes lists the content types of the request bodies accepted by the route, all
+		// content types are accepted if empty. See Consumes for the matching rules.
+		Consumes []string
 	}
 
 	// HandleFunc provides the implementation for an API endpoint.
@@ -48,7 +85,10 @@ type (
 	DefaultMux struct {
 		*defaultVersionMux
 		selectVersion SelectVersionFunc
-		muxes         map[string]VersionMux
+		newRouter     RouterFactory
+		handlers      *muxHandlers
+		muxes         map[string]*defaultVersionMux
+		described     map[string]Route
 	}
 
 	// SelectVersionFunc is used by the default goa mux to compute the API version targetted by
@@ -59,8 +99,19 @@ type (
 
 	// defaultVersionMux is the default goa API version specific mux.
 	defaultVersionMux struct {
-		router  *httprouter.Router
-		handles map[string]HandleFunc
+		version     string
+		router      Router
+		handlers    *muxHandlers
+		handles     map[string]HandleFunc
+		routes      map[string]Route
+		autoHead    map[string]bool
+		deprecation *Deprecation
+	}
+
+	// muxHandlers contains the handlers shared by all the version muxes of a DefaultMux.
+	muxHandlers struct {
+		notFound         HandleFunc
+		methodNotAllowed HandleFunc
 	}
 )
 
@@ -68,14 +119,42 @@ type (
 // The default versioning handling assumes that the base path for the API is "/api" and the
 // base paths for each version "/:version/api". Use SelectVersion to specify a different scheme
 // if the service exposes a versioned API with different base paths.
+// The version muxes use routers created with NewHTTPRouter, use NewMuxWithRouter to use a
+// different router.
 func NewMux() ServeMux {
+	return NewMuxWithRouter(NewHTTPRouter)
+}
+
+// NewMuxWithRouter creates a top level mux using the default goa mux implementation and routers
+// created with the given factory, e.g.:
+//
+//	service.SetServeMux(goa.NewMuxWithRouter(goa.NewTreeRouter))
+func NewMuxWithRouter(newRouter RouterFactory) ServeMux {
+	handlers := &muxHandlers{
+		notFound:         handleNotFound,
+		methodNotAllowed: handleMethodNotAllowed,
+	}
 	return &DefaultMux{
-		defaultVersionMux: &defaultVersionMux{
-			router:  httprouter.New(),
-			handles: make(map[string]HandleFunc),
-		},
-		selectVersion: PathSelectVersionFunc("/:version/", "api"),
+		defaultVersionMux: newVersionMux("", newRouter(), handlers),
+		selectVersion:     PathSelectVersionFunc("/:version/", "api"),
+		newRouter:         newRouter,
+		handlers:          handlers,
+	}
+}
+
+// newVersionMux creates a version mux that registers its routes with the given router.
+func newVersionMux(version string, router Router, handlers *muxHandlers) *defaultVersionMux {
+	mux := &defaultVersionMux{
+		version:  version,
+		router:   router,
+		handlers: handlers,
+		handles:  make(map[string]HandleFunc),
+		routes:   make(map[string]Route),
+		autoHead: make(map[string]bool),
 	}
+	router.HandleNotFound(mux.handleNotFound)
+	router.HandleMethodNotAllowed(mux.handleMethodNotAllowed)
+	return mux
 }
 
 // PathSelectVersionFunc returns a SelectVersionFunc that uses the given path pattern to extract the
@@ -111,6 +190,46 @@ func QuerySelectVersionFunc(query string) SelectVersionFunc {
 	}
 }
 
+// versionSuffixRegex matches the version suffix of vendor media types, e.g. ".v2" in
+// "application/vnd.acme.v2+json".
+var versionSuffixRegex = regexp.MustCompile(`\.(v[0-9][0-9A-Za-z.\-]*)(?:\+|$)`)
+
+// MediaTypeSelectVersionFunc returns a SelectVersionFunc that looks for the version in the media
+// types of the Accept request header and, if none specifies it, in the media type of the
+// Content-Type request header. The version is read from the media type parameter with the given
+// name, e.g. "application/json; version=2.0" with the param "version", or from a vendor media type
+// suffix starting with "v", e.g. "application/vnd.acme.v2+json" selects the version "v2". The
+// default mux routes both requests to the mux of the version "2.0" or "v2" whichever is defined.
+func MediaTypeSelectVersionFunc(param string) SelectVersionFunc {
+	return func(req *http.Request) string {
+		for _, mt := range strings.Split(req.Header.Get("Accept"), ",") {
+			if version := mediaTypeVersion(mt, param); version != "" {
+				return version
+			}
+		}
+		return mediaTypeVersion(req.Header.Get("Content-Type"), param)
+	}
+}
+
+// mediaTypeVersion returns the version specified by the given media type, see
+// MediaTypeSelectVersionFunc.
+func mediaTypeVersion(mediaType, param string) string {
+	mt, params, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return ""
+	}
+	if version := params[param]; param != "" && version != "" {
+		return version
+	}
+	if i := strings.Index(mt, "/"); i >= 0 {
+		mt = mt[i+1:]
+	}
+	if match := versionSuffixRegex.FindStringSubmatch(mt); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
 // CombineSelectVersionFunc returns a SelectVersionFunc that tries each func passed as argument
 // in order and returns the first non-empty string version.
 func CombineSelectVersionFunc(funcs ...SelectVersionFunc) SelectVersionFunc {
@@ -127,40 +246,85 @@ func CombineSelectVersionFunc(funcs ...SelectVersionFunc) SelectVersionFunc {
 // Version returns the mux addressing the given version if any.
 func (m *DefaultMux) Version(version string) VersionMux {
 	if m.muxes == nil {
-		m.muxes = make(map[string]VersionMux)
+		m.muxes = make(map[string]*defaultVersionMux)
 	}
 	if mux, ok := m.muxes[version]; ok {
 		return mux
 	}
-	mux := &defaultVersionMux{
-		router:  httprouter.New(),
-		handles: make(map[string]HandleFunc),
-	}
+	mux := newVersionMux(version, m.newRouter(), m.handlers)
 	m.muxes[version] = mux
 	return mux
 }
 
+// Routes returns the routes registered with all the version muxes sorted by version, path and
+// method. The routes of the handlers created with the controller HandleRoute method include the
+// resource and action names, the maximum body size and the consumed content types.
+func (m *DefaultMux) Routes() []Route {
+	routes := m.describedRoutes(m.defaultVersionMux)
+	for _, mux := range m.muxes {
+		routes = append(routes, m.describedRoutes(mux)...)
+	}
+	sort.Sort(byVersionPathMethod(routes))
+	return routes
+}
+
+// describedRoutes returns the routes registered with the given version mux completed with the
+// routes recorded by describeRoute.
+func (m *DefaultMux) describedRoutes(mux *defaultVersionMux) []Route {
+	routes := make([]Route, 0, len(mux.routes))
+	for _, r := range mux.routes {
+		if d, ok := m.described[routeID(r.Version, r.Method, r.Path)]; ok {
+			r = d
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// describeRoute records the given route so that Routes returns it once a handler is registered
+// for its version, method and path. HandleRoute calls it with the routes of the handlers it
+// creates.
+func (m *DefaultMux) describeRoute(route *Route) {
+	if m.described == nil {
+		m.described = make(map[string]Route)
+	}
+	m.described[routeID(route.Version, route.Method, route.Path)] = *route
+}
+
+// routeID returns the key used to index the route with the given version, method and path.
+func routeID(version, method, path string) string {
+	return version + " " + method + " " + path
+}
+
+// HandleNotFound sets the handler called by all the version muxes for requests whose path matches
+// no route. The default handler responds with the problem describing an error with ID ErrNotFound.
+func (m *DefaultMux) HandleNotFound(handle HandleFunc) {
+	m.handlers.notFound = handle
+}
+
+// HandleMethodNotAllowed sets the handler called by all the version muxes for requests whose path
+// matches routes registered for other HTTP methods. The default handler responds with the problem
+// describing an error with ID ErrMethodNotAllowed.
+func (m *DefaultMux) HandleMethodNotAllowed(handle HandleFunc) {
+	m.handlers.methodNotAllowed = handle
+}
+
 // SelectVersion sets the func used to compute the API version targetted by a request.
 func (m *DefaultMux) SelectVersion(sv SelectVersionFunc) {
 	m.selectVersion = sv
 }
 
-// HandleMissingVersion handles requests that specify a non-existing API version.
+// HandleMissingVersion handles requests that specify a non-existing API version. It responds with
+// the problem describing an error with ID ErrInvalidVersion.
 func (m *DefaultMux) HandleMissingVersion(rw http.ResponseWriter, req *http.Request, version string) {
-	rw.WriteHeader(400)
-	resp := TypedError{ID: ErrInvalidVersion, Mesg: fmt.Sprintf(`API does not support version %s`, version)}
-	b, err := json.Marshal(resp)
-	if err != nil {
-		b = []byte("API does not support version")
-	}
-	rw.Write(b)
+	respondProblem(rw, &TypedError{ID: ErrInvalidVersion, Mesg: fmt.Sprintf(`API does not support version %s`, version)})
 }
 
 // ServeHTTP is the function called back by the underlying HTTP server to handle incoming requests.
 func (m *DefaultMux) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	// Optimize the unversionned API case
 	if len(m.muxes) == 0 {
-		m.router.ServeHTTP(rw, req)
+		m.defaultVersionMux.ServeHTTP(rw, req)
 		return
 	}
 	var mux VersionMux
@@ -169,7 +333,7 @@ func (m *DefaultMux) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		mux = m.defaultVersionMux
 	} else {
 		var ok bool
-		mux, ok = m.muxes[version]
+		mux, ok = m.versionMux(version)
 		if !ok {
 			m.HandleMissingVersion(rw, req, version)
 			return
@@ -178,17 +342,51 @@ func (m *DefaultMux) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	mux.ServeHTTP(rw, req)
 }
 
-// Handle sets the handler for the given verb and path.
-func (m *defaultVersionMux) Handle(method, path string, handle HandleFunc) {
-	hthandle := func(rw http.ResponseWriter, req *http.Request, htparams httprouter.Params) {
-		params := req.URL.Query()
-		for _, p := range htparams {
-			params.Set(p.Key, p.Value)
+// versionMux returns the mux of the given version. The versions that differ only by a "v" prefix
+// or by trailing ".0" components are the same, e.g. "v2", "2" and "2.0" select the same mux so
+// that a version given by a vendor media type suffix matches the same version given by a media
+// type parameter.
+func (m *DefaultMux) versionMux(version string) (*defaultVersionMux, bool) {
+	if mux, ok := m.muxes[version]; ok {
+		return mux, true
+	}
+	canonical := canonicalVersion(version)
+	for v, mux := range m.muxes {
+		if canonicalVersion(v) == canonical {
+			return mux, true
 		}
-		handle(rw, req, params)
 	}
+	return nil, false
+}
+
+// canonicalVersion returns the given version without "v" prefix and trailing ".0" components.
+func canonicalVersion(version string) string {
+	v := strings.TrimPrefix(strings.ToLower(version), "v")
+	for strings.HasSuffix(v, ".0") {
+		v = strings.TrimSuffix(v, ".0")
+	}
+	return v
+}
+
+// Handle sets the handler for the given verb and path. HEAD requests sent to the path of a GET
+// route are handled by the GET handler unless a HEAD handler is registered for the path.
+func (m *defaultVersionMux) Handle(method, path string, handle HandleFunc) {
 	m.handles[method+path] = handle
-	m.router.Handle(method, path, hthandle)
+	m.routes[method+path] = Route{Method: method, Path: path, Version: m.version}
+	if method == "HEAD" && m.autoHead[path] {
+		return // The HEAD route is already registered, it looks up the handler on each request.
+	}
+	m.router.Handle(method, path, handle)
+	if method == "GET" && m.handles["HEAD"+path] == nil {
+		m.autoHead[path] = true
+		m.router.Handle("HEAD", path, func(rw http.ResponseWriter, req *http.Request, params url.Values) {
+			h := m.handles["HEAD"+path]
+			if h == nil {
+				h = handle
+			}
+			h(rw, req, params)
+		})
+	}
 }
 
 // Lookup returns the HandleFunc associated with the given method and path.
@@ -196,7 +394,87 @@ func (m *defaultVersionMux) Lookup(method, path string) HandleFunc {
 	return m.handles[method+path]
 }
 
+// Deprecate marks the API version as deprecated.
+func (m *defaultVersionMux) Deprecate(d *Deprecation) {
+	m.deprecation = d
+}
+
 // ServeHTTP is the function called back by the underlying HTTP server to handle incoming requests.
 func (m *defaultVersionMux) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if m.deprecation != nil {
+		m.deprecation.SetHeaders(rw.Header())
+	}
 	m.router.ServeHTTP(rw, req)
 }
+
+// handleNotFound handles requests whose path matches no route.
+func (m *defaultVersionMux) handleNotFound(rw http.ResponseWriter, req *http.Request, params url.Values) {
+	m.handlers.notFound(rw, req, params)
+}
+
+// handleMethodNotAllowed handles requests whose path matches routes registered for other
+// methods. It answers OPTIONS requests with the allowed methods and calls the mux method not
+// allowed handler for the other requests.
+func (m *defaultVersionMux) handleMethodNotAllowed(rw http.ResponseWriter, req *http.Request, params url.Values) {
+	if req.Method == "OPTIONS" {
+		rw.WriteHeader(200)
+		return
+	}
+	m.handlers.methodNotAllowed(rw, req, params)
+}
+
+// notFoundError returns the typed error describing a request whose path matches no route.
+func notFoundError(req *http.Request) *TypedError {
+	return &TypedError{
+		ID:   ErrNotFound,
+		Mesg: fmt.Sprintf("%s %s does not match any route", req.Method, req.URL.Path),
+	}
+}
+
+// methodNotAllowedError returns the typed error describing a request whose path matches routes
+// registered for the allowed methods only.
+func methodNotAllowedError(req *http.Request, allowed string) *TypedError {
+	return &TypedError{
+		ID:   ErrMethodNotAllowed,
+		Mesg: fmt.Sprintf("method %s is not allowed, allowed methods are %s", req.Method, allowed),
+	}
+}
+
+// handleNotFound is the default mux handler for requests whose path matches no route.
+func handleNotFound(rw http.ResponseWriter, req *http.Request, params url.Values) {
+	respondProblem(rw, notFoundError(req))
+}
+
+// handleMethodNotAllowed is the default mux handler for requests whose path matches routes
+// registered for other methods.
+func handleMethodNotAllowed(rw http.ResponseWriter, req *http.Request, params url.Values) {
+	respondProblem(rw, methodNotAllowedError(req, rw.Header().Get("Allow")))
+}
+
+// respondProblem writes the JSON representation of the problem that describes the given error
+// using the problem status. It is used by the mux when it is not set up by a service.
+func respondProblem(rw http.ResponseWriter, err error) {
+	p := NewProblem(err)
+	b, err := json.Marshal(p)
+	if err != nil {
+		b = []byte(p.Title)
+	}
+	rw.Header().Set("Content-Type", ProblemJSONContentType)
+	rw.WriteHeader(p.Status)
+	rw.Write(b)
+}
+
+// byVersionPathMethod makes it possible to sort routes by version, path and method.
+type byVersionPathMethod []Route
+
+func (r byVersionPathMethod) Len() int      { return len(r) }
+func (r byVersionPathMethod) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r byVersionPathMethod) Less(i, j int) bool {
+	if r[i].Version != r[j].Version {
+		return r[i].Version < r[j].Version
+	}
+	if r[i].Path != r[j].Path {
+		return r[i].Path < r[j].Path
+	}
+	return r[i].Method < r[j].Method
+}
@@ -1,7 +1,11 @@
 package goa_test
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -52,3 +56,256 @@ var _ = Describe("PathSelectVersionFunc", func() {
 	})
 
 })
+
+var _ = Describe("MediaTypeSelectVersionFunc", func() {
+	var accept, contentType string
+	var version string
+
+	BeforeEach(func() {
+		accept = ""
+		contentType = ""
+	})
+
+	JustBeforeEach(func() {
+		request, err := http.NewRequest("GET", "/foo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		if accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+		if contentType != "" {
+			request.Header.Set("Content-Type", contentType)
+		}
+		version = goa.MediaTypeSelectVersionFunc("version")(request)
+	})
+
+	Context("with a version parameter", func() {
+		BeforeEach(func() {
+			accept = "text/html, application/json; version=2.0"
+		})
+
+		It("selects the version", func() {
+			Ω(version).Should(Equal("2.0"))
+		})
+	})
+
+	Context("with a vendor media type suffix", func() {
+		BeforeEach(func() {
+			accept = "application/vnd.acme.v2+json"
+		})
+
+		It("selects the version", func() {
+			Ω(version).Should(Equal("v2"))
+		})
+	})
+
+	Context("with a versioned request content type", func() {
+		BeforeEach(func() {
+			accept = "application/json"
+			contentType = "application/json; charset=utf-8; version=1.0"
+		})
+
+		It("selects the version", func() {
+			Ω(version).Should(Equal("1.0"))
+		})
+	})
+
+	Context("with unversioned media types", func() {
+		BeforeEach(func() {
+			accept = "application/vnd.api+json"
+			contentType = "application/json"
+		})
+
+		It("routes to the unversioned controller", func() {
+			Ω(version).Should(Equal(""))
+		})
+	})
+})
+
+var _ = Describe("DefaultMux", func() {
+	var mux goa.ServeMux
+
+	BeforeEach(func() {
+		handle := func(http.ResponseWriter, *http.Request, url.Values) {}
+		mux = goa.NewMuxWithRouter(goa.NewTreeRouter)
+		mux.Handle("GET", "/users/:id", handle)
+		mux.Handle("GET", "/users/me", handle)
+		mux.Version("v1").Handle("POST", "/users", handle)
+		mux.Handle("DELETE", "/users/:id", handle)
+	})
+
+	It("lists the registered routes", func() {
+		Ω(mux.Routes()).Should(Equal([]goa.Route{
+			{Method: "DELETE", Path: "/users/:id"},
+			{Method: "GET", Path: "/users/:id"},
+			{Method: "GET", Path: "/users/me"},
+			{Method: "POST", Path: "/users", Version: "v1"},
+		}))
+	})
+
+	It("lists routes registered twice once", func() {
+		handle := func(http.ResponseWriter, *http.Request, url.Values) {}
+		mux := goa.NewMuxWithRouter(func() goa.Router { return &replacingRouter{} })
+		mux.Handle("OPTIONS", "/users", handle)
+		mux.Handle("OPTIONS", "/users", handle)
+		Ω(mux.Routes()).Should(Equal([]goa.Route{{Method: "OPTIONS", Path: "/users"}}))
+	})
+
+	It("lists the resource and action of the routes of controller handlers", func() {
+		service := goa.New("test")
+		service.SetServeMux(mux)
+		ctrl := service.NewController("UserController")
+		route := &goa.Route{Method: "PUT", Path: "/users/:id", Version: "v1", Resource: "user", Action: "update", MaxBodySize: 1024}
+		mux.Version("v1").Handle(route.Method, route.Path, ctrl.HandleRoute(route, "Update", func(*goa.Context) error { return nil }, nil))
+		Ω(mux.Routes()).Should(ContainElement(*route))
+	})
+
+	It("looks up the handlers by method and path", func() {
+		Ω(mux.Lookup("GET", "/users/me")).ShouldNot(BeNil())
+		Ω(mux.Lookup("POST", "/users")).Should(BeNil())
+		Ω(mux.Version("v1").Lookup("POST", "/users")).ShouldNot(BeNil())
+	})
+
+	It("adds the deprecation headers to the responses of deprecated versions", func() {
+		sunset := time.Date(2016, 6, 30, 0, 0, 0, 0, time.UTC)
+		mux.Version("v1").Deprecate(&goa.Deprecation{Sunset: sunset, Link: "http://example.com/v2"})
+		req, err := http.NewRequest("POST", "/v1/users", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw := httptest.NewRecorder()
+		mux.ServeHTTP(rw, req)
+		Ω(rw.Header().Get("Deprecation")).Should(Equal("true"))
+		Ω(rw.Header().Get("Sunset")).Should(Equal("Thu, 30 Jun 2016 00:00:00 GMT"))
+		Ω(rw.Header().Get("Link")).Should(Equal(`<http://example.com/v2>; rel="successor-version"`))
+
+		req, err = http.NewRequest("GET", "/api/users/me", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw = httptest.NewRecorder()
+		mux.ServeHTTP(rw, req)
+		Ω(rw.Header().Get("Deprecation")).Should(BeEmpty())
+	})
+
+	Context("selecting the version with the request media types", func() {
+		var handled int
+
+		BeforeEach(func() {
+			handled = 0
+			mux.Version("2.0").Handle("GET", "/users", func(http.ResponseWriter, *http.Request, url.Values) {
+				handled++
+			})
+			mux.(*goa.DefaultMux).SelectVersion(goa.MediaTypeSelectVersionFunc("version"))
+		})
+
+		It("routes the vendor suffix and parameter forms to the same version", func() {
+			for _, accept := range []string{"application/vnd.acme.v2+json", "application/json; version=2.0", "application/json; version=2"} {
+				req, err := http.NewRequest("GET", "/users", nil)
+				Ω(err).ShouldNot(HaveOccurred())
+				req.Header.Set("Accept", accept)
+				rw := httptest.NewRecorder()
+				mux.ServeHTTP(rw, req)
+				Ω(rw.Code).Should(Equal(200))
+			}
+			Ω(handled).Should(Equal(3))
+		})
+	})
+})
+
+var _ = Describe("DefaultMux method handling", func() {
+	var newRouter goa.RouterFactory
+	var mux goa.ServeMux
+	var handled string
+	var rw *httptest.ResponseRecorder
+
+	serve := func(method, path string) {
+		req, err := http.NewRequest(method, path, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw = httptest.NewRecorder()
+		mux.ServeHTTP(rw, req)
+	}
+
+	JustBeforeEach(func() {
+		handled = ""
+		handle := func(name string) goa.HandleFunc {
+			return func(rw http.ResponseWriter, req *http.Request, p url.Values) {
+				handled = name
+				rw.Write([]byte(name))
+			}
+		}
+		mux = goa.NewMuxWithRouter(newRouter)
+		mux.Handle("GET", "/users/:id", handle("show"))
+		mux.Handle("DELETE", "/users/:id", handle("delete"))
+		mux.Handle("GET", "/users", handle("list"))
+		mux.Handle("HEAD", "/users", handle("head"))
+	})
+
+	for name, factory := range map[string]goa.RouterFactory{"httprouter": goa.NewHTTPRouter, "tree": goa.NewTreeRouter} {
+		factory := factory
+		Context("using the "+name+" router", func() {
+			BeforeEach(func() {
+				newRouter = factory
+			})
+
+			It("handles HEAD requests with the GET handler", func() {
+				serve("HEAD", "/users/42")
+				Ω(handled).Should(Equal("show"))
+				Ω(rw.Code).Should(Equal(200))
+			})
+
+			It("uses the registered HEAD handler", func() {
+				serve("HEAD", "/users")
+				Ω(handled).Should(Equal("head"))
+			})
+
+			It("answers OPTIONS requests with the allowed methods", func() {
+				serve("OPTIONS", "/users/42")
+				Ω(handled).Should(BeEmpty())
+				Ω(rw.Code).Should(Equal(200))
+				Ω(rw.Header().Get("Allow")).Should(Equal("DELETE, GET, HEAD, OPTIONS"))
+			})
+
+			It("responds with 405 and a problem to requests using a method that is not allowed", func() {
+				serve("POST", "/users/42")
+				Ω(handled).Should(BeEmpty())
+				Ω(rw.Code).Should(Equal(405))
+				Ω(rw.Header().Get("Allow")).Should(Equal("DELETE, GET, HEAD, OPTIONS"))
+				Ω(rw.Header().Get("Content-Type")).Should(Equal(goa.ProblemJSONContentType))
+				var problem goa.Problem
+				Ω(json.Unmarshal(rw.Body.Bytes(), &problem)).ShouldNot(HaveOccurred())
+				Ω(problem.Type).Should(Equal("urn:goa:error:method-not-allowed"))
+				Ω(problem.Title).Should(Equal("method not allowed"))
+				Ω(problem.Status).Should(Equal(405))
+			})
+
+			It("responds with 404 and a problem to requests that match no route", func() {
+				serve("GET", "/posts")
+				Ω(handled).Should(BeEmpty())
+				Ω(rw.Code).Should(Equal(404))
+				Ω(rw.Header().Get("Content-Type")).Should(Equal(goa.ProblemJSONContentType))
+				var problem goa.Problem
+				Ω(json.Unmarshal(rw.Body.Bytes(), &problem)).ShouldNot(HaveOccurred())
+				Ω(problem.Type).Should(Equal("urn:goa:error:not-found"))
+				Ω(problem.Status).Should(Equal(404))
+			})
+		})
+	}
+})
+
+// replacingRouter is a router that replaces the handlers registered twice.
+type replacingRouter struct {
+	handles map[string]goa.HandleFunc
+}
+
+func (r *replacingRouter) Handle(method, path string, handle goa.HandleFunc) {
+	if r.handles == nil {
+		r.handles = make(map[string]goa.HandleFunc)
+	}
+	r.handles[method+path] = handle
+}
+
+func (r *replacingRouter) HandleNotFound(handle goa.HandleFunc) {}
+
+func (r *replacingRouter) HandleMethodNotAllowed(handle goa.HandleFunc) {}
+
+func (r *replacingRouter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if h, ok := r.handles[req.Method+req.URL.Path]; ok {
+		h(rw, req, req.URL.Query())
+	}
+}
@@ -0,0 +1,216 @@
+package goa
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type (
+	// Problem describes an error response body as defined by RFC 7807 "Problem Details for
+	// HTTP APIs". The default error handlers render errors as problems using NewProblem.
+	// Problem implements the error interface so that handlers may return a problem directly
+	// in which case it is rendered as is.
+	Problem struct {
+		XMLName xml.Name `json:"-" xml:"urn:ietf:rfc:7807 problem"`
+		// Type is a URI reference that identifies the problem type.
+		Type string `json:"type" xml:"type"`
+		// Title is a short, human-readable summary of the problem type.
+		Title string `json:"title" xml:"title"`
+		// Status is the HTTP status code of the response.
+		Status int `json:"status" xml:"status"`
+		// Detail is a human-readable explanation specific to this occurrence of the problem.
+		Detail string `json:"detail,omitempty" xml:"detail,omitempty"`
+		// Instance is a URI reference that identifies the specific occurrence of the problem.
+		Instance string `json:"instance,omitempty" xml:"instance,omitempty"`
+		// InvalidParams lists the request parameters, headers or payload attributes that
+		// failed validation.
+		InvalidParams []*InvalidParam `json:"invalid-params,omitempty" xml:"invalid-params>param,omitempty"`
+	}
+
+	// InvalidParam describes a request parameter, header or payload attribute that failed
+	// validation.
+	InvalidParam struct {
+		// Name is the name of the invalid parameter, header or attribute.
+		Name string `json:"name" xml:"name"`
+		// Reason describes why the value is invalid.
+		Reason string `json:"reason" xml:"reason"`
+	}
+)
+
+const (
+	// ProblemJSONContentType is the content type of JSON encoded problem responses.
+	ProblemJSONContentType = "application/problem+json"
+
+	// ProblemXMLContentType is the content type of XML encoded problem responses.
+	ProblemXMLContentType = "application/problem+xml"
+)
+
+// ProblemTypeBase is the prefix used to build the problem type URI of errors identified by an
+// ErrorID. The type URI consists of the prefix followed by the error title where spaces are
+// replaced with dashes, e.g. "urn:goa:error:invalid-parameter-value". Set it to the URL of the
+// API error documentation if there is one.
+var ProblemTypeBase = "urn:goa:error:"
+
+// NewProblem builds the problem that describes the given error:
+//
+// - a *Problem is returned as is.
+//
+// - a *TypedError produces a problem whose type and title are computed from the error ID.
+// The problem status is given by the error ID Status method and the invalid parameters list the
+// error field if there is one.
+//
+// - a MultiError produces a problem that combines the problems of each error. The type and title
+// are the ones of the first error if all the errors share the same ID, "about:blank" and the
+// status text otherwise. The status is the highest of all the errors statuses.
+//
+// - a *BadRequestError produces the problem of the error it wraps with a status of 400.
+//
+// - a *UnauthorizedError and a *ForbiddenError produce problems with status 401 and 403
+// respectively.
+//
+// - a *TimeoutError produces a problem with status 503.
+//
+// - any other error produces a problem with status 500 whose detail is the error message.
+func NewProblem(err error) *Problem {
+	switch e := err.(type) {
+	case *Problem:
+		return e
+	case *TypedError:
+		p := &Problem{
+			Type:   e.ID.TypeURI(),
+			Title:  e.ID.Title(),
+			Status: e.ID.Status(),
+			Detail: e.Mesg,
+		}
+		if e.Field != "" {
+			p.InvalidParams = []*InvalidParam{{Name: e.Field, Reason: e.Mesg}}
+		}
+		return p
+	case MultiError:
+		if len(e) == 1 {
+			return NewProblem(e[0])
+		}
+		p := &Problem{Type: "about:blank"}
+		details := make([]string, len(e))
+		same := true
+		for i, err := range e {
+			pi := NewProblem(err)
+			if i == 0 {
+				p.Type, p.Title = pi.Type, pi.Title
+			} else if pi.Type != p.Type {
+				same = false
+			}
+			if pi.Status > p.Status {
+				p.Status = pi.Status
+			}
+			details[i] = pi.Detail
+			p.InvalidParams = append(p.InvalidParams, pi.InvalidParams...)
+		}
+		if len(e) == 0 {
+			p.Status = http.StatusBadRequest
+		}
+		if !same || len(e) == 0 {
+			p.Type = "about:blank"
+			p.Title = http.StatusText(p.Status)
+		}
+		p.Detail = strings.Join(details, ", ")
+		return p
+	case *BadRequestError:
+		p := NewProblem(e.Actual)
+		if p.Status != http.StatusBadRequest {
+			p.Status = http.StatusBadRequest
+			if p.Type == "about:blank" {
+				p.Title = http.StatusText(p.Status)
+			}
+		}
+		return p
+	case *UnauthorizedError:
+		return newSecurityProblem(http.StatusUnauthorized, e)
+	case *ForbiddenError:
+		return newSecurityProblem(http.StatusForbidden, e)
+	case *TimeoutError:
+		return &Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusServiceUnavailable),
+			Status: http.StatusServiceUnavailable,
+			Detail: e.Error(),
+		}
+	default:
+		return &Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+		}
+	}
+}
+
+// Error returns the problem detail or title if there is no detail.
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// EncodeProblem writes the given problem to the response using the status code of the problem.
+// The response content type is negotiated using the request Accept header: it is
+// "application/problem+json" or "application/problem+xml" if the client accepts these (and
+// the corresponding JSON or XML encoder is registered) or any of the registered encoders
+// content types. The JSON encoder is used with the "application/problem+json" content type when
+// the client does not express a preference. The default encoder is used with the corresponding
+// problem content type if there is no encoder for the negotiated content type. EncodeProblem
+// returns an error and does not write the response if there is no default encoder either.
+func (app *Application) EncodeProblem(ctx *Context, p *Problem) error {
+	contentType := ProblemJSONContentType
+	pool := app.encoderPools["application/json"]
+	if req := ctx.Request(); req != nil {
+		offers := []string{ProblemJSONContentType}
+		if _, ok := app.encoderPools["application/xml"]; ok {
+			offers = append(offers, ProblemXMLContentType)
+		}
+		for _, ct := range app.encodableContentTypes {
+			if ct != "*/*" {
+				offers = append(offers, ct)
+			}
+		}
+		if contentType = negotiateContentType(req, offers); contentType == "" {
+			contentType = ProblemJSONContentType
+		}
+		switch contentType {
+		case ProblemJSONContentType:
+		case ProblemXMLContentType:
+			pool = app.encoderPools["application/xml"]
+		default:
+			pool = app.encoderPools[contentType]
+		}
+	}
+	if pool == nil {
+		pool = app.encoderPools["*/*"]
+		contentType = problemContentType(app.defaultContentType)
+	}
+	if pool == nil {
+		return fmt.Errorf("no encoder registered for content type %s", contentType)
+	}
+	if header := ctx.Header(); header != nil {
+		header.Set("Content-Type", contentType)
+	}
+	ctx.WriteHeader(p.Status)
+	encoder := pool.Get(ctx)
+	defer pool.Put(encoder)
+	return encoder.Encode(p)
+}
+
+// problemContentType returns the problem details content type corresponding to the given encoder
+// content type.
+func problemContentType(contentType string) string {
+	switch contentType {
+	case "application/xml":
+		return ProblemXMLContentType
+	case "", "application/json":
+		return ProblemJSONContentType
+	}
+	return contentType
+}
@@ -0,0 +1,145 @@
+package goa_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+var _ = Describe("NewProblem", func() {
+	var err error
+	var problem *goa.Problem
+
+	JustBeforeEach(func() {
+		problem = goa.NewProblem(err)
+	})
+
+	Context("with a typed error", func() {
+		BeforeEach(func() {
+			err = goa.MissingParamError("id", nil)
+		})
+
+		It("uses the error ID to build the problem", func() {
+			kind := goa.ErrorID(goa.ErrMissingParam)
+			Ω(problem.Type).Should(Equal("urn:goa:error:missing-required-parameter"))
+			Ω(problem.Title).Should(Equal(kind.Title()))
+			Ω(problem.Status).Should(Equal(400))
+			Ω(problem.Detail).Should(Equal(`missing required parameter "id"`))
+			Ω(problem.InvalidParams).Should(HaveLen(1))
+			Ω(problem.InvalidParams[0].Name).Should(Equal("id"))
+			Ω(problem.InvalidParams[0].Reason).Should(Equal(problem.Detail))
+		})
+	})
+
+	Context("with multiple typed errors", func() {
+		BeforeEach(func() {
+			err = goa.MissingParamError("id", nil)
+			err = goa.MissingHeaderError("X-Foo", err)
+			err = goa.NewBadRequestError(err)
+		})
+
+		It("merges the errors", func() {
+			Ω(problem.Type).Should(Equal("about:blank"))
+			Ω(problem.Title).Should(Equal(http.StatusText(400)))
+			Ω(problem.Status).Should(Equal(400))
+			Ω(problem.InvalidParams).Should(HaveLen(2))
+			Ω(problem.InvalidParams[0].Name).Should(Equal("id"))
+			Ω(problem.InvalidParams[1].Name).Should(Equal("X-Foo"))
+		})
+	})
+
+	Context("with a timeout error", func() {
+		BeforeEach(func() {
+			err = &goa.TimeoutError{}
+		})
+
+		It("uses status code 503", func() {
+			Ω(problem.Status).Should(Equal(503))
+		})
+	})
+
+	Context("with a generic error", func() {
+		BeforeEach(func() {
+			err = errors.New("boom")
+		})
+
+		It("uses status code 500", func() {
+			Ω(problem.Type).Should(Equal("about:blank"))
+			Ω(problem.Title).Should(Equal(http.StatusText(500)))
+			Ω(problem.Status).Should(Equal(500))
+			Ω(problem.Detail).Should(Equal("boom"))
+		})
+	})
+})
+
+var _ = Describe("EncodeProblem", func() {
+	var accept string
+	var rw *TestResponseWriter
+	problem := &goa.Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "foo"}
+
+	BeforeEach(func() {
+		accept = ""
+	})
+
+	JustBeforeEach(func() {
+		req, err := http.NewRequest("GET", "/foo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rw = &TestResponseWriter{ParentHeader: make(http.Header)}
+		ctx := goa.NewContext(nil, goa.New("test"), req, rw, nil)
+		Ω(ctx.RespondProblem(problem)).ShouldNot(HaveOccurred())
+	})
+
+	It("defaults to problem+json", func() {
+		Ω(rw.Status).Should(Equal(404))
+		Ω(rw.ParentHeader.Get("Content-Type")).Should(Equal(goa.ProblemJSONContentType))
+		var p goa.Problem
+		Ω(json.Unmarshal(rw.Body, &p)).ShouldNot(HaveOccurred())
+		Ω(p.Detail).Should(Equal("foo"))
+	})
+
+	Context("with a request that accepts problem+xml", func() {
+		BeforeEach(func() {
+			accept = goa.ProblemXMLContentType
+		})
+
+		It("uses the XML encoder", func() {
+			Ω(rw.ParentHeader.Get("Content-Type")).Should(Equal(goa.ProblemXMLContentType))
+			var p goa.Problem
+			Ω(xml.Unmarshal(rw.Body, &p)).ShouldNot(HaveOccurred())
+			Ω(p.Detail).Should(Equal("foo"))
+		})
+	})
+
+	Context("with a service that has no encoder", func() {
+		It("returns an error without writing the response", func() {
+			req, err := http.NewRequest("GET", "/foo", nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			rw := &TestResponseWriter{ParentHeader: make(http.Header)}
+			ctx := goa.NewContext(nil, &goa.Application{}, req, rw, nil)
+			Ω(ctx.RespondProblem(problem)).Should(HaveOccurred())
+			Ω(rw.Status).Should(Equal(0))
+			Ω(rw.ParentHeader).ShouldNot(HaveKey("Content-Type"))
+		})
+	})
+
+	Context("with a request that accepts a registered content type", func() {
+		BeforeEach(func() {
+			accept = "application/xml"
+		})
+
+		It("uses the corresponding encoder", func() {
+			Ω(rw.ParentHeader.Get("Content-Type")).Should(Equal("application/xml"))
+			var p goa.Problem
+			Ω(xml.Unmarshal(rw.Body, &p)).ShouldNot(HaveOccurred())
+			Ω(p.Status).Should(Equal(404))
+		})
+	})
+})
@@ -0,0 +1,219 @@
+package goa
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// RateLimiter limits the rate of the requests sent by each client using token buckets: each
+	// client gets a bucket per limit which holds up to the limit number of requests and refills
+	// continuously over the limit period. Requests are rejected with a 429 Too Many Requests
+	// error when the client bucket is empty.
+	RateLimiter struct {
+		// Store holds the token buckets.
+		Store RateLimitStore
+		// Key computes the key that identifies the client that sent the request.
+		Key RateLimitKeyFunc
+	}
+
+	// RateLimitKeyFunc computes the key that identifies the client that sent a request. Requests
+	// for which the func returns an empty key are not rate limited.
+	RateLimitKeyFunc func(ctx *Context) string
+
+	// RateLimitStore is the interface implemented by the stores that hold the rate limiter token
+	// buckets. The goa package provides an in-memory implementation with NewMemoryRateLimitStore,
+	// implementations backed by shared stores make it possible to enforce limits across
+	// multiple service instances.
+	RateLimitStore interface {
+		// Take takes a token from the bucket with the given key, the bucket holds up to
+		// requests tokens and refills completely over period. Take creates a full bucket
+		// if there is none.
+		Take(key string, requests int, period time.Duration) (*RateLimitStatus, error)
+	}
+
+	// RateLimitStatus describes the state of a token bucket after a request tried to take a
+	// token from it.
+	RateLimitStatus struct {
+		// Allowed is true if the bucket had a token for the request.
+		Allowed bool
+		// Remaining is the number of tokens left in the bucket.
+		Remaining int
+		// Reset is the time until the bucket is full.
+		Reset time.Duration
+		// RetryAfter is the time until the bucket has a token if Allowed is false.
+		RetryAfter time.Duration
+	}
+
+	// memoryRateLimitStore is the in-memory RateLimitStore implementation.
+	memoryRateLimitStore struct {
+		mu         sync.Mutex
+		maxBuckets int
+		buckets    map[string]*list.Element
+		lru        *list.List
+		lastSweep  time.Time
+	}
+
+	// tokenBucket is a token bucket held by memoryRateLimitStore.
+	tokenBucket struct {
+		key     string
+		tokens  float64
+		updated time.Time
+		full    time.Time
+	}
+)
+
+// DefaultRateLimiter is the rate limiter used by RateLimit. It keeps up to 10000 token buckets in
+// memory and identifies clients by their IP address, set its Store and Key fields to change that.
+var DefaultRateLimiter = &RateLimiter{
+	Store: NewMemoryRateLimitStore(10000),
+	Key:   RemoteIPRateLimitKey,
+}
+
+// RateLimit returns a middleware that limits the rate of the requests sent by each client to the
+// given number of requests per period using DefaultRateLimiter. The name identifies the limit so
+// that handlers using limits with different names do not share token buckets.
+// goagen generates code that uses RateLimit for actions whose design defines the "ratelimit"
+// metadata, for example:
+//
+//	Action("show", func() {
+//		Metadata("ratelimit", "100/1m")
+//	})
+//
+// limits the number of requests sent by each client to the show action to 100 per minute.
+func RateLimit(name string, requests int, period time.Duration) Middleware {
+	return DefaultRateLimiter.Limit(name, requests, period)
+}
+
+// Limit returns a middleware that limits the rate of the requests sent by each client to the given
+// number of requests per period. The name identifies the limit so that handlers using limits with
+// different names do not share token buckets.
+// The middleware sets the "X-RateLimit-Limit", "X-RateLimit-Remaining" and "X-RateLimit-Reset"
+// response headers, the latter is the number of seconds until the client bucket is full. Requests
+// sent by clients whose bucket is empty are rejected with an error with ID ErrTooManyRequests and
+// a "Retry-After" response header.
+func (l *RateLimiter) Limit(name string, requests int, period time.Duration) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx *Context) error {
+			key := l.Key(ctx)
+			if key == "" {
+				return h(ctx)
+			}
+			status, err := l.Store.Take(name+":"+key, requests, period)
+			if err != nil {
+				return fmt.Errorf("failed to apply rate limit %s: %s", name, err)
+			}
+			if header := ctx.Header(); header != nil {
+				header.Set("X-RateLimit-Limit", strconv.Itoa(requests))
+				header.Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+				header.Set("X-RateLimit-Reset", strconv.Itoa(seconds(status.Reset)))
+				if !status.Allowed {
+					header.Set("Retry-After", strconv.Itoa(seconds(status.RetryAfter)))
+				}
+			}
+			if !status.Allowed {
+				return &TypedError{
+					ID:   ErrTooManyRequests,
+					Mesg: fmt.Sprintf("rate limit of %d requests per %s exceeded", requests, period),
+				}
+			}
+			return h(ctx)
+		}
+	}
+}
+
+// RemoteIPRateLimitKey is a RateLimitKeyFunc that identifies clients by the IP address of the
+// request remote address. Use HeaderRateLimitKey to identify clients by the IP address set by a
+// proxy in a request header.
+func RemoteIPRateLimitKey(ctx *Context) string {
+	addr := ctx.Request().RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// HeaderRateLimitKey returns a RateLimitKeyFunc that identifies clients by the value of the
+// request header with the given name, e.g. an API key header.
+func HeaderRateLimitKey(name string) RateLimitKeyFunc {
+	return func(ctx *Context) string {
+		return ctx.Request().Header.Get(name)
+	}
+}
+
+// NewMemoryRateLimitStore returns a RateLimitStore that holds up to maxBuckets token buckets in
+// memory. The store removes the buckets once they are full and discards the least recently used
+// buckets first when it is full, so that clients cannot exhaust the memory by sending requests
+// with many different keys.
+func NewMemoryRateLimitStore(maxBuckets int) RateLimitStore {
+	return &memoryRateLimitStore{
+		maxBuckets: maxBuckets,
+		buckets:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Take takes a token from the bucket with the given key.
+func (s *memoryRateLimitStore) Take(key string, requests int, period time.Duration) (*RateLimitStatus, error) {
+	if requests <= 0 || period <= 0 {
+		return nil, fmt.Errorf("invalid rate limit of %d requests per %s", requests, period)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.sweep(now)
+	interval := period / time.Duration(requests)
+	var b *tokenBucket
+	if elem, ok := s.buckets[key]; ok {
+		b = elem.Value.(*tokenBucket)
+		s.lru.MoveToFront(elem)
+	} else {
+		b = &tokenBucket{key: key, tokens: float64(requests), updated: now}
+		s.buckets[key] = s.lru.PushFront(b)
+		for s.maxBuckets > 0 && s.lru.Len() > s.maxBuckets {
+			s.remove(s.lru.Back())
+		}
+	}
+	b.tokens = math.Min(float64(requests), b.tokens+float64(now.Sub(b.updated))/float64(interval))
+	b.updated = now
+	status := &RateLimitStatus{}
+	if b.tokens >= 1 {
+		b.tokens--
+		status.Allowed = true
+	} else {
+		status.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+	status.Remaining = int(b.tokens)
+	status.Reset = time.Duration((float64(requests) - b.tokens) * float64(interval))
+	b.full = now.Add(status.Reset)
+	return status, nil
+}
+
+// sweep removes the buckets that are full, it runs at most once a minute.
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for _, elem := range s.buckets {
+		if !now.Before(elem.Value.(*tokenBucket).full) {
+			s.remove(elem)
+		}
+	}
+}
+
+// remove removes the given element from the store.
+func (s *memoryRateLimitStore) remove(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.buckets, elem.Value.(*tokenBucket).key)
+}
+
+// seconds returns the given duration in seconds rounded up.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
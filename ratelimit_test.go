@@ -0,0 +1,110 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+var _ = Describe("RateLimiter", func() {
+	var limiter *goa.RateLimiter
+	var remoteAddr string
+	var handled int
+
+	serve := func() (*httptest.ResponseRecorder, error) {
+		req, err := http.NewRequest("GET", "/bottles", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		ctx := goa.NewContext(nil, goa.New("test"), req, rec, url.Values{})
+		h := func(ctx *goa.Context) error {
+			handled++
+			return nil
+		}
+		return rec, limiter.Limit("list", 2, time.Hour)(h)(ctx)
+	}
+
+	BeforeEach(func() {
+		limiter = &goa.RateLimiter{
+			Store: goa.NewMemoryRateLimitStore(10),
+			Key:   goa.RemoteIPRateLimitKey,
+		}
+		remoteAddr = "10.0.0.1:4242"
+		handled = 0
+	})
+
+	It("lets requests through until the client bucket is empty", func() {
+		rec, err := serve()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(rec.Header().Get("X-RateLimit-Limit")).Should(Equal("2"))
+		Ω(rec.Header().Get("X-RateLimit-Remaining")).Should(Equal("1"))
+		Ω(rec.Header().Get("X-RateLimit-Reset")).Should(Equal("1800"))
+
+		rec, err = serve()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(rec.Header().Get("X-RateLimit-Remaining")).Should(Equal("0"))
+
+		rec, err = serve()
+		Ω(err).Should(HaveOccurred())
+		terr, ok := err.(*goa.TypedError)
+		Ω(ok).Should(BeTrue())
+		Ω(terr.ID).Should(BeEquivalentTo(goa.ErrTooManyRequests))
+		Ω(terr.ID.Status()).Should(Equal(429))
+		Ω(rec.Header().Get("Retry-After")).Should(Equal("1800"))
+		Ω(handled).Should(Equal(2))
+	})
+
+	It("uses a bucket per client", func() {
+		for i := 0; i < 2; i++ {
+			_, err := serve()
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+		remoteAddr = "10.0.0.2:4242"
+		_, err := serve()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(handled).Should(Equal(3))
+	})
+
+	Context("with a key func that does not identify the client", func() {
+		BeforeEach(func() {
+			limiter.Key = goa.HeaderRateLimitKey("X-Api-Key")
+		})
+
+		It("does not limit the requests", func() {
+			for i := 0; i < 3; i++ {
+				rec, err := serve()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(rec.Header()).ShouldNot(HaveKey("X-Ratelimit-Limit"))
+			}
+			Ω(handled).Should(Equal(3))
+		})
+	})
+})
+
+var _ = Describe("NewMemoryRateLimitStore", func() {
+	var store goa.RateLimitStore
+
+	BeforeEach(func() {
+		store = goa.NewMemoryRateLimitStore(2)
+	})
+
+	take := func(key string) bool {
+		status, err := store.Take(key, 1, time.Hour)
+		Ω(err).ShouldNot(HaveOccurred())
+		return status.Allowed
+	}
+
+	It("discards the least recently used buckets", func() {
+		Ω(take("a")).Should(BeTrue())
+		Ω(take("b")).Should(BeTrue())
+		Ω(take("a")).Should(BeFalse())
+		Ω(take("c")).Should(BeTrue())
+		Ω(take("a")).Should(BeFalse())
+		Ω(take("b")).Should(BeTrue())
+	})
+})
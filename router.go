@@ -0,0 +1,257 @@
+package goa
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type (
+	// Router is the interface implemented by the request routers used by the default goa mux.
+	// Each API version gets its own router created with the RouterFactory given to
+	// NewMuxWithRouter. The goa package provides two implementations: NewHTTPRouter and
+	// NewTreeRouter.
+	Router interface {
+		http.Handler
+		// Handle registers the handler for the given HTTP method and path. The path may
+		// contain wildcards of the form ":name" matching a single path segment and a last
+		// segment of the form "*name" matching the rest of the path. The handler is given
+		// the values of the wildcards merged with the request querystring values.
+		Handle(method, path string, handle HandleFunc)
+		// HandleNotFound sets the handler called when the request path matches no route.
+		HandleNotFound(handle HandleFunc)
+		// HandleMethodNotAllowed sets the handler called when the request path matches
+		// routes registered for other HTTP methods. The router sets the "Allow" response
+		// header to the comma separated list of these methods and OPTIONS before calling
+		// the handler.
+		HandleMethodNotAllowed(handle HandleFunc)
+	}
+
+	// RouterFactory creates a router.
+	RouterFactory func() Router
+
+	// httpRouter is the Router implementation backed by httprouter.
+	httpRouter struct {
+		router *httprouter.Router
+	}
+
+	// treeRouter is the Router implementation backed by a tree of path segments.
+	treeRouter struct {
+		root             *routeNode
+		notFound         HandleFunc
+		methodNotAllowed HandleFunc
+	}
+
+	// routeNode is a node of the treeRouter tree, there is one node per path segment.
+	routeNode struct {
+		static   map[string]*routeNode
+		wildcard *routeNode
+		catchAll map[string]*treeRoute
+		routes   map[string]*treeRoute
+	}
+
+	// treeRoute is a route registered with the treeRouter, names contains the names of the
+	// wildcards in the order they appear in the path.
+	treeRoute struct {
+		path   string
+		names  []string
+		handle HandleFunc
+	}
+)
+
+// NewHTTPRouter returns a router that uses httprouter (https://github.com/julienschmidt/httprouter).
+// It is the router used by the mux returned by NewMux. Routes registered with the router must
+// use the same wildcard names at the same positions and cannot mix wildcards and static segments
+// at the same position, e.g. "/users/:id" and "/users/me".
+func NewHTTPRouter() Router {
+	router := httprouter.New()
+	// Let the method not allowed handler answer OPTIONS requests like the other routers do.
+	router.HandleOPTIONS = false
+	return &httpRouter{router: router}
+}
+
+// Handle sets the handler for the given verb and path.
+func (r *httpRouter) Handle(method, path string, handle HandleFunc) {
+	r.router.Handle(method, path, func(rw http.ResponseWriter, req *http.Request, htparams httprouter.Params) {
+		params := req.URL.Query()
+		for _, p := range htparams {
+			params.Set(p.Key, p.Value)
+		}
+		handle(rw, req, params)
+	})
+}
+
+// HandleNotFound sets the handler called when the request path matches no route.
+func (r *httpRouter) HandleNotFound(handle HandleFunc) {
+	r.router.NotFound = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		handle(rw, req, req.URL.Query())
+	})
+}
+
+// HandleMethodNotAllowed sets the handler called when the request path matches routes registered
+// for other HTTP methods.
+func (r *httpRouter) HandleMethodNotAllowed(handle HandleFunc) {
+	r.router.MethodNotAllowed = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		handle(rw, req, req.URL.Query())
+	})
+}
+
+// ServeHTTP dispatches the request to the handler registered for its method and path.
+func (r *httpRouter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	r.router.ServeHTTP(rw, req)
+}
+
+// NewTreeRouter returns a router that accepts routes rejected by httprouter: routes with
+// different wildcard names at the same position and routes with both a wildcard and a static
+// segment at the same position. Static segments take precedence over wildcards which take
+// precedence over catch-all wildcards so that given the routes "/users/:id" and "/users/me"
+// requests sent to "/users/me" are handled by the latter unless it has no handler for the request
+// method, in which case the router falls back to the former. Designs select this router with the
+// Router DSL, the main function generated by goagen then uses it to create the service mux.
+func NewTreeRouter() Router {
+	return &treeRouter{root: new(routeNode)}
+}
+
+// Handle sets the handler for the given verb and path. It panics if a handler is already
+// registered for a path that matches the same requests.
+func (r *treeRouter) Handle(method, path string, handle HandleFunc) {
+	if !strings.HasPrefix(path, "/") {
+		panic(fmt.Sprintf("goa: path %#v must begin with /", path))
+	}
+	route := &treeRoute{path: path, handle: handle}
+	node := r.root
+	segments := strings.Split(path[1:], "/")
+	for i, s := range segments {
+		switch {
+		case strings.HasPrefix(s, "*"):
+			if i != len(segments)-1 {
+				panic(fmt.Sprintf("goa: catch-all wildcard must be the last segment of path %#v", path))
+			}
+			route.names = append(route.names, s[1:])
+			if node.catchAll == nil {
+				node.catchAll = make(map[string]*treeRoute)
+			}
+			addRoute(node.catchAll, method, route)
+			return
+		case strings.HasPrefix(s, ":"):
+			route.names = append(route.names, s[1:])
+			if node.wildcard == nil {
+				node.wildcard = new(routeNode)
+			}
+			node = node.wildcard
+		default:
+			if node.static == nil {
+				node.static = make(map[string]*routeNode)
+			}
+			child, ok := node.static[s]
+			if !ok {
+				child = new(routeNode)
+				node.static[s] = child
+			}
+			node = child
+		}
+	}
+	if node.routes == nil {
+		node.routes = make(map[string]*treeRoute)
+	}
+	addRoute(node.routes, method, route)
+}
+
+// HandleNotFound sets the handler called when the request path matches no route.
+func (r *treeRouter) HandleNotFound(handle HandleFunc) {
+	r.notFound = handle
+}
+
+// HandleMethodNotAllowed sets the handler called when the request path matches routes registered
+// for other HTTP methods.
+func (r *treeRouter) HandleMethodNotAllowed(handle HandleFunc) {
+	r.methodNotAllowed = handle
+}
+
+// ServeHTTP dispatches the request to the handler registered for its method and path. It calls
+// the handler set with HandleMethodNotAllowed, or responds with 405 Method Not Allowed if there is
+// none, if handlers are registered for the path but not for the request method. It calls the
+// handler set with HandleNotFound, or responds with 404 Not Found if there is none, if no handler
+// is registered for the path.
+func (r *treeRouter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	var route *treeRoute
+	var values []string
+	allowed := make(map[string]bool)
+	if path := req.URL.Path; strings.HasPrefix(path, "/") {
+		route, values = r.root.match(req.Method, strings.Split(path[1:], "/"), nil, allowed)
+	}
+	if route == nil {
+		if len(allowed) > 0 {
+			allowed["OPTIONS"] = true
+			methods := make([]string, 0, len(allowed))
+			for m := range allowed {
+				methods = append(methods, m)
+			}
+			sort.Strings(methods)
+			rw.Header().Set("Allow", strings.Join(methods, ", "))
+			if r.methodNotAllowed != nil {
+				r.methodNotAllowed(rw, req, req.URL.Query())
+				return
+			}
+			http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if r.notFound != nil {
+			r.notFound(rw, req, req.URL.Query())
+			return
+		}
+		http.NotFound(rw, req)
+		return
+	}
+	params := req.URL.Query()
+	for i, name := range route.names {
+		params.Set(name, values[i])
+	}
+	route.handle(rw, req, params)
+}
+
+// addRoute registers route with the given method in routes.
+func addRoute(routes map[string]*treeRoute, method string, route *treeRoute) {
+	if other, ok := routes[method]; ok {
+		panic(fmt.Sprintf("goa: %s %s conflicts with %s %s", method, route.path, method, other.path))
+	}
+	routes[method] = route
+}
+
+// match returns the route registered for the given method and path segments and the values of
+// its wildcards. It tries the static segment first, then the wildcard and finally the catch-all
+// wildcard so that a node that matches the path but has no route for the method does not hide
+// the routes of the other branches. The methods of the routes that match the path are recorded in
+// allowed.
+func (n *routeNode) match(method string, segments, values []string, allowed map[string]bool) (*treeRoute, []string) {
+	if len(segments) == 0 {
+		return lookupRoute(n.routes, method, values, allowed)
+	}
+	s, rest := segments[0], segments[1:]
+	if child, ok := n.static[s]; ok {
+		if route, vals := child.match(method, rest, values, allowed); route != nil {
+			return route, vals
+		}
+	}
+	if n.wildcard != nil && s != "" {
+		if route, vals := n.wildcard.match(method, rest, append(values[:len(values):len(values)], s), allowed); route != nil {
+			return route, vals
+		}
+	}
+	return lookupRoute(n.catchAll, method, append(values[:len(values):len(values)], "/"+strings.Join(segments, "/")), allowed)
+}
+
+// lookupRoute returns the route registered for method in routes and the given wildcard values, it
+// records the methods of the routes in allowed if there is none.
+func lookupRoute(routes map[string]*treeRoute, method string, values []string, allowed map[string]bool) (*treeRoute, []string) {
+	if route, ok := routes[method]; ok {
+		return route, values
+	}
+	for m := range routes {
+		allowed[m] = true
+	}
+	return nil, nil
+}
@@ -0,0 +1,104 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+var _ = Describe("TreeRouter", func() {
+	var router goa.Router
+	var handled string
+	var params url.Values
+
+	handle := func(name string) goa.HandleFunc {
+		return func(rw http.ResponseWriter, req *http.Request, p url.Values) {
+			handled = name
+			params = p
+		}
+	}
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	BeforeEach(func() {
+		handled = ""
+		params = nil
+		router = goa.NewTreeRouter()
+		router.Handle("GET", "/users/:id", handle("show"))
+		router.Handle("GET", "/users/me", handle("me"))
+		router.Handle("GET", "/users/:userID/posts", handle("posts"))
+		router.Handle("DELETE", "/users/:id", handle("delete"))
+		router.Handle("GET", "/files/*filepath", handle("files"))
+	})
+
+	It("prefers static segments over wildcards", func() {
+		serve("GET", "/users/me")
+		Ω(handled).Should(Equal("me"))
+		serve("GET", "/users/42?view=full")
+		Ω(handled).Should(Equal("show"))
+		Ω(params.Get("id")).Should(Equal("42"))
+		Ω(params.Get("view")).Should(Equal("full"))
+	})
+
+	It("falls back to wildcards when the static segment has no route for the method", func() {
+		rw := serve("DELETE", "/users/me")
+		Ω(rw.Code).Should(Equal(200))
+		Ω(handled).Should(Equal("delete"))
+		Ω(params.Get("id")).Should(Equal("me"))
+	})
+
+	It("falls back to catch-all wildcards when the other routes do not have the method", func() {
+		router.Handle("POST", "/files/*path", handle("upload"))
+		router.Handle("GET", "/files/css/main.css", handle("css"))
+		serve("POST", "/files/css/main.css")
+		Ω(handled).Should(Equal("upload"))
+		Ω(params.Get("path")).Should(Equal("/css/main.css"))
+	})
+
+	It("supports different wildcard names at the same position", func() {
+		serve("GET", "/users/42/posts")
+		Ω(handled).Should(Equal("posts"))
+		Ω(params.Get("userID")).Should(Equal("42"))
+		Ω(params).ShouldNot(HaveKey("id"))
+	})
+
+	It("matches the rest of the path with catch-all wildcards", func() {
+		serve("GET", "/files/css/main.css")
+		Ω(handled).Should(Equal("files"))
+		Ω(params.Get("filepath")).Should(Equal("/css/main.css"))
+	})
+
+	It("responds with 405 to requests using an unsupported method", func() {
+		rw := serve("POST", "/users/42")
+		Ω(handled).Should(BeEmpty())
+		Ω(rw.Code).Should(Equal(405))
+		Ω(rw.Header().Get("Allow")).Should(Equal("DELETE, GET, OPTIONS"))
+	})
+
+	It("lists the methods of all the matching routes in the Allow header", func() {
+		rw := serve("POST", "/users/me")
+		Ω(handled).Should(BeEmpty())
+		Ω(rw.Code).Should(Equal(405))
+		Ω(rw.Header().Get("Allow")).Should(Equal("DELETE, GET, OPTIONS"))
+	})
+
+	It("responds with 404 to requests that match no route", func() {
+		rw := serve("GET", "/users")
+		Ω(handled).Should(BeEmpty())
+		Ω(rw.Code).Should(Equal(404))
+	})
+
+	It("panics when a route is registered twice", func() {
+		Ω(func() { router.Handle("GET", "/users/:name", handle("dup")) }).Should(Panic())
+	})
+})
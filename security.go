@@ -0,0 +1,86 @@
+package goa
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type (
+	// UnauthorizedError is the error returned by security middleware when the request does not
+	// carry valid credentials for the security scheme. It produces a 401 response.
+	UnauthorizedError struct {
+		// Scheme is the name of the security scheme that rejected the request.
+		Scheme string
+		// Reason describes why the credentials were rejected.
+		Reason string
+	}
+
+	// ForbiddenError is the error returned by security middleware when the request credentials
+	// are valid but do not grant the scopes required by the action. It produces a 403
+	// response.
+	ForbiddenError struct {
+		// Scheme is the name of the security scheme that rejected the request.
+		Scheme string
+		// Scopes lists the scopes required by the action.
+		Scopes []string
+	}
+)
+
+// RequireSecurity returns a middleware that enforces the security scheme with the given name.
+// The middleware records the scopes required by the action in the context (see
+// Context.RequiredScopes) and runs the middleware registered with the service for the scheme
+// using SetSecurityMiddleware. It fails with an internal error if no middleware is registered.
+// Code generated by goagen uses RequireSecurity to wrap the handlers of secured actions.
+func RequireSecurity(scheme string, scopes ...string) Middleware {
+	return func(h Handler) Handler {
+		return func(ctx *Context) error {
+			m := ctx.Service().SecurityMiddleware(scheme)
+			if m == nil {
+				return fmt.Errorf("no middleware registered for security scheme %s", scheme)
+			}
+			ctx.SetValue(securityScopesKey, scopes)
+			return m(h)(ctx)
+		}
+	}
+}
+
+// SetSecurityMiddleware registers the middleware that validates the request credentials for the
+// security scheme with the given name. The middleware should return an *UnauthorizedError if the
+// credentials are missing or invalid and a *ForbiddenError if they do not grant the scopes
+// returned by Context.RequiredScopes.
+func (app *Application) SetSecurityMiddleware(scheme string, m Middleware) {
+	if app.securityMiddleware == nil {
+		app.securityMiddleware = make(map[string]Middleware)
+	}
+	app.securityMiddleware[scheme] = m
+}
+
+// SecurityMiddleware returns the middleware registered for the security scheme with the given
+// name, nil if there is none.
+func (app *Application) SecurityMiddleware(scheme string) Middleware {
+	return app.securityMiddleware[scheme]
+}
+
+// Error returns the error message.
+func (e *UnauthorizedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("%s: unauthorized", e.Scheme)
+	}
+	return fmt.Sprintf("%s: unauthorized: %s", e.Scheme, e.Reason)
+}
+
+// Error returns the error message.
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("%s: missing required scopes %s", e.Scheme, strings.Join(e.Scopes, ", "))
+}
+
+// newSecurityProblem builds the problem that describes a security error.
+func newSecurityProblem(status int, err error) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+	}
+}
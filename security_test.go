@@ -0,0 +1,88 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+var _ = Describe("RequireSecurity", func() {
+	const scheme = "jwt"
+	var scopes []string
+	var service goa.Service
+	var ctx *goa.Context
+	var handlerCalled bool
+	var requiredScopes []string
+	var err error
+
+	BeforeEach(func() {
+		scopes = []string{"read", "write"}
+		service = goa.New("test")
+		req, err := http.NewRequest("GET", "/goo", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		ctx = goa.NewContext(nil, service, req, new(TestResponseWriter), url.Values{})
+		handlerCalled = false
+		requiredScopes = nil
+	})
+
+	JustBeforeEach(func() {
+		h := func(ctx *goa.Context) error {
+			handlerCalled = true
+			return nil
+		}
+		err = goa.RequireSecurity(scheme, scopes...)(h)(ctx)
+	})
+
+	Context("with no registered middleware", func() {
+		It("fails", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("no middleware registered"))
+			Ω(handlerCalled).Should(BeFalse())
+		})
+	})
+
+	Context("with a middleware that accepts the request", func() {
+		BeforeEach(func() {
+			service.SetSecurityMiddleware(scheme, func(h goa.Handler) goa.Handler {
+				return func(ctx *goa.Context) error {
+					requiredScopes = ctx.RequiredScopes()
+					return h(ctx)
+				}
+			})
+		})
+
+		It("calls the handler with the required scopes in the context", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(handlerCalled).Should(BeTrue())
+			Ω(requiredScopes).Should(Equal(scopes))
+		})
+	})
+
+	Context("with a middleware that rejects the request", func() {
+		BeforeEach(func() {
+			service.SetSecurityMiddleware(scheme, func(h goa.Handler) goa.Handler {
+				return func(ctx *goa.Context) error {
+					return &goa.ForbiddenError{Scheme: scheme, Scopes: ctx.RequiredScopes()}
+				}
+			})
+		})
+
+		It("returns the error which produces a 403 problem", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(handlerCalled).Should(BeFalse())
+			Ω(goa.NewProblem(err).Status).Should(Equal(403))
+		})
+	})
+})
+
+var _ = Describe("UnauthorizedError", func() {
+	It("produces a 401 problem", func() {
+		err := &goa.UnauthorizedError{Scheme: "basic", Reason: "invalid password"}
+		p := goa.NewProblem(err)
+		Ω(p.Status).Should(Equal(401))
+		Ω(p.Detail).Should(Equal("basic: unauthorized: invalid password"))
+	})
+})
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"golang.org/x/net/context"
 	log "gopkg.in/inconshreveable/log15.v2"
@@ -32,6 +33,17 @@ type (
 		// Use adds a middleware to the service-wide middleware chain.
 		Use(m Middleware)
 
+		// SetRecover enables or disables service-wide panic recovery, see Recover.
+		SetRecover(enabled bool)
+
+		// SetSecurityMiddleware registers the middleware that enforces the security scheme
+		// with the given name, see RequireSecurity.
+		SetSecurityMiddleware(scheme string, m Middleware)
+
+		// SecurityMiddleware returns the middleware registered for the given security
+		// scheme, nil if there is none.
+		SecurityMiddleware(scheme string) Middleware
+
 		// ListenAndServe starts a HTTP server on the given port.
 		ListenAndServe(addr string) error
 
@@ -53,6 +65,10 @@ type (
 		// ServeMux returns the service request mux.
 		ServeMux() ServeMux
 
+		// SetServeMux replaces the service request mux, see NewMuxWithRouter. It must be
+		// called before the controllers are mounted.
+		SetServeMux(mux ServeMux)
+
 		// NewController returns a controller for the resource with the given name.
 		// This method is mainly intended for use by generated code.
 		NewController(resName string) Controller
@@ -61,9 +77,22 @@ type (
 		// the request `Content-Type` header
 		DecodeRequest(ctx *Context, v interface{}) error
 
-		// EncodeResponse uses registered Encoders to marshal the response body based on the
-		// request `Accept` header and writes it to the http.ResponseWriter
-		EncodeResponse(ctx *Context, v interface{}) error
+		// SetDecoder registers a decoder factory for the given content types, see
+		// Application.SetDecoder.
+		SetDecoder(f DecoderFactory, makeDefault bool, contentTypes ...string)
+
+		// SetEncoder registers an encoder factory for the given content types, see
+		// Application.SetEncoder.
+		SetEncoder(f EncoderFactory, makeDefault bool, contentTypes ...string)
+
+		// EncodeResponse negotiates the response content type using the request `Accept`
+		// header, sets the `Content-Type` header and writes the response with the given
+		// status code using the matching registered Encoder.
+		EncodeResponse(ctx *Context, code int, v interface{}) error
+
+		// EncodeProblem uses registered Encoders to marshal the given problem details based
+		// on the request `Accept` header and writes it to the http.ResponseWriter.
+		EncodeProblem(ctx *Context, p *Problem) error
 	}
 
 	// Controller is the interface implemented by all goa controllers.
@@ -84,8 +113,14 @@ type (
 		// SetErrorHandler sets the controller specific error handler.
 		SetErrorHandler(ErrorHandler)
 		// HandleFunc returns a HandleFunc from the given handler
-		// name is used solely for logging.
+		// name is the action name used for logging and exposed by the request contexts via
+		// ActionName.
 		HandleFunc(name string, h, d Handler) HandleFunc
+		// HandleRoute returns a HandleFunc from the given handler like HandleFunc for the
+		// handler registered with the given route. The request contexts expose the route
+		// via Route and the route resource, action and version via ResourceName, ActionName
+		// and APIVersion.
+		HandleRoute(route *Route, name string, h, d Handler) HandleFunc
 	}
 
 	// Application represents a goa application. At the basic level an application consists of
@@ -112,6 +147,9 @@ type (
 		decoderPools          map[string]*decoderPool // Registered decoders for the service
 		encoderPools          map[string]*encoderPool // Registered encoders for the service
 		encodableContentTypes []string                // List of registered contentTypes for response negotiation
+		defaultContentType    string                  // Content type of the default encoder
+		recoverPanics         bool                    // Whether to recover from panics in all handlers
+		securityMiddleware    map[string]Middleware   // Security middleware indexed by scheme name
 	}
 
 	// ApplicationController provides the common state and behavior for generated controllers.
@@ -171,10 +209,10 @@ func New(name string) Service {
 		Logger:       Log.New("app", name),
 		name:         name,
 		errorHandler: DefaultErrorHandler,
-		mux:          NewMux(),
 	}
 
 	app.initEncoding()
+	app.SetServeMux(NewMux())
 
 	return app
 }
@@ -198,14 +236,22 @@ func (app *Application) Use(m Middleware) {
 	app.middleware = append(app.middleware, m)
 }
 
+// SetRecover enables or disables panic recovery for all the application controllers. When
+// enabled panics raised by controller actions or middleware are recovered, logged with the
+// controller logger and handed to the error handler as a *PanicError. This is equivalent to
+// using the Recover middleware as the first middleware of every controller chain.
+func (app *Application) SetRecover(enabled bool) {
+	app.recoverPanics = enabled
+}
+
 // ErrorHandler returns the currently set error handler.
 func (app *Application) ErrorHandler() ErrorHandler {
 	return app.errorHandler
 }
 
 // SetErrorHandler defines an application wide error handler.
-// The default error handler (DefaultErrorHandler) responds with a 500 status code and the error
-// message in the response body.
+// The default error handler (DefaultErrorHandler) responds with a RFC 7807 problem that includes
+// the error message.
 // TerseErrorHandler provides an alternative implementation that does not write the error message
 // to the response body for internal errors (e.g. for production).
 // Set it with SetErrorHandler(TerseErrorHandler).
@@ -267,6 +313,35 @@ func (app *Application) ServeMux() ServeMux {
 	return app.mux
 }
 
+// SetServeMux replaces the top level mux. SetServeMux sets the mux not found and method not allowed
+// handlers so that the errors with ID ErrNotFound and ErrMethodNotAllowed describing the requests
+// the mux cannot route go through the service middleware and error handler like the errors
+// returned by the controllers. Use the mux HandleNotFound and HandleMethodNotAllowed methods
+// after calling SetServeMux to override these handlers.
+func (app *Application) SetServeMux(mux ServeMux) {
+	ctrl := app.NewController("Mux")
+	mux.HandleNotFound(muxErrorHandler(ctrl, "NotFound", func(ctx *Context) error {
+		return notFoundError(ctx.Request())
+	}))
+	mux.HandleMethodNotAllowed(muxErrorHandler(ctrl, "MethodNotAllowed", func(ctx *Context) error {
+		return methodNotAllowedError(ctx.Request(), ctx.Header().Get("Allow"))
+	}))
+	app.mux = mux
+}
+
+// muxErrorHandler returns a HandleFunc that runs the controller middleware and gives the error
+// returned by h to the error handler. The HandleFunc and its middleware chain are built once when
+// the first request is handled so that they include the middleware added to the service after
+// SetServeMux is called.
+func muxErrorHandler(ctrl Controller, name string, h Handler) HandleFunc {
+	var once sync.Once
+	var handle HandleFunc
+	return func(rw http.ResponseWriter, req *http.Request, params url.Values) {
+		once.Do(func() { handle = ctrl.HandleFunc(name, h, nil) })
+		handle(rw, req, params)
+	}
+}
+
 // NewController returns a controller for the given resource. This method is mainly intended for
 // use by the generated code. User code shouldn't have to call it directly.
 func (app *Application) NewController(resName string) Controller {
@@ -303,8 +378,15 @@ func (ctrl *ApplicationController) SetErrorHandler(handler ErrorHandler) {
 }
 
 // HandleError invokes the controller error handler or - if there isn't one - the service error
-// handler.
+// handler. Errors created with the constructors generated for the errors defined in the design
+// (instances of ResponseError) are not given to the error handlers, instead HandleError writes
+// the designed response directly.
 func (ctrl *ApplicationController) HandleError(ctx *Context, err error) {
+	if rerr, ok := err.(*ResponseError); ok {
+		if err = rerr.Respond(ctx); err == nil {
+			return
+		}
+	}
 	if ctrl.errorHandler != nil {
 		ctrl.errorHandler(ctx, err)
 	} else if ctrl.app.errorHandler != nil {
@@ -313,15 +395,35 @@ func (ctrl *ApplicationController) HandleError(ctx *Context, err error) {
 }
 
 // HandleFunc wraps al request handler into a HandleFunc. The HandleFunc initializes the
-// request context by loading the request state, invokes the handler and in case of error invokes
-// the controller (if there is one) or application error handler.
+// request context by loading the request state including the trace context propagated in the
+// "traceparent" and "tracestate" headers (see ContextTraceParent), invokes the handler and in case
+// of error invokes the controller (if there is one) or application error handler.
 // This function is intended for the controller generated code. User code should not need to call
 // it directly.
 func (ctrl *ApplicationController) HandleFunc(name string, h, d Handler) HandleFunc {
+	return ctrl.HandleRoute(nil, name, h, d)
+}
+
+// HandleRoute wraps a request handler registered with the given route into a HandleFunc like
+// HandleFunc does. The code generated by goagen uses HandleRoute to mount the controller actions.
+// Requests whose body is larger than the route MaxBodySize are handled by the error handler with
+// an error with ID ErrPayloadTooLarge, this includes requests that use chunked transfer encoding
+// and requests whose body is read by the handler, e.g. multipart forms read with FormFile.
+// Requests whose body content type is not listed in the route Consumes are handled by the error
+// handler with an error with ID ErrUnsupportedMediaType before the body is decoded.
+// The DefaultMux Routes method lists the given route once the handler is registered with the mux.
+func (ctrl *ApplicationController) HandleRoute(route *Route, name string, h, d Handler) HandleFunc {
+	if mux, ok := ctrl.app.ServeMux().(*DefaultMux); ok && route != nil {
+		mux.describeRoute(route)
+	}
 	// Setup middleware outside of closure
 	middleware := func(ctx *Context) error {
 		if !ctx.ResponseWritten() {
 			if err := h(ctx); err != nil {
+				if body, ok := ctx.Request().Body.(*limitedBody); ok && body.err != nil {
+					// The handler failed reading a body larger than the route limit
+					err = body.err
+				}
 				ctrl.HandleError(ctx, err)
 			}
 		}
@@ -338,18 +440,62 @@ func (ctrl *ApplicationController) HandleFunc(name string, h, d Handler) HandleF
 		defer cancel() // Signal completion of request to any child goroutine
 		ctx := NewContext(gctx, ctrl.app, r, w, params)
 		ctx.Logger = ctrl.Logger.New("action", name)
+		ctx.SetValue(actionNameKey, name)
+		if route != nil {
+			ctx.SetValue(routeKey, route)
+			ctx.SetValue(resourceNameKey, route.Resource)
+			ctx.SetValue(apiVersionKey, route.Version)
+			if route.Action != "" {
+				ctx.SetValue(actionNameKey, route.Action)
+			}
+		}
+		if tp := ParseTraceParent(r.Header.Get("traceparent"), r.Header.Get("tracestate")); tp != nil {
+			ctx.SetValue(traceParentKey, tp)
+		}
 
-		// Load body if any
+		// Check the request content type before decoding the body
 		var err error
-		if r.ContentLength > 0 && d != nil {
+		if route != nil && len(route.Consumes) > 0 {
+			err = checkContentType(r, route.Consumes)
+		}
+
+		// Enforce the route maximum body size
+		var body *limitedBody
+		if err == nil && route != nil && route.MaxBodySize > 0 && r.Body != nil {
+			if r.ContentLength > route.MaxBodySize {
+				err = payloadTooLargeError(route.MaxBodySize)
+			} else {
+				body = newLimitedBody(r.Body, route.MaxBodySize)
+				r.Body = body
+			}
+		}
+
+		// Load body if any, the length of chunked bodies is unknown (-1)
+		if err == nil && r.ContentLength != 0 && d != nil {
 			err = d(ctx)
+			if err == nil && body != nil {
+				// Detect trailing data the decoder did not read
+				err = body.drain()
+			}
+			if body != nil && body.err != nil {
+				err = body.err
+			} else if err == io.EOF && r.ContentLength < 0 {
+				err = nil
+			}
 		}
 
-		// Handle invalid payload
+		// Handle unsupported, invalid or too large payload
 		handler := middleware
 		if err != nil {
+			berr := err
+			if terr, ok := err.(*TypedError); !ok || (terr.ID != ErrPayloadTooLarge && terr.ID != ErrUnsupportedMediaType) {
+				if _, ok := err.(MultiError); !ok {
+					err = InvalidEncodingError(err, nil)
+				}
+				berr = NewBadRequestError(err)
+			}
 			handler = func(ctx *Context) error {
-				ctx.RespondBytes(400, []byte(fmt.Sprintf(`{"kind":"invalid request","msg":"invalid JSON: %s"}`, err)))
+				ctrl.HandleError(ctx, berr)
 				return nil
 			}
 			for i := range chain {
@@ -357,8 +503,15 @@ func (ctrl *ApplicationController) HandleFunc(name string, h, d Handler) HandleF
 			}
 		}
 
+		// Recover from panics if configured to
+		if ctrl.app.recoverPanics {
+			handler = Recover()(handler)
+		}
+
 		// Invoke middleware chain
-		handler(ctx)
+		if err := handler(ctx); err != nil {
+			ctrl.HandleError(ctx, err)
+		}
 
 		// Make sure a response is sent back to client.
 		if ctx.ResponseStatus() == 0 {
@@ -367,31 +520,24 @@ func (ctrl *ApplicationController) HandleFunc(name string, h, d Handler) HandleF
 	}
 }
 
-// DefaultErrorHandler returns a 400 response for request validation errors (instances of
-// BadRequestError) and a 500 response for other errors. It writes the error message to the
-// response body in both cases.
+// DefaultErrorHandler renders the error as a RFC 7807 problem (see NewProblem). The response
+// status is 400 for request validation errors (instances of BadRequestError), 503 for timeouts
+// (instances of TimeoutError) and 500 for other errors. The problem detail includes the error
+// message in all cases.
 func DefaultErrorHandler(c *Context, e error) {
-	status := 500
-	if _, ok := e.(*BadRequestError); ok {
-		c.Header().Set("Content-Type", "application/json")
-		status = 400
-	}
-	if err := c.RespondBytes(status, []byte(e.Error())); err != nil {
+	if err := c.RespondProblem(NewProblem(e)); err != nil {
 		Log.Error("failed to send default error handler response", "err", err)
 	}
 }
 
-// TerseErrorHandler behaves like DefaultErrorHandler except that it does not set the response
-// body for internal errors.
+// TerseErrorHandler behaves like DefaultErrorHandler except that it does not include the error
+// message in the problem detail for internal errors.
 func TerseErrorHandler(c *Context, e error) {
-	status := 500
-	var body []byte
-	if _, ok := e.(*BadRequestError); ok {
-		c.Header().Set("Content-Type", "application/json")
-		status = 400
-		body = []byte(e.Error())
+	p := NewProblem(e)
+	if p.Status >= 500 {
+		p.Detail = ""
 	}
-	if err := c.RespondBytes(status, body); err != nil {
+	if err := c.RespondProblem(p); err != nil {
 		Log.Error("failed to send terse error handler response", "err", err)
 	}
 }
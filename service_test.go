@@ -2,15 +2,17 @@ package goa_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/raphael/goa"
-	"github.com/raphael/goa-middleware/middleware"
+	"github.com/raphael/goa/middleware"
 )
 
 var _ = Describe("Application", func() {
@@ -57,7 +59,6 @@ var _ = Describe("Application", func() {
 	})
 
 	Describe("HandleFunc", func() {
-		const resName = "res"
 		const actName = "act"
 		var handler, unmarshaler goa.Handler
 		const respStatus = 200
@@ -121,6 +122,12 @@ var _ = Describe("Application", func() {
 				Ω(tw.Body).Should(Equal(respContent))
 			})
 
+			It("exposes the action name", func() {
+				Ω(ctx.ResourceName()).Should(BeEmpty())
+				Ω(ctx.ActionName()).Should(Equal(actName))
+				Ω(ctx.APIVersion()).Should(BeEmpty())
+			})
+
 			Context("and middleware", func() {
 				middlewareCalled := false
 
@@ -167,6 +174,19 @@ var _ = Describe("Application", func() {
 					})
 				})
 
+				Context("by panicking", func() {
+					BeforeEach(func() {
+						handler = func(ctx *goa.Context) error {
+							panic("boom")
+						}
+						s.SetRecover(true)
+					})
+
+					It("triggers the error handler", func() {
+						Ω(errorHandlerCalled).Should(BeTrue())
+					})
+				})
+
 				Context("by not handling the request", func() {
 					BeforeEach(func() {
 						handler = func(ctx *goa.Context) error {
@@ -204,6 +224,31 @@ var _ = Describe("Application", func() {
 					})
 				})
 
+				Context("with an invalid body", func() {
+					BeforeEach(func() {
+						content := []byte(`{"hello"`)
+						r.Body = ioutil.NopCloser(bytes.NewReader(content))
+						r.ContentLength = int64(len(content))
+						rw = &TestResponseWriter{ParentHeader: make(http.Header)}
+						unmarshaler = func(c *goa.Context) error {
+							var payload interface{}
+							return c.Service().DecodeRequest(c, &payload)
+						}
+					})
+
+					It("responds with a problem", func() {
+						tw := rw.(*TestResponseWriter)
+						Ω(tw.Status).Should(Equal(400))
+						Ω(tw.ParentHeader.Get("Content-Type")).Should(Equal(goa.ProblemJSONContentType))
+						var p goa.Problem
+						err := json.Unmarshal(tw.Body, &p)
+						Ω(err).ShouldNot(HaveOccurred())
+						Ω(p.Type).Should(Equal(goa.ErrorID(goa.ErrInvalidEncoding).TypeURI()))
+						Ω(p.Title).Should(Equal(goa.ErrorID(goa.ErrInvalidEncoding).Title()))
+						Ω(p.Status).Should(Equal(400))
+					})
+				})
+
 				Context("with a Content-Type of 'application/octet-stream' or any other", func() {
 					BeforeEach(func() {
 						r.Header.Set("Content-Type", "application/octet-stream")
@@ -216,6 +261,110 @@ var _ = Describe("Application", func() {
 			})
 		})
 	})
+
+	Describe("HandleRoute", func() {
+		var ctrl goa.Controller
+		var ctx *goa.Context
+
+		BeforeEach(func() {
+			ctrl = s.NewController("test")
+		})
+
+		handle := func(route *goa.Route) {
+			h := func(c *goa.Context) error {
+				ctx = c
+				return c.RespondBytes(200, nil)
+			}
+			r, err := http.NewRequest(route.Method, route.Path, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			ctrl.HandleRoute(route, "ListBottles", h, nil)(httptest.NewRecorder(), r, url.Values{})
+		}
+
+		It("exposes the resource, designed action and version of the route", func() {
+			v1 := &goa.Route{Method: "GET", Path: "/v1/bottles", Resource: "bottle", Action: "list bottles", Version: "v1"}
+			v2 := &goa.Route{Method: "GET", Path: "/v2/bottles", Resource: "bottle", Action: "list bottles", Version: "v2"}
+			handle(v1)
+			Ω(ctx.ResourceName()).Should(Equal("bottle"))
+			Ω(ctx.ActionName()).Should(Equal("list bottles"))
+			Ω(ctx.APIVersion()).Should(Equal("v1"))
+			handle(v2)
+			Ω(ctx.APIVersion()).Should(Equal("v2"))
+		})
+	})
+
+	Describe("ServeMux", func() {
+		var rw *httptest.ResponseRecorder
+		var handledErr error
+
+		BeforeEach(func() {
+			handledErr = nil
+			s.SetErrorHandler(func(ctx *goa.Context, err error) {
+				handledErr = err
+				goa.DefaultErrorHandler(ctx, err)
+			})
+			s.ServeMux().Handle("GET", "/foo", func(http.ResponseWriter, *http.Request, url.Values) {})
+		})
+
+		serve := func(method, path string) {
+			req, err := http.NewRequest(method, path, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			rw = httptest.NewRecorder()
+			s.ServeMux().ServeHTTP(rw, req)
+		}
+
+		It("gives requests that match no route to the error handler", func() {
+			serve("GET", "/bar")
+			Ω(handledErr).Should(HaveOccurred())
+			Ω(handledErr.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrNotFound)))
+			Ω(rw.Code).Should(Equal(404))
+			var problem goa.Problem
+			Ω(json.Unmarshal(rw.Body.Bytes(), &problem)).ShouldNot(HaveOccurred())
+			Ω(problem.Type).Should(Equal("urn:goa:error:not-found"))
+		})
+
+		It("gives requests with a method that is not allowed to the error handler", func() {
+			serve("DELETE", "/foo")
+			Ω(handledErr).Should(HaveOccurred())
+			Ω(handledErr.(*goa.TypedError).ID).Should(Equal(goa.ErrorID(goa.ErrMethodNotAllowed)))
+			Ω(rw.Code).Should(Equal(405))
+			Ω(rw.Header().Get("Allow")).Should(Equal("GET, HEAD, OPTIONS"))
+		})
+
+		Context("with middleware added after the mux is set", func() {
+			var calls int
+
+			BeforeEach(func() {
+				calls = 0
+				s.Use(func(h goa.Handler) goa.Handler {
+					return func(ctx *goa.Context) error {
+						calls++
+						return h(ctx)
+					}
+				})
+			})
+
+			It("runs it for the requests that match no route", func() {
+				serve("GET", "/bar")
+				serve("DELETE", "/foo")
+				Ω(calls).Should(Equal(2))
+				Ω(rw.Code).Should(Equal(405))
+			})
+		})
+
+		Context("with a custom not found handler", func() {
+			BeforeEach(func() {
+				s.ServeMux().HandleNotFound(func(rw http.ResponseWriter, req *http.Request, params url.Values) {
+					rw.WriteHeader(418)
+				})
+			})
+
+			It("uses it", func() {
+				serve("GET", "/bar")
+				Ω(handledErr).ShouldNot(HaveOccurred())
+				Ω(rw.Code).Should(Equal(418))
+			})
+		})
+	})
 })
 
 func TErrorHandler(witness *bool) goa.ErrorHandler {
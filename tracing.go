@@ -0,0 +1,280 @@
+package goa
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// TraceParent is the W3C trace context (https://www.w3.org/TR/trace-context/) propagated
+	// with the "traceparent" and "tracestate" HTTP headers.
+	TraceParent struct {
+		// TraceID identifies the trace, it is a 32 characters lower case hex string.
+		TraceID string
+		// SpanID identifies the span of the caller, it is a 16 characters lower case hex
+		// string.
+		SpanID string
+		// Sampled is true if the caller records the trace.
+		Sampled bool
+		// State is the value of the "tracestate" header which carries vendor specific trace
+		// data.
+		State string
+	}
+
+	// Span describes the handling of a request by an action. Spans are created by the Tracer
+	// middleware and given to the tracer exporter once the request is handled.
+	Span struct {
+		// Name is the span name, e.g. "bottle.show".
+		Name string
+		// TraceID identifies the trace the span belongs to.
+		TraceID string
+		// SpanID identifies the span.
+		SpanID string
+		// ParentID identifies the parent span if any.
+		ParentID string
+		// Sampled is true if the trace is recorded.
+		Sampled bool
+		// State is the trace state propagated to the downstream services.
+		State string
+		// Start is the time the span started.
+		Start time.Time
+		// End is the time the span ended.
+		End time.Time
+		// Attributes describe the request, see Tracer.Middleware.
+		Attributes map[string]string
+		// Error is the message of the error returned by the handler if any.
+		Error string
+
+		mu sync.Mutex
+	}
+
+	// SpanExporter is the interface implemented by the exporters that send spans to a tracing
+	// backend. The goa package provides an in-memory implementation with NewMemorySpanExporter.
+	SpanExporter interface {
+		// Export is called with each span once it ends. Export must not block.
+		Export(span *Span)
+	}
+
+	// Tracer creates a span for each request and gives it to its exporter once the request is
+	// handled.
+	Tracer struct {
+		// Exporter receives the sampled spans.
+		Exporter SpanExporter
+	}
+
+	// MemorySpanExporter is a SpanExporter that keeps the spans in memory. It is mainly
+	// intended for tests.
+	MemorySpanExporter struct {
+		mu    sync.Mutex
+		spans []*Span
+	}
+)
+
+// traceParentRegex matches the values of the "traceparent" header for version "00".
+var traceParentRegex = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+// ParseTraceParent parses the values of the "traceparent" and "tracestate" headers. It returns nil
+// if traceparent is not a valid version "00" trace parent or has an all zeros trace or parent ID.
+func ParseTraceParent(traceparent, tracestate string) *TraceParent {
+	match := traceParentRegex.FindStringSubmatch(strings.TrimSpace(traceparent))
+	if match == nil || isZeroID(match[1]) || isZeroID(match[2]) {
+		return nil
+	}
+	flags, _ := strconv.ParseUint(match[3], 16, 8)
+	return &TraceParent{
+		TraceID: match[1],
+		SpanID:  match[2],
+		Sampled: flags&1 == 1,
+		State:   strings.TrimSpace(tracestate),
+	}
+}
+
+// SetHeaders sets the "traceparent" header and the "tracestate" header if there is a trace state.
+func (t *TraceParent) SetHeaders(header http.Header) {
+	flags := "00"
+	if t.Sampled {
+		flags = "01"
+	}
+	header.Set("traceparent", fmt.Sprintf("00-%s-%s-%s", t.TraceID, t.SpanID, flags))
+	if t.State != "" {
+		header.Set("tracestate", t.State)
+	} else {
+		header.Del("tracestate")
+	}
+}
+
+// ContextTraceParent returns the trace context that must be propagated to the requests made while
+// handling the request described by the given context. It is the current span if the Tracer
+// middleware created one, the trace context extracted from the request headers otherwise. It
+// returns nil if the context carries no trace.
+func ContextTraceParent(ctx context.Context) *TraceParent {
+	if span := ContextSpan(ctx); span != nil {
+		return &TraceParent{TraceID: span.TraceID, SpanID: span.SpanID, Sampled: span.Sampled, State: span.State}
+	}
+	if tp, ok := ctx.Value(traceParentKey).(*TraceParent); ok {
+		return tp
+	}
+	return nil
+}
+
+// ContextSpan returns the span created by the Tracer middleware for the request described by the
+// given context, nil if there is none.
+func ContextSpan(ctx context.Context) *Span {
+	if span, ok := ctx.Value(spanKey).(*Span); ok {
+		return span
+	}
+	return nil
+}
+
+// NewTracer returns a tracer that gives the spans to the given exporter.
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{Exporter: exporter}
+}
+
+// Middleware returns a middleware that creates a span for each request. The span continues the
+// trace extracted from the request "traceparent" and "tracestate" headers if any, it starts a new
+// sampled trace otherwise. Spans are named after the designed resource and action and have the
+// following attributes:
+//
+//	goa.resource, goa.action, goa.version: the names of the designed resource, action and
+//	API version (see Context.ResourceName, ActionName and APIVersion)
+//	http.method, http.route: the method and path of the route that matched the request
+//	http.target: the request path
+//	http.status_code: the response status
+//
+// The span is given to the tracer exporter once the next handlers return if the trace is sampled.
+// Handlers retrieve the span with ContextSpan. Use the Client WithContext method to propagate the
+// trace to the requests made while handling the request.
+func (t *Tracer) Middleware() Middleware {
+	return func(h Handler) Handler {
+		return func(ctx *Context) error {
+			span := &Span{
+				Name:    spanName(ctx),
+				TraceID: newTraceID(),
+				SpanID:  newSpanID(),
+				Sampled: true,
+				Start:   time.Now(),
+				Attributes: map[string]string{
+					"goa.resource": ctx.ResourceName(),
+					"goa.action":   ctx.ActionName(),
+					"goa.version":  ctx.APIVersion(),
+				},
+			}
+			if tp, ok := ctx.Value(traceParentKey).(*TraceParent); ok {
+				span.TraceID = tp.TraceID
+				span.ParentID = tp.SpanID
+				span.Sampled = tp.Sampled
+				span.State = tp.State
+			}
+			if req := ctx.Request(); req != nil {
+				span.Attributes["http.method"] = req.Method
+				span.Attributes["http.target"] = req.URL.Path
+			}
+			if route := ctx.Route(); route != nil {
+				span.Attributes["http.route"] = route.Path
+			}
+			ctx.SetValue(spanKey, span)
+			err := h(ctx)
+			status := ctx.ResponseStatus()
+			if status == 0 && err != nil {
+				status = NewProblem(err).Status
+			}
+			span.SetAttribute("http.status_code", strconv.Itoa(status))
+			span.mu.Lock()
+			span.End = time.Now()
+			if err != nil {
+				span.Error = err.Error()
+			}
+			span.mu.Unlock()
+			if span.Sampled && t.Exporter != nil {
+				t.Exporter.Export(span)
+			}
+			return err
+		}
+	}
+}
+
+// SetAttribute sets the value of a span attribute.
+func (s *Span) SetAttribute(name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Attributes[name] = value
+}
+
+// Attribute returns the value of a span attribute, empty string if the span has no attribute with
+// the given name.
+func (s *Span) Attribute(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Attributes[name]
+}
+
+// Duration returns the span duration.
+func (s *Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// NewMemorySpanExporter returns an exporter that keeps the spans in memory.
+func NewMemorySpanExporter() *MemorySpanExporter {
+	return &MemorySpanExporter{}
+}
+
+// Export records the span.
+func (e *MemorySpanExporter) Export(span *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the recorded spans in the order they ended.
+func (e *MemorySpanExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Span(nil), e.spans...)
+}
+
+// Reset discards the recorded spans.
+func (e *MemorySpanExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// spanName returns the name of the span of the request described by the given context.
+func spanName(ctx *Context) string {
+	if res := ctx.ResourceName(); res != "" {
+		return res + "." + ctx.ActionName()
+	}
+	return ctx.ActionName()
+}
+
+// newTraceID returns a random trace ID.
+func newTraceID() string {
+	return randomID(16)
+}
+
+// newSpanID returns a random span ID.
+func newSpanID() string {
+	return randomID(8)
+}
+
+// randomID returns a random hex encoded ID of the given size in bytes.
+func randomID(size int) string {
+	b := make([]byte, size)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// isZeroID returns true if the given hex encoded ID is all zeros.
+func isZeroID(id string) bool {
+	return strings.Trim(id, "0") == ""
+}
@@ -0,0 +1,146 @@
+package goa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+)
+
+var _ = Describe("ParseTraceParent", func() {
+	It("parses valid trace parents", func() {
+		tp := goa.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "acme=1")
+		Ω(tp).ShouldNot(BeNil())
+		Ω(tp.TraceID).Should(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+		Ω(tp.SpanID).Should(Equal("00f067aa0ba902b7"))
+		Ω(tp.Sampled).Should(BeTrue())
+		Ω(tp.State).Should(Equal("acme=1"))
+	})
+
+	It("rejects invalid trace parents", func() {
+		Ω(goa.ParseTraceParent("", "")).Should(BeNil())
+		Ω(goa.ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "")).Should(BeNil())
+		Ω(goa.ParseTraceParent("00-00000000000000000000000000000000-00f067aa0ba902b7-01", "")).Should(BeNil())
+		Ω(goa.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", "")).Should(BeNil())
+	})
+})
+
+var _ = Describe("Tracer", func() {
+	var exporter *goa.MemorySpanExporter
+	var service goa.Service
+	var handler goa.Handler
+	var traceparent string
+
+	serve := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/bottles/1", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		if traceparent != "" {
+			req.Header.Set("traceparent", traceparent)
+			req.Header.Set("tracestate", "acme=1")
+		}
+		rec := httptest.NewRecorder()
+		service.ServeMux().ServeHTTP(rec, req)
+		return rec
+	}
+
+	BeforeEach(func() {
+		exporter = goa.NewMemorySpanExporter()
+		service = goa.New("test")
+		service.Use(goa.NewTracer(exporter).Middleware())
+		handler = func(ctx *goa.Context) error {
+			return ctx.RespondBytes(200, []byte("{}"))
+		}
+		traceparent = ""
+	})
+
+	JustBeforeEach(func() {
+		ctrl := service.NewController("BottleController")
+		route := &goa.Route{Method: "GET", Path: "/bottles/:id", Resource: "bottle", Action: "show"}
+		service.ServeMux().Handle(route.Method, route.Path, ctrl.HandleRoute(route, "show", func(ctx *goa.Context) error {
+			return handler(ctx)
+		}, nil))
+	})
+
+	It("exports a span per request", func() {
+		serve()
+		spans := exporter.Spans()
+		Ω(spans).Should(HaveLen(1))
+		span := spans[0]
+		Ω(span.Name).Should(Equal("bottle.show"))
+		Ω(span.TraceID).Should(HaveLen(32))
+		Ω(span.SpanID).Should(HaveLen(16))
+		Ω(span.ParentID).Should(BeEmpty())
+		Ω(span.Attribute("goa.resource")).Should(Equal("bottle"))
+		Ω(span.Attribute("goa.action")).Should(Equal("show"))
+		Ω(span.Attribute("http.method")).Should(Equal("GET"))
+		Ω(span.Attribute("http.route")).Should(Equal("/bottles/:id"))
+		Ω(span.Attribute("http.target")).Should(Equal("/bottles/1"))
+		Ω(span.Attribute("http.status_code")).Should(Equal("200"))
+		Ω(span.End).ShouldNot(BeTemporally("<", span.Start))
+	})
+
+	Context("with a request that carries a trace", func() {
+		BeforeEach(func() {
+			traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		})
+
+		It("continues the trace", func() {
+			serve()
+			spans := exporter.Spans()
+			Ω(spans).Should(HaveLen(1))
+			Ω(spans[0].TraceID).Should(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+			Ω(spans[0].ParentID).Should(Equal("00f067aa0ba902b7"))
+			Ω(spans[0].State).Should(Equal("acme=1"))
+		})
+
+		It("propagates the current span to the client requests made with the request context", func() {
+			var outbound http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				outbound = req.Header
+			}))
+			defer server.Close()
+			handler = func(ctx *goa.Context) error {
+				req, err := http.NewRequest("GET", server.URL, nil)
+				if err != nil {
+					return err
+				}
+				resp, err := goa.NewClient().WithContext(ctx).Do(req)
+				if err != nil {
+					return err
+				}
+				resp.Body.Close()
+				return ctx.RespondBytes(200, []byte("{}"))
+			}
+			serve()
+			span := exporter.Spans()[0]
+			Ω(outbound.Get("traceparent")).Should(Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-" + span.SpanID + "-01"))
+			Ω(outbound.Get("tracestate")).Should(Equal("acme=1"))
+		})
+	})
+
+	Context("with a request whose trace is not sampled", func() {
+		BeforeEach(func() {
+			traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+		})
+
+		It("does not export the span", func() {
+			serve()
+			Ω(exporter.Spans()).Should(BeEmpty())
+		})
+	})
+
+	Context("with a handler that fails", func() {
+		BeforeEach(func() {
+			handler = func(ctx *goa.Context) error {
+				return &goa.TypedError{ID: goa.ErrTooManyRequests, Mesg: "slow down"}
+			}
+		})
+
+		It("records the error response status", func() {
+			serve()
+			Ω(exporter.Spans()[0].Attribute("http.status_code")).Should(Equal("429"))
+		})
+	})
+})
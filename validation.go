@@ -2,10 +2,13 @@ package goa
 
 import (
 	"fmt"
+	"mime"
+	"mime/multipart"
 	"net"
 	"net/mail"
 	"net/url"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -114,3 +117,31 @@ func ValidatePattern(p string, val string) bool {
 	}
 	return r.MatchString(val)
 }
+
+// ValidateFile checks that the uploaded file with the given form field name is no larger than
+// maxSize bytes - if maxSize is greater than 0 - and that its content type matches one of the
+// given content types - if there are any. It appends the validation errors to err and returns it.
+func ValidateFile(name string, fh *multipart.FileHeader, maxSize int64, contentTypes []string, err error) error {
+	if maxSize > 0 && fh.Size > maxSize {
+		reason := fmt.Sprintf("size %d is larger than the maximum size %d", fh.Size, maxSize)
+		err = InvalidFileError(name, reason, err)
+	}
+	if len(contentTypes) > 0 {
+		ct := fh.Header.Get("Content-Type")
+		if mediaType, _, perr := mime.ParseMediaType(ct); perr == nil {
+			ct = mediaType
+		}
+		matched := false
+		for _, spec := range contentTypes {
+			if matchContentType(spec, ct) >= 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			reason := fmt.Sprintf("content type %#v is not one of %s", ct, strings.Join(contentTypes, ", "))
+			err = InvalidFileError(name, reason, err)
+		}
+	}
+	return err
+}
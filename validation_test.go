@@ -1,6 +1,9 @@
 package goa_test
 
 import (
+	"mime/multipart"
+	"net/textproto"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/raphael/goa"
@@ -262,3 +265,50 @@ var _ = Describe("ValidateFormat", func() {
 
 	})
 })
+
+var _ = Describe("ValidateFile", func() {
+	var fh *multipart.FileHeader
+	var maxSize int64
+	var contentTypes []string
+	var valErr error
+
+	BeforeEach(func() {
+		fh = &multipart.FileHeader{
+			Filename: "goa.png",
+			Header:   textproto.MIMEHeader{"Content-Type": {"image/png"}},
+			Size:     1024,
+		}
+		maxSize = 2048
+		contentTypes = []string{"image/*"}
+	})
+
+	JustBeforeEach(func() {
+		valErr = goa.ValidateFile("picture", fh, maxSize, contentTypes, nil)
+	})
+
+	It("validates", func() {
+		Ω(valErr).ShouldNot(HaveOccurred())
+	})
+
+	Context("with a file larger than the maximum size", func() {
+		BeforeEach(func() {
+			maxSize = 512
+		})
+
+		It("does not validate", func() {
+			Ω(valErr).Should(HaveOccurred())
+			Ω(valErr.Error()).Should(ContainSubstring("larger than the maximum size"))
+		})
+	})
+
+	Context("with a content type that is not allowed", func() {
+		BeforeEach(func() {
+			contentTypes = []string{"image/jpeg", "text/*"}
+		})
+
+		It("does not validate", func() {
+			Ω(valErr).Should(HaveOccurred())
+			Ω(valErr.Error()).Should(ContainSubstring("is not one of"))
+		})
+	})
+})
@@ -0,0 +1,179 @@
+package goa
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocket is a WebSocket connection established by Context.RespondWebSocket. Messages are
+// JSON encoded and sent in text frames.
+type WebSocket struct {
+	conn *websocket.Conn
+	once sync.Once
+	done chan bool
+}
+
+// RespondWebSocket upgrades the request connection to a WebSocket connection and calls fn with
+// it. The connection is closed when fn returns. RespondWebSocket returns a bad request error if the
+// request is not a WebSocket handshake. Browsers may only open connections from the origin of the
+// service or from the origins allowed by the CORS middleware, other handshakes are rejected with
+// a 403 Forbidden response.
+// This method should only be called once per request.
+func (ctx *Context) RespondWebSocket(fn func(ws *WebSocket) error) error {
+	rw, ok := ctx.Value(respKey).(http.ResponseWriter)
+	if !ok {
+		return fmt.Errorf("response writer not initialized")
+	}
+	req := ctx.Request()
+	if !headerContains(req.Header, "Connection", "upgrade") || !headerContains(req.Header, "Upgrade", "websocket") {
+		return NewBadRequestError(fmt.Errorf("request is not a websocket handshake"))
+	}
+	if !canHijack(rw) {
+		return fmt.Errorf("response writer does not support websocket connections")
+	}
+	allowed := rw.Header().Get("Access-Control-Allow-Origin")
+	var err error
+	server := websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			return checkWebSocketOrigin(config, req, allowed)
+		},
+		Handler: func(conn *websocket.Conn) {
+			ctx.Context = context.WithValue(ctx.Context, respStatusKey, http.StatusSwitchingProtocols)
+			err = fn(&WebSocket{conn: conn})
+		},
+	}
+	server.ServeHTTP(rw, req)
+	return err
+}
+
+// wrapper is the interface implemented by the response writers of the goa middlewares that wrap
+// the response writer of the next handler.
+type wrapper interface {
+	wrapped() http.ResponseWriter
+}
+
+// canHijack returns true if the given response writer and the writers it wraps support hijacking
+// the connection.
+func canHijack(rw http.ResponseWriter) bool {
+	for {
+		if _, ok := rw.(http.Hijacker); !ok {
+			return false
+		}
+		w, ok := rw.(wrapper)
+		if !ok {
+			return true
+		}
+		rw = w.wrapped()
+	}
+}
+
+// hijack hijacks the connection of the given response writer.
+func hijack(rw http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := rw.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("response writer does not support hijacking")
+}
+
+// Send sends a message whose data is the JSON representation of v.
+func (ws *WebSocket) Send(v interface{}) error {
+	return websocket.JSON.Send(ws.conn, v)
+}
+
+// Receive receives a message and decodes its JSON data into v. Receive must not be used together
+// with Done.
+func (ws *WebSocket) Receive(v interface{}) error {
+	return websocket.JSON.Receive(ws.conn, v)
+}
+
+// Done returns a channel that is closed when the client closes the connection. Done discards the
+// messages sent by the client so that it can detect the connection closing, use Receive instead
+// for connections that read client messages.
+func (ws *WebSocket) Done() <-chan bool {
+	ws.once.Do(func() {
+		ws.done = make(chan bool)
+		go func() {
+			defer close(ws.done)
+			var msg []byte
+			for {
+				if err := websocket.Message.Receive(ws.conn, &msg); err != nil {
+					return
+				}
+			}
+		}()
+	})
+	return ws.done
+}
+
+// Conn returns the underlying WebSocket connection.
+func (ws *WebSocket) Conn() *websocket.Conn {
+	return ws.conn
+}
+
+// Close closes the connection.
+func (ws *WebSocket) Close() error {
+	return ws.conn.Close()
+}
+
+// DialWebSocket opens a WebSocket connection to the service endpoint with the given URL, the
+// scheme of the URL is either "ws" or "wss". The header is sent with the handshake request, use it
+// to provide credentials.
+func DialWebSocket(u string, header http.Header) (*WebSocket, error) {
+	target, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	origin := &url.URL{Scheme: "http", Host: target.Host}
+	if target.Scheme == "wss" {
+		origin.Scheme = "https"
+	}
+	config, err := websocket.NewConfig(target.String(), origin.String())
+	if err != nil {
+		return nil, err
+	}
+	config.Header = header
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &WebSocket{conn: conn}, nil
+}
+
+// checkWebSocketOrigin accepts the handshakes sent by clients that do not set the Origin header,
+// by the service origin or by the origin allowed by the CORS middleware.
+func checkWebSocketOrigin(config *websocket.Config, req *http.Request, allowed string) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" || allowed == "*" || origin == allowed {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(u.Host, req.Host) {
+		return fmt.Errorf("origin %s not allowed", origin)
+	}
+	config.Origin = u
+	return nil
+}
+
+// headerContains returns true if the comma separated list of tokens in the request header with
+// the given name includes token.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
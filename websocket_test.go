@@ -0,0 +1,131 @@
+package goa_test
+
+import (
+	"compress/flate"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/raphael/goa"
+	"golang.org/x/net/websocket"
+)
+
+var _ = Describe("RespondWebSocket", func() {
+	var service goa.Service
+	var server *httptest.Server
+	var wsURL string
+	var closed chan bool
+	var wrap goa.Middleware
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		closed = make(chan bool, 1)
+		wrap = func(h goa.Handler) goa.Handler { return h }
+	})
+
+	JustBeforeEach(func() {
+		ctrl := service.NewController("DashboardController")
+		h := func(ctx *goa.Context) error {
+			return ctx.RespondWebSocket(func(ws *goa.WebSocket) error {
+				var msg map[string]int
+				if err := ws.Receive(&msg); err != nil {
+					return err
+				}
+				return ws.Send(map[string]int{"value": msg["value"] + 1})
+			})
+		}
+		route := &goa.Route{Method: "GET", Path: "/live"}
+		service.ServeMux().Handle(route.Method, route.Path, ctrl.HandleRoute(route, "live", wrap(h), nil))
+		watch := func(ctx *goa.Context) error {
+			return ctx.RespondWebSocket(func(ws *goa.WebSocket) error {
+				if err := ws.Send(map[string]int{"value": 1}); err != nil {
+					return err
+				}
+				<-ws.Done()
+				closed <- true
+				return nil
+			})
+		}
+		route = &goa.Route{Method: "GET", Path: "/watch"}
+		service.ServeMux().Handle(route.Method, route.Path, ctrl.HandleRoute(route, "watch", watch, nil))
+		server = httptest.NewServer(service.ServeMux())
+		wsURL = "ws" + strings.TrimPrefix(server.URL, "http") + "/live"
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("exchanges JSON messages with the client", func() {
+		ws, err := goa.DialWebSocket(wsURL, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		defer ws.Close()
+		Ω(ws.Send(map[string]int{"value": 1})).Should(Succeed())
+		var msg map[string]int
+		Ω(ws.Receive(&msg)).Should(Succeed())
+		Ω(msg).Should(Equal(map[string]int{"value": 2}))
+	})
+
+	It("detects when the client closes the connection", func() {
+		ws, err := goa.DialWebSocket(strings.Replace(wsURL, "/live", "/watch", 1), nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		var msg map[string]int
+		Ω(ws.Receive(&msg)).Should(Succeed())
+		Ω(ws.Close()).Should(Succeed())
+		Eventually(closed).Should(Receive())
+	})
+
+	Context("with middleware that wraps the response writer", func() {
+		exchange := func() {
+			header := http.Header{"Accept-Encoding": {"gzip, deflate"}}
+			ws, err := goa.DialWebSocket(wsURL, header)
+			Ω(err).ShouldNot(HaveOccurred())
+			defer ws.Close()
+			Ω(ws.Send(map[string]int{"value": 1})).Should(Succeed())
+			var msg map[string]int
+			Ω(ws.Receive(&msg)).Should(Succeed())
+			Ω(msg).Should(Equal(map[string]int{"value": 2}))
+		}
+
+		Context("with the Compress middleware", func() {
+			BeforeEach(func() {
+				service.Use(goa.Compress(flate.DefaultCompression, 0))
+			})
+
+			It("upgrades the connection", exchange)
+		})
+
+		Context("with the Timeout middleware", func() {
+			BeforeEach(func() {
+				wrap = goa.Timeout(time.Second)
+			})
+
+			It("upgrades the connection", exchange)
+		})
+
+		Context("with the ETag middleware", func() {
+			BeforeEach(func() {
+				wrap = goa.ETag()
+			})
+
+			It("upgrades the connection", exchange)
+		})
+	})
+
+	It("rejects requests that are not WebSocket handshakes", func() {
+		resp, err := http.Get(server.URL + "/live")
+		Ω(err).ShouldNot(HaveOccurred())
+		resp.Body.Close()
+		Ω(resp.StatusCode).Should(Equal(400))
+	})
+
+	It("rejects handshakes sent from other origins", func() {
+		config, err := websocket.NewConfig(wsURL, "http://evil.example.com")
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = websocket.DialConfig(config)
+		Ω(err).Should(HaveOccurred())
+	})
+})